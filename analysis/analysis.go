@@ -0,0 +1,182 @@
+// Package analysis finds likely mistakes in Monkey programs without running
+// them.
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/token"
+)
+
+// Issue is a problem found in a program, at the position of the token it was
+// found at. Style issues found by Lint have a code, such as ML001.
+type Issue struct {
+	Code    string
+	Message string
+	Line    int
+	Col     int
+}
+
+func (i Issue) String() string {
+	if i.Code != "" {
+		return fmt.Sprintf("%d:%d: %s %s", i.Line, i.Col, i.Code, i.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", i.Line, i.Col, i.Message)
+}
+
+// Check runs every pass over `program`, returning the issues found in order of
+// their position
+func Check(program *ast.Program) []Issue {
+	issues := []Issue{}
+	issues = append(issues, unusedVariables(program)...)
+	issues = append(issues, unreachableCode(program)...)
+
+	sortIssues(issues)
+	return issues
+}
+
+func sortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Col < issues[j].Col
+	})
+}
+
+func newIssue(tok token.Token, format string, args ...interface{}) Issue {
+	return Issue{Message: fmt.Sprintf(format, args...), Line: tok.Line, Col: tok.Col}
+}
+
+// unusedVariables reports names bound by `let` that are never referred to.
+// Scopes aren't tracked, so a name is only reported when nothing anywhere in
+// the program uses it. Names starting with an underscore are never reported.
+func unusedVariables(program *ast.Program) []Issue {
+	// Identifiers that bind a name rather than use one
+	binding := map[*ast.Identifier]bool{}
+	lets := []*ast.Identifier{}
+
+	ast.Walk(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			binding[node.Name] = true
+			lets = append(lets, node.Name)
+		case *ast.LetInExpression:
+			binding[node.Name] = true
+			lets = append(lets, node.Name)
+		case *ast.DestructuringStatement:
+			for _, name := range ast.PatternNames(node.Pattern) {
+				binding[name] = true
+				lets = append(lets, name)
+			}
+		case *ast.ForStatement:
+			binding[node.Variable] = true
+		case *ast.WithStatement:
+			binding[node.Name] = true
+		case *ast.TryCatchStatement:
+			if node.CatchName != nil {
+				binding[node.CatchName] = true
+			}
+		case *ast.FunctionLiteral:
+			for _, p := range node.Parameters {
+				binding[p] = true
+			}
+		case *ast.SelectStatement:
+			for _, c := range node.Cases {
+				if c.Var != nil {
+					binding[c.Var] = true
+				}
+			}
+		case *ast.MemberExpression:
+			binding[node.Property] = true
+		case *ast.MatchExpression:
+			for _, arm := range node.Arms {
+				ast.Walk(arm.Pattern, func(node ast.Node) bool {
+					if ident, ok := node.(*ast.Identifier); ok {
+						binding[ident] = true
+					}
+					return true
+				})
+			}
+		}
+		return true
+	})
+
+	used := map[string]bool{}
+	ast.Walk(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			if !binding[node] {
+				used[node.Value] = true
+			}
+		case *ast.TypeAliasStatement:
+			// Names in a type are types, not variables
+			return false
+		}
+		return true
+	})
+
+	issues := []Issue{}
+	for _, name := range lets {
+		if !used[name.Value] && name.Value[0] != '_' {
+			issues = append(issues, newIssue(name.Token, "%s is never used", name.Value))
+		}
+	}
+	return issues
+}
+
+// unreachableCode reports the first statement after a `return` in a program or
+// block, as it can never run
+func unreachableCode(program *ast.Program) []Issue {
+	issues := []Issue{}
+
+	check := func(statements []ast.Statement) {
+		for i := 0; i+1 < len(statements); i++ {
+			if _, ok := statements[i].(*ast.ReturnStatement); ok {
+				issues = append(issues, newIssue(statementToken(statements[i+1]), "unreachable code"))
+				return
+			}
+		}
+	}
+
+	ast.Walk(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			check(node.Statements)
+		case *ast.BlockStatement:
+			check(node.Statements)
+		}
+		return true
+	})
+	return issues
+}
+
+func statementToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.DestructuringStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	case *ast.GoStatement:
+		return stmt.Token
+	case *ast.TypeAliasStatement:
+		return stmt.Token
+	case *ast.WithStatement:
+		return stmt.Token
+	case *ast.TryCatchStatement:
+		return stmt.Token
+	case *ast.ForStatement:
+		return stmt.Token
+	case *ast.SelectStatement:
+		return stmt.Token
+	case *ast.BlockStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
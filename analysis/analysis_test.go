@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; puts(x);", []string{}},
+		{"let x = 1;", []string{"1:5: x is never used"}},
+		{"let _x = 1;", []string{}},
+		{"let add = fn(a, b) { a };", []string{"1:5: add is never used"}},
+		{"let f = fn(x) { 1 }; f(2);", []string{}},
+		{"let h = {}; h.x;", []string{}},
+		{"let x = 1; match 1 { case x: 2 };", []string{"1:5: x is never used"}},
+		{"let x = 1 in x + 1;", []string{}},
+		{"let y = 1 in 2;", []string{"1:5: y is never used"}},
+		{"return 1; puts(2); puts(3);", []string{"1:11: unreachable code"}},
+		{"fn() {\n  if (true) { return 1; 2 }\n  return 3;\n}", []string{"2:25: unreachable code"}},
+		{"let f = fn() { return 1; };\nf();", []string{}},
+		{"let [first, second = first] = [1]; puts(second);", []string{}},
+		{"let [first, second] = [1]; puts(first);", []string{"1:13: second is never used"}},
+		{`let {x: first, second} = {}; puts(first);`, []string{"1:16: second is never used"}},
+		{"let Point = 1; type Line = [Point, Point];", []string{"1:5: Point is never used"}},
+		{"return 1;\nlet x = 2;", []string{"2:1: unreachable code", "2:5: x is never used"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors())
+		}
+
+		issues := Check(program)
+		if len(issues) != len(tt.expected) {
+			t.Errorf("wrong number of issues for %q. expected=%v, got=%v", tt.input, tt.expected, issues)
+			continue
+		}
+		for i, issue := range issues {
+			if issue.String() != tt.expected[i] {
+				t.Errorf("wrong issue for %q. expected=%q, got=%q", tt.input, tt.expected[i], issue.String())
+			}
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let total = 1;", []string{}},
+		{"let x = 1;", []string{"1:5: ML005 x is too short to say what it holds"}},
+		{"let _ = 1; let X = 2; let xs = 3;", []string{}},
+		{"fn(aa, ab, ac, ad, ae, af, ag, ah, ai, aj) { 1 }", []string{}},
+		{"fn(aa, ab, ac, ad, ae, af, ag, ah, ai, aj, ak) { 1 }", []string{"1:1: ML001 function has 11 parameters, more than 10"}},
+		{"let total = 1; let fun = fn() { let total = 2; total };", []string{"1:37: ML004 total shadows a variable in an outer scope"}},
+		{"let total = 1; if (true) { let total = 2; }", []string{}},
+		{"let fun = fn(total) { let total = 2; total };", []string{}},
+		{"let fun = fn() { let total = 2; total }; let total = 1;", []string{}},
+		{"let total = 1; match 2 { case n: let total = n in total };", []string{"1:38: ML004 total shadows a variable in an outer scope"}},
+		{"if (a) { if (b) { if (c) { if (d) { 1 } } } }", []string{}},
+		{"if (a) { if (b) { if (c) { if (d) { if (e) { 1 } } } } }", []string{"1:37: ML003 if is nested 5 deep, more than 4"}},
+		{"if (a) { 1 } else { if (b) { 2 } else { if (c) { 3 } else { if (d) { 4 } else { if (e) { 5 } } } } }", []string{}},
+	}
+
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors())
+		}
+
+		issues := Lint(program)
+		if len(issues) != len(tt.expected) {
+			t.Errorf("wrong number of issues for %q. expected=%v, got=%v", tt.input, tt.expected, issues)
+			continue
+		}
+		for i, issue := range issues {
+			if issue.String() != tt.expected[i] {
+				t.Errorf("wrong issue for %q. expected=%q, got=%q", tt.input, tt.expected[i], issue.String())
+			}
+		}
+	}
+
+	long := "fn() {" + strings.Repeat(" 1;", 51) + " }"
+	issues := Lint(parser.NewParser(lexer.NewLexer(long)).ParseProgram())
+	if len(issues) != 1 || issues[0].Code != LongBlock {
+		t.Errorf("expected a long block to be reported, got=%v", issues)
+	}
+}
@@ -0,0 +1,208 @@
+package analysis
+
+import (
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/token"
+)
+
+// The style issues Lint reports
+const (
+	TooManyParameters = "ML001" // A function with more than maxParameters parameters
+	LongBlock         = "ML002" // A block with more than maxBlockStatements statements
+	DeepNesting       = "ML003" // An if nested more than maxIfDepth deep
+	Shadowing         = "ML004" // A let inside a function that hides a name from outside it
+	ShortName         = "ML005" // A let binding a single lowercase letter
+)
+
+const (
+	maxParameters      = 10
+	maxBlockStatements = 50
+	maxIfDepth         = 4
+)
+
+// Lint returns the style issues in `program`, in order of their position.
+// Unlike the issues found by Check these don't make the program wrong.
+func Lint(program *ast.Program) []Issue {
+	l := &linter{issues: []Issue{}, scopes: []map[string]bool{{}}}
+	l.visit(program)
+
+	sortIssues(l.issues)
+	return l.issues
+}
+
+type linter struct {
+	issues []Issue
+
+	// The names bound in each scope, outermost first. Functions, let-in
+	// bodies and match arms start a new scope, blocks don't.
+	scopes []map[string]bool
+
+	ifDepth int
+}
+
+func (l *linter) report(tok token.Token, code, format string, args ...interface{}) {
+	issue := newIssue(tok, format, args...)
+	issue.Code = code
+	l.issues = append(l.issues, issue)
+}
+
+func (l *linter) visit(node ast.Node) {
+	ast.Walk(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if node.Value != nil {
+				l.visit(node.Value)
+			}
+			l.let(node.Name)
+			return false
+		case *ast.DestructuringStatement:
+			for _, n := range []ast.Node{node.Value, node.Pattern} {
+				if n != nil {
+					l.visit(n)
+				}
+			}
+			for _, name := range ast.PatternNames(node.Pattern) {
+				l.let(name)
+			}
+			return false
+		case *ast.DefaultPattern:
+			// Only the default is an expression, the target is bound by the
+			// destructuring statement
+			if node.Default != nil {
+				l.visit(node.Default)
+			}
+			return false
+		case *ast.LetInExpression:
+			if node.Value != nil {
+				l.visit(node.Value)
+			}
+			l.push()
+			l.let(node.Name)
+			if node.Body != nil {
+				l.visit(node.Body)
+			}
+			l.pop()
+			return false
+		case *ast.FunctionLiteral:
+			if len(node.Parameters) > maxParameters {
+				l.report(node.Token, TooManyParameters, "function has %d parameters, more than %d", len(node.Parameters), maxParameters)
+			}
+			l.push()
+			if node.Name != "" {
+				l.bind(node.Name)
+			}
+			for _, p := range node.Parameters {
+				l.bind(p.Value)
+			}
+			if node.Body != nil {
+				l.visit(node.Body)
+			}
+			l.pop()
+			return false
+		case *ast.MatchExpression:
+			if node.Subject != nil {
+				l.visit(node.Subject)
+			}
+			for _, arm := range node.Arms {
+				l.push()
+				ast.Walk(arm.Pattern, func(node ast.Node) bool {
+					if ident, ok := node.(*ast.Identifier); ok {
+						l.bind(ident.Value)
+					}
+					return true
+				})
+				for _, n := range []ast.Node{arm.Guard, arm.Body} {
+					if n != nil {
+						l.visit(n)
+					}
+				}
+				l.pop()
+			}
+			if node.Default != nil {
+				l.visit(node.Default)
+			}
+			return false
+		case *ast.IfExpression:
+			l.visitIf(node)
+			return false
+		case *ast.BlockStatement:
+			if len(node.Statements) > maxBlockStatements {
+				l.report(node.Token, LongBlock, "block has %d statements, more than %d", len(node.Statements), maxBlockStatements)
+			}
+		}
+		return true
+	})
+}
+
+// visitIf counts how deeply ifs are nested. An if that is the only thing in
+// an else, Monkey's `else if`, is counted at the same depth as the if before.
+func (l *linter) visitIf(node *ast.IfExpression) {
+	l.ifDepth++
+	defer func() { l.ifDepth-- }()
+
+	if l.ifDepth == maxIfDepth+1 {
+		l.report(node.Token, DeepNesting, "if is nested %d deep, more than %d", l.ifDepth, maxIfDepth)
+	}
+
+	if node.Condition != nil {
+		l.visit(node.Condition)
+	}
+	if node.Consequence != nil {
+		l.visit(node.Consequence)
+	}
+	if node.Alternative == nil {
+		return
+	}
+
+	if len(node.Alternative.Statements) == 1 {
+		if stmt, ok := node.Alternative.Statements[0].(*ast.ExpressionStatement); ok {
+			if elseIf, ok := stmt.Expression.(*ast.IfExpression); ok {
+				l.ifDepth--
+				l.visitIf(elseIf)
+				l.ifDepth++
+				return
+			}
+		}
+	}
+	l.visit(node.Alternative)
+}
+
+// let checks then binds a name bound with `let`
+func (l *linter) let(name *ast.Identifier) {
+	if name == nil {
+		return
+	}
+
+	if len(name.Value) == 1 && name.Value[0] >= 'a' && name.Value[0] <= 'z' {
+		l.report(name.Token, ShortName, "%s is too short to say what it holds", name.Value)
+	}
+
+	inner := l.scopes[len(l.scopes)-1]
+	if !inner[name.Value] {
+		for _, scope := range l.scopes[:len(l.scopes)-1] {
+			if scope[name.Value] {
+				l.report(name.Token, Shadowing, "%s shadows a variable in an outer scope", name.Value)
+				break
+			}
+		}
+	}
+
+	l.bind(name.Value)
+}
+
+func (l *linter) bind(name string) {
+	l.scopes[len(l.scopes)-1][name] = true
+}
+
+func (l *linter) push() {
+	l.scopes = append(l.scopes, map[string]bool{})
+}
+
+func (l *linter) pop() {
+	l.scopes = l.scopes[:len(l.scopes)-1]
+}
+
+// Codes returns the code of every lint check
+func Codes() []string {
+	return []string{TooManyParameters, LongBlock, DeepNesting, Shadowing, ShortName}
+}
@@ -11,6 +11,9 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	// Useful describes the node and its children, with the type and fields of
+	// each, for debugging
+	Useful() string
 }
 
 type Statement interface {
@@ -38,6 +41,10 @@ func (p Program) String() string {
 	return out.String()
 }
 
+func (p *Program) Useful() string {
+	return fmt.Sprintf("ast.Program -> Statements=[%s]", usefulStatements(p.Statements))
+}
+
 func (p *Program) TokenLiteral() string {
 	if len(p.Statements) > 0 {
 		return p.Statements[0].TokenLiteral()
@@ -47,25 +54,39 @@ func (p *Program) TokenLiteral() string {
 }
 
 // Let statement
+// Comments starting with `##` on the lines just before a let statement are
+// its Doc.
+// A function declaration, `fn add(x, y) { x + y }`, is parsed as a
+// LetStatement whose token is the 'fn' token and whose value is the named
+// FunctionLiteral.
 type LetStatement struct {
-	Token token.Token // the token.LET token
+	Token token.Token // the token.LET or token.FUNCTION token
 	Name  *Identifier
 	Value Expression
+	Doc   string // From the `##` comments just before the statement, without the `##`
 }
 
 func (ls LetStatement) statementNode()       {}
 func (ls LetStatement) TokenLiteral() string { return ls.Token.Literal }
 func (ls LetStatement) Useful() string {
-	return fmt.Sprintf("ast.LetStatement -> Token=%s, Name=%s", ls.Token.Useful(), ls.Name.Useful())
+	return fmt.Sprintf("ast.LetStatement -> Token=%s, Name=%s, Value=%s",
+		ls.Token.Useful(), usefulIdentifier(ls.Name), usefulExpression(ls.Value))
 }
 func (ls LetStatement) String() string {
+	if ls.Token.Type == token.FUNCTION && ls.Value != nil {
+		return ls.Value.String()
+	}
+
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
 	out.WriteString(" = ")
 
-	if ls.Value != nil {
+	// The name of a function is implied by the let, so isn't repeated
+	if fn, ok := ls.Value.(*FunctionLiteral); ok && fn.Name == ls.Name.Value {
+		out.WriteString(fn.string(false))
+	} else if ls.Value != nil {
 		out.WriteString(ls.Value.String())
 	}
 
@@ -74,6 +95,183 @@ func (ls LetStatement) String() string {
 	return out.String()
 }
 
+// Let-in expression, `let x = 5 in x * x`. Name is only bound while Body is
+// evaluated.
+type LetInExpression struct {
+	Token token.Token // the token.LET token
+	Name  *Identifier
+	Value Expression
+	Body  Expression
+}
+
+func (le LetInExpression) expressionNode()      {}
+func (le LetInExpression) TokenLiteral() string { return le.Token.Literal }
+func (le LetInExpression) Useful() string {
+	return fmt.Sprintf("ast.LetInExpression -> Token=%s, Name=%s, Value=%s, Body=%s",
+		le.Token.Useful(), usefulIdentifier(le.Name), usefulExpression(le.Value), usefulExpression(le.Body))
+}
+func (le LetInExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("let ")
+	out.WriteString(le.Name.String())
+	out.WriteString(" = ")
+	out.WriteString(le.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(le.Body.String())
+
+	return out.String()
+}
+
+// Range literal, `1..5` includes 5 and `1...5` doesn't
+type RangeLiteral struct {
+	Token     token.Token // the token.DOTDOT or token.DOTDOTDOT token
+	Low       Expression
+	High      Expression
+	Inclusive bool
+}
+
+func (rl RangeLiteral) expressionNode()      {}
+func (rl RangeLiteral) TokenLiteral() string { return rl.Token.Literal }
+func (rl RangeLiteral) Useful() string {
+	return fmt.Sprintf("ast.RangeLiteral -> Token=%s, Low=%s, High=%s, Inclusive=%t",
+		rl.Token.Useful(), usefulExpression(rl.Low), usefulExpression(rl.High), rl.Inclusive)
+}
+func (rl RangeLiteral) String() string {
+	operator := "..."
+	if rl.Inclusive {
+		operator = ".."
+	}
+	return "(" + rl.Low.String() + operator + rl.High.String() + ")"
+}
+
+// Match expression, `match x { case 1: "one", case [h, ...t]: h, default: 0 }`.
+// The body of the first arm whose pattern matches the subject is evaluated.
+type MatchExpression struct {
+	Token   token.Token // the token.MATCH token
+	Subject Expression
+	Arms    []MatchArm
+	Default Node // nil if there is no default, otherwise like MatchArm.Body
+}
+
+// Switch expression, `switch x { case 1: "one", case 2: { "two" }, default: 0 }`.
+// The body of the first case whose value equals the subject is evaluated.
+// Unlike a match, the cases are values rather than patterns.
+type SwitchExpression struct {
+	Token   token.Token // the token.SWITCH token
+	Subject Expression
+	Cases   []SwitchCase
+	Default Node // nil if there is no default, otherwise like SwitchCase.Body
+}
+
+// SwitchCase is one `case value: body` of a switch. The body is an Expression,
+// or a *BlockStatement when it is written in braces.
+type SwitchCase struct {
+	Token token.Token // the token.CASE token
+	Value Expression
+	Body  Node
+}
+
+func (se SwitchExpression) expressionNode()      {}
+func (se SwitchExpression) TokenLiteral() string { return se.Token.Literal }
+func (se SwitchExpression) Useful() string {
+	cases := []string{}
+	for _, c := range se.Cases {
+		cases = append(cases, fmt.Sprintf("ast.SwitchCase -> Token=%s, Value=%s, Body=%s",
+			c.Token.Useful(), usefulExpression(c.Value), usefulNode(c.Body)))
+	}
+	return fmt.Sprintf("ast.SwitchExpression -> Token=%s, Subject=%s, Cases=[%s], Default=%s",
+		se.Token.Useful(), usefulExpression(se.Subject), strings.Join(cases, ", "), usefulNode(se.Default))
+}
+func (se SwitchExpression) String() string {
+	var out bytes.Buffer
+
+	cases := []string{}
+	for _, c := range se.Cases {
+		cases = append(cases, "case "+c.Value.String()+": "+switchBody(c.Body))
+	}
+	if se.Default != nil {
+		cases = append(cases, "default: "+switchBody(se.Default))
+	}
+
+	out.WriteString("switch ")
+	out.WriteString(se.Subject.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(cases, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// BlockStatement.String() leaves out the braces
+func switchBody(body Node) string {
+	if block, ok := body.(*BlockStatement); ok {
+		return "{ " + block.String() + " }"
+	}
+	return body.String()
+}
+
+// MatchArm is one `case pattern: body` of a match. Patterns are literals, `_`,
+// identifiers, which bind the value they match, and array and hash literals
+// of patterns. With a guard, `case n if n > 0: body`, the arm only matches if
+// the guard is also truthy.
+type MatchArm struct {
+	Token   token.Token // the token.CASE token
+	Pattern Expression
+	Guard   Expression // nil if the arm has no guard
+	Body    Node       // an Expression, or a *BlockStatement when written in braces
+}
+
+func (me MatchExpression) expressionNode()      {}
+func (me MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me MatchExpression) Useful() string {
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, fmt.Sprintf("ast.MatchArm -> Token=%s, Pattern=%s, Guard=%s, Body=%s",
+			arm.Token.Useful(), usefulExpression(arm.Pattern), usefulExpression(arm.Guard), usefulNode(arm.Body)))
+	}
+	return fmt.Sprintf("ast.MatchExpression -> Token=%s, Subject=%s, Arms=[%s], Default=%s",
+		me.Token.Useful(), usefulExpression(me.Subject), strings.Join(arms, ", "), usefulNode(me.Default))
+}
+func (me MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		pattern := arm.Pattern.String()
+		if arm.Guard != nil {
+			pattern += " if " + arm.Guard.String()
+		}
+		arms = append(arms, "case "+pattern+": "+switchBody(arm.Body))
+	}
+	if me.Default != nil {
+		arms = append(arms, "default: "+switchBody(me.Default))
+	}
+
+	out.WriteString("match ")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// Spread, `...rest`, matches the rest of an array in a match pattern
+type SpreadExpression struct {
+	Token token.Token // the token.DOTDOTDOT token
+	Value Expression
+}
+
+func (se SpreadExpression) expressionNode()      {}
+func (se SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se SpreadExpression) Useful() string {
+	return fmt.Sprintf("ast.SpreadExpression -> Token=%s, Value=%s", se.Token.Useful(), usefulExpression(se.Value))
+}
+func (se SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
 // Identifier statement
 type Identifier struct {
 	Token token.Token // the token.IDENT token
@@ -87,6 +285,44 @@ func (i Identifier) Useful() string {
 }
 func (i Identifier) String() string { return i.Value }
 
+// Destructuring let statement, `let [x, y = 0] = point` or
+// `let {x: px, y} = point`. The pattern is an Identifier, an ArrayLiteral of
+// patterns, or a HashLiteral from StringLiteral keys to patterns. Each element
+// of an array or hash pattern can be a DefaultPattern.
+type DestructuringStatement struct {
+	Token   token.Token // the token.LET token
+	Pattern Expression
+	Value   Expression
+}
+
+func (ds DestructuringStatement) statementNode()       {}
+func (ds DestructuringStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds DestructuringStatement) Useful() string {
+	return fmt.Sprintf("ast.DestructuringStatement -> Token=%s, Pattern=%s, Value=%s",
+		ds.Token.Useful(), usefulExpression(ds.Pattern), usefulExpression(ds.Value))
+}
+func (ds DestructuringStatement) String() string {
+	return ds.TokenLiteral() + " " + ds.Pattern.String() + " = " + ds.Value.String() + ";"
+}
+
+// DefaultPattern is `target = default` in a destructuring pattern. The default
+// is only evaluated when the value for the target is missing or null.
+type DefaultPattern struct {
+	Token   token.Token // the '=' token
+	Target  Expression
+	Default Expression
+}
+
+func (dp DefaultPattern) expressionNode()      {}
+func (dp DefaultPattern) TokenLiteral() string { return dp.Token.Literal }
+func (dp DefaultPattern) Useful() string {
+	return fmt.Sprintf("ast.DefaultPattern -> Token=%s, Target=%s, Default=%s",
+		dp.Token.Useful(), usefulExpression(dp.Target), usefulExpression(dp.Default))
+}
+func (dp DefaultPattern) String() string {
+	return dp.Target.String() + " = " + dp.Default.String()
+}
+
 // Return statement
 type ReturnStatement struct {
 	Token       token.Token // the token.RETURN token
@@ -96,7 +332,7 @@ type ReturnStatement struct {
 func (rs ReturnStatement) statementNode()       {}
 func (rs ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 func (rs ReturnStatement) Useful() string {
-	return fmt.Sprintf("ast.ReturnStatement -> Token=%s, ReturnValue=%s", rs.Token.Useful(), "NI")
+	return fmt.Sprintf("ast.ReturnStatement -> Token=%s, ReturnValue=%s", rs.Token.Useful(), usefulExpression(rs.ReturnValue))
 }
 func (rs ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -112,6 +348,174 @@ func (rs ReturnStatement) String() string {
 	return out.String()
 }
 
+// Go statement, runs the call in a new goroutine
+type GoStatement struct {
+	Token token.Token // the token.GO token
+	Call  *CallExpression
+}
+
+func (gs GoStatement) statementNode()       {}
+func (gs GoStatement) TokenLiteral() string { return gs.Token.Literal }
+func (gs GoStatement) Useful() string {
+	call := "nil"
+	if gs.Call != nil {
+		call = gs.Call.Useful()
+	}
+	return fmt.Sprintf("ast.GoStatement -> Token=%s, Call=%s", gs.Token.Useful(), call)
+}
+func (gs GoStatement) String() string {
+	return gs.TokenLiteral() + " " + gs.Call.String() + ";"
+}
+
+// Type alias statement, `type Vector = [Int]`. Monkey has no types yet, so
+// the evaluator ignores it; it is there to document values, and for tools.
+type TypeAliasStatement struct {
+	Token token.Token // the token.TYPE token
+	Name  *Identifier
+	Type  Expression
+}
+
+func (ts TypeAliasStatement) statementNode()       {}
+func (ts TypeAliasStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts TypeAliasStatement) Useful() string {
+	return fmt.Sprintf("ast.TypeAliasStatement -> Token=%s, Name=%s, Type=%s",
+		ts.Token.Useful(), usefulIdentifier(ts.Name), usefulExpression(ts.Type))
+}
+func (ts TypeAliasStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Name.String() + " = " + ts.Type.String() + ";"
+}
+
+// With statement, `with open("f") as fh { body }`. The init value is bound to
+// the name for the body, and its `__close__` function is called afterwards.
+type WithStatement struct {
+	Token token.Token // the token.WITH token
+	Init  Expression
+	Name  *Identifier
+	Body  *BlockStatement
+}
+
+func (ws WithStatement) statementNode()       {}
+func (ws WithStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws WithStatement) Useful() string {
+	return fmt.Sprintf("ast.WithStatement -> Token=%s, Init=%s, Name=%s, Body=%s",
+		ws.Token.Useful(), usefulExpression(ws.Init), usefulIdentifier(ws.Name), usefulBlock(ws.Body))
+}
+func (ws WithStatement) String() string {
+	return "with " + ws.Init.String() + " as " + ws.Name.String() + " " + ws.Body.String()
+}
+
+// Try statement, `try { body } catch (err) { handler } finally { cleanup }`.
+// It needs a catch, a finally or both. The catch runs if the body fails, with
+// the error bound to its name, and the finally runs however the rest finished.
+type TryCatchStatement struct {
+	Token     token.Token // the token.TRY token
+	Body      *BlockStatement
+	CatchName *Identifier     // nil if there is no catch
+	Catch     *BlockStatement // nil if there is no catch
+	Finally   *BlockStatement // nil if there is no finally
+}
+
+func (ts TryCatchStatement) statementNode()       {}
+func (ts TryCatchStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts TryCatchStatement) Useful() string {
+	return fmt.Sprintf("ast.TryCatchStatement -> Token=%s, Body=%s, CatchName=%s, Catch=%s, Finally=%s",
+		ts.Token.Useful(), usefulBlock(ts.Body), usefulIdentifier(ts.CatchName), usefulBlock(ts.Catch), usefulBlock(ts.Finally))
+}
+func (ts TryCatchStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(ts.Body.String())
+	if ts.Catch != nil {
+		out.WriteString(" catch (" + ts.CatchName.String() + ") ")
+		out.WriteString(ts.Catch.String())
+	}
+	if ts.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(ts.Finally.String())
+	}
+
+	return out.String()
+}
+
+// For statement, `for (x in iterable) { body }`
+type ForStatement struct {
+	Token    token.Token // the token.FOR token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs ForStatement) statementNode()       {}
+func (fs ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs ForStatement) Useful() string {
+	return fmt.Sprintf("ast.ForStatement -> Token=%s, Variable=%s, Iterable=%s, Body=%s",
+		fs.Token.Useful(), usefulIdentifier(fs.Variable), usefulExpression(fs.Iterable), usefulBlock(fs.Body))
+}
+func (fs ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// Select statement, `select { case (x in ch) { body } default { body } }`
+type SelectStatement struct {
+	Token   token.Token // the token.SELECT token
+	Cases   []SelectCase
+	Default *BlockStatement
+}
+
+// SelectCase receives from Chan, binding the value to Var when it is set
+type SelectCase struct {
+	Token token.Token // the token.CASE token
+	Chan  Expression
+	Var   *Identifier
+	Body  *BlockStatement
+}
+
+func (ss SelectStatement) statementNode()       {}
+func (ss SelectStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss SelectStatement) Useful() string {
+	cases := []string{}
+	for _, c := range ss.Cases {
+		cases = append(cases, fmt.Sprintf("ast.SelectCase -> Token=%s, Chan=%s, Var=%s, Body=%s",
+			c.Token.Useful(), usefulExpression(c.Chan), usefulIdentifier(c.Var), usefulBlock(c.Body)))
+	}
+	return fmt.Sprintf("ast.SelectStatement -> Token=%s, Cases=[%s], Default=%s",
+		ss.Token.Useful(), strings.Join(cases, ", "), usefulBlock(ss.Default))
+}
+func (ss SelectStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("select { ")
+	for _, c := range ss.Cases {
+		out.WriteString("case (")
+		if c.Var != nil {
+			out.WriteString(c.Var.String())
+			out.WriteString(" in ")
+		}
+		out.WriteString(c.Chan.String())
+		out.WriteString(") ")
+		out.WriteString(c.Body.String())
+		out.WriteString(" ")
+	}
+	if ss.Default != nil {
+		out.WriteString("default ")
+		out.WriteString(ss.Default.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
 // Expression statement
 type ExpressionStatement struct {
 	Token      token.Token // The first token of the expression
@@ -121,7 +525,7 @@ type ExpressionStatement struct {
 func (es ExpressionStatement) statementNode()       {}
 func (es ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
 func (es ExpressionStatement) Useful() string {
-	return fmt.Sprintf("ast.ExpressionStatement -> Token=%s, Expression=%s", es.Token.Useful(), "NI")
+	return fmt.Sprintf("ast.ExpressionStatement -> Token=%s, Expression=%s", es.Token.Useful(), usefulExpression(es.Expression))
 }
 func (es ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -139,7 +543,7 @@ type IntegerLiteral struct {
 func (il IntegerLiteral) expressionNode()      {}
 func (il IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il IntegerLiteral) Useful() string {
-	return fmt.Sprintf("ast.IntegerLiteral -> Token=%s Value=%d", il.Token.Useful(), il.Value)
+	return fmt.Sprintf("ast.IntegerLiteral -> Token=%s, Value=%d", il.Token.Useful(), il.Value)
 }
 func (il IntegerLiteral) String() string { return il.Token.Literal }
 
@@ -154,7 +558,7 @@ func (pe PrefixExpression) expressionNode()      {}
 func (pe PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
 func (pe PrefixExpression) Useful() string {
 	return fmt.Sprintf("ast.PrefixExpression -> Token=%s, Operator=%s, Right=%s",
-		pe.Token.Useful(), pe.Operator, "NI")
+		pe.Token.Useful(), pe.Operator, usefulExpression(pe.Right))
 }
 func (pe PrefixExpression) String() string {
 	var out bytes.Buffer
@@ -179,7 +583,7 @@ func (ie InfixExpression) expressionNode()      {}
 func (ie InfixExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie InfixExpression) Useful() string {
 	return fmt.Sprintf("ast.InfixExpression -> Token=%s, Left=%s, Operator=%s, Right=%s",
-		ie.Token.Useful(), "NI", ie.Operator, "NI")
+		ie.Token.Useful(), usefulExpression(ie.Left), ie.Operator, usefulExpression(ie.Right))
 }
 func (ie InfixExpression) String() string {
 	var out bytes.Buffer
@@ -201,7 +605,10 @@ type Boolean struct {
 
 func (b Boolean) expressionNode()      {}
 func (b Boolean) TokenLiteral() string { return b.Token.Literal }
-func (b Boolean) String() string       { return b.Token.Literal }
+func (b Boolean) Useful() string {
+	return fmt.Sprintf("ast.Boolean -> Token=%s, Value=%t", b.Token.Useful(), b.Value)
+}
+func (b Boolean) String() string { return b.Token.Literal }
 
 // If expression
 type IfExpression struct {
@@ -213,6 +620,10 @@ type IfExpression struct {
 
 func (ie IfExpression) expressionNode()      {}
 func (ie IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie IfExpression) Useful() string {
+	return fmt.Sprintf("ast.IfExpression -> Token=%s, Condition=%s, Consequence=%s, Alternative=%s",
+		ie.Token.Useful(), usefulExpression(ie.Condition), usefulBlock(ie.Consequence), usefulBlock(ie.Alternative))
+}
 func (ie IfExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("if")
@@ -226,6 +637,40 @@ func (ie IfExpression) String() string {
 	return out.String()
 }
 
+// Try expression, `try risky()`. An error from the body becomes its value
+// rather than stopping the program. The body extends as far as it can.
+type TryExpression struct {
+	Token token.Token // the token.TRY token
+	Body  Expression
+}
+
+func (te TryExpression) expressionNode()      {}
+func (te TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te TryExpression) Useful() string {
+	return fmt.Sprintf("ast.TryExpression -> Token=%s, Body=%s", te.Token.Useful(), usefulExpression(te.Body))
+}
+func (te TryExpression) String() string {
+	return "try " + te.Body.String()
+}
+
+// Ternary expression, `condition ? consequence : alternative`
+type TernaryExpression struct {
+	Token       token.Token // The '?' token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (te TernaryExpression) expressionNode()      {}
+func (te TernaryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te TernaryExpression) Useful() string {
+	return fmt.Sprintf("ast.TernaryExpression -> Token=%s, Condition=%s, Consequence=%s, Alternative=%s",
+		te.Token.Useful(), usefulExpression(te.Condition), usefulExpression(te.Consequence), usefulExpression(te.Alternative))
+}
+func (te TernaryExpression) String() string {
+	return "(" + te.Condition.String() + " ? " + te.Consequence.String() + " : " + te.Alternative.String() + ")"
+}
+
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
@@ -233,6 +678,9 @@ type BlockStatement struct {
 
 func (bs BlockStatement) statementNode()       {}
 func (bs BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs BlockStatement) Useful() string {
+	return fmt.Sprintf("ast.BlockStatement -> Token=%s, Statements=[%s]", bs.Token.Useful(), usefulStatements(bs.Statements))
+}
 func (bs BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -243,15 +691,32 @@ func (bs BlockStatement) String() string {
 	return out.String()
 }
 
+// Name is set for functions that are declared with a name, `fn add() {}`, or
+// bound with `let add = fn() {}`. The evaluator binds a named function to its
+// name inside its closure so it can call itself.
 type FunctionLiteral struct {
 	Token      token.Token // The 'fn' token
+	Name       string      // Empty for anonymous functions
+	Doc        string      // The Doc of the let statement that names the function
 	Parameters []*Identifier
 	Body       *BlockStatement
 }
 
 func (fl FunctionLiteral) expressionNode()      {}
 func (fl FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl FunctionLiteral) Useful() string {
+	params := []string{}
+	for _, p := range fl.Parameters {
+		params = append(params, usefulIdentifier(p))
+	}
+	return fmt.Sprintf("ast.FunctionLiteral -> Token=%s, Name=%s, Parameters=[%s], Body=%s",
+		fl.Token.Useful(), fl.Name, strings.Join(params, ", "), usefulBlock(fl.Body))
+}
 func (fl FunctionLiteral) String() string {
+	return fl.string(true)
+}
+
+func (fl FunctionLiteral) string(withName bool) string {
 	var out bytes.Buffer
 
 	params := []string{}
@@ -260,6 +725,9 @@ func (fl FunctionLiteral) String() string {
 	}
 
 	out.WriteString(fl.TokenLiteral())
+	if withName && fl.Name != "" {
+		out.WriteString(" " + fl.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
@@ -276,6 +744,10 @@ type CallExpression struct {
 
 func (ce CallExpression) expressionNode()      {}
 func (ce CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce CallExpression) Useful() string {
+	return fmt.Sprintf("ast.CallExpression -> Token=%s, Function=%s, Arguments=[%s]",
+		ce.Token.Useful(), usefulExpression(ce.Function), usefulExpressions(ce.Arguments))
+}
 func (ce CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -291,3 +763,167 @@ func (ce CallExpression) String() string {
 
 	return out.String()
 }
+
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl StringLiteral) expressionNode()      {}
+func (sl StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl StringLiteral) Useful() string {
+	return fmt.Sprintf("ast.StringLiteral -> Token=%s, Value=%q", sl.Token.Useful(), sl.Value)
+}
+func (sl StringLiteral) String() string { return sl.Token.Literal }
+
+type ArrayLiteral struct {
+	Token    token.Token // the '[' token
+	Elements []Expression
+}
+
+func (al ArrayLiteral) expressionNode()      {}
+func (al ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al ArrayLiteral) Useful() string {
+	return fmt.Sprintf("ast.ArrayLiteral -> Token=%s, Elements=[%s]", al.Token.Useful(), usefulExpressions(al.Elements))
+}
+func (al ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+type IndexExpression struct {
+	Token token.Token // The '[' token
+	Left  Expression
+	Index Expression
+}
+
+func (ie IndexExpression) expressionNode()      {}
+func (ie IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie IndexExpression) Useful() string {
+	return fmt.Sprintf("ast.IndexExpression -> Token=%s, Left=%s, Index=%s",
+		ie.Token.Useful(), usefulExpression(ie.Left), usefulExpression(ie.Index))
+}
+func (ie IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// MemberExpression is `object.property`, used to reach the fields and methods
+// of Go values
+type MemberExpression struct {
+	Token    token.Token // The '.' token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me MemberExpression) expressionNode()      {}
+func (me MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me MemberExpression) Useful() string {
+	return fmt.Sprintf("ast.MemberExpression -> Token=%s, Object=%s, Property=%s",
+		me.Token.Useful(), usefulExpression(me.Object), usefulIdentifier(me.Property))
+}
+func (me MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
+
+// Pairs are kept in source order
+type HashLiteral struct {
+	Token token.Token // the '{' token
+	Pairs []HashPair
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl HashLiteral) expressionNode()      {}
+func (hl HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl HashLiteral) Useful() string {
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, usefulExpression(pair.Key)+": "+usefulExpression(pair.Value))
+	}
+	return fmt.Sprintf("ast.HashLiteral -> Token=%s, Pairs=[%s]", hl.Token.Useful(), strings.Join(pairs, ", "))
+}
+func (hl HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// The parser can leave nil children behind when it hits an error, which are
+// described as "nil" rather than calling Useful on them
+func usefulExpression(exp Expression) string {
+	if exp == nil {
+		return "nil"
+	}
+	return exp.Useful()
+}
+
+func usefulNode(node Node) string {
+	if node == nil {
+		return "nil"
+	}
+	return node.Useful()
+}
+
+func usefulIdentifier(ident *Identifier) string {
+	if ident == nil {
+		return "nil"
+	}
+	return ident.Useful()
+}
+
+func usefulBlock(block *BlockStatement) string {
+	if block == nil {
+		return "nil"
+	}
+	return block.Useful()
+}
+
+func usefulExpressions(exps []Expression) string {
+	useful := []string{}
+	for _, exp := range exps {
+		useful = append(useful, usefulExpression(exp))
+	}
+	return strings.Join(useful, ", ")
+}
+
+func usefulStatements(statements []Statement) string {
+	useful := []string{}
+	for _, stmt := range statements {
+		if stmt == nil {
+			useful = append(useful, "nil")
+			continue
+		}
+		useful = append(useful, stmt.Useful())
+	}
+	return strings.Join(useful, ", ")
+}
@@ -0,0 +1,377 @@
+// Package json converts a Monkey AST to JSON, so tools that aren't written in
+// Go can consume it. The format is described in schema.md.
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/token"
+)
+
+// Marshal returns the indented JSON encoding of the tree rooted at `node`
+func Marshal(node ast.Node) ([]byte, error) {
+	v, err := convert(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// object is a JSON object that keeps its keys in the order they were added,
+// so "type" and the position always come first.
+type object []field
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func newObject(typ string, tok token.Token) object {
+	return object{{"type", typ}, {"line", tok.Line}, {"col", tok.Col}}
+}
+
+func convert(node ast.Node) (interface{}, error) {
+	var (
+		o   object
+		err error
+	)
+
+	// Fields of each node are converted in order, stopping at the first error
+	set := func(key string, child ast.Node) {
+		if err != nil {
+			return
+		}
+		var v interface{}
+		v, err = convertChild(child)
+		o = append(o, field{key, v})
+	}
+	setList := func(key string, children []ast.Node) {
+		if err != nil {
+			return
+		}
+		list := []interface{}{}
+		for _, child := range children {
+			var v interface{}
+			if v, err = convertChild(child); err != nil {
+				return
+			}
+			list = append(list, v)
+		}
+		o = append(o, field{key, list})
+	}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		o = object{{"type", "Program"}}
+		setList("statements", statements(node.Statements))
+	case *ast.LetStatement:
+		o = newObject("LetStatement", node.Token)
+		set("name", node.Name)
+		set("value", node.Value)
+		if node.Doc != "" {
+			o = append(o, field{"doc", node.Doc})
+		}
+	case *ast.DestructuringStatement:
+		o = newObject("DestructuringStatement", node.Token)
+		set("pattern", node.Pattern)
+		set("value", node.Value)
+	case *ast.DefaultPattern:
+		o = newObject("DefaultPattern", node.Token)
+		set("target", node.Target)
+		set("default", node.Default)
+	case *ast.LetInExpression:
+		o = newObject("LetInExpression", node.Token)
+		set("name", node.Name)
+		set("value", node.Value)
+		set("body", node.Body)
+	case *ast.MatchExpression:
+		o = newObject("MatchExpression", node.Token)
+		set("subject", node.Subject)
+		arms := []interface{}{}
+		for _, arm := range node.Arms {
+			converted, err := convertMatchArm(arm)
+			if err != nil {
+				return nil, err
+			}
+			arms = append(arms, converted)
+		}
+		o = append(o, field{"arms", arms})
+		set("default", node.Default)
+	case *ast.SwitchExpression:
+		o = newObject("SwitchExpression", node.Token)
+		set("subject", node.Subject)
+		cases := []interface{}{}
+		for _, c := range node.Cases {
+			converted, err := convertSwitchCase(c)
+			if err != nil {
+				return nil, err
+			}
+			cases = append(cases, converted)
+		}
+		o = append(o, field{"cases", cases})
+		set("default", node.Default)
+	case *ast.TryExpression:
+		o = newObject("TryExpression", node.Token)
+		set("body", node.Body)
+	case *ast.SpreadExpression:
+		o = newObject("SpreadExpression", node.Token)
+		set("value", node.Value)
+	case *ast.ReturnStatement:
+		o = newObject("ReturnStatement", node.Token)
+		set("value", node.ReturnValue)
+	case *ast.ExpressionStatement:
+		o = newObject("ExpressionStatement", node.Token)
+		set("expression", node.Expression)
+	case *ast.GoStatement:
+		o = newObject("GoStatement", node.Token)
+		set("call", node.Call)
+	case *ast.TypeAliasStatement:
+		o = newObject("TypeAliasStatement", node.Token)
+		set("name", node.Name)
+		set("alias", node.Type)
+	case *ast.WithStatement:
+		o = newObject("WithStatement", node.Token)
+		set("init", node.Init)
+		set("name", node.Name)
+		set("body", node.Body)
+	case *ast.TryCatchStatement:
+		o = newObject("TryCatchStatement", node.Token)
+		set("body", node.Body)
+		set("catchName", node.CatchName)
+		set("catch", node.Catch)
+		set("finally", node.Finally)
+	case *ast.ForStatement:
+		o = newObject("ForStatement", node.Token)
+		set("variable", node.Variable)
+		set("iterable", node.Iterable)
+		set("body", node.Body)
+	case *ast.SelectStatement:
+		o = newObject("SelectStatement", node.Token)
+		cases := []interface{}{}
+		for _, c := range node.Cases {
+			converted, err := convertSelectCase(c)
+			if err != nil {
+				return nil, err
+			}
+			cases = append(cases, converted)
+		}
+		o = append(o, field{"cases", cases})
+		set("default", node.Default)
+	case *ast.BlockStatement:
+		o = newObject("BlockStatement", node.Token)
+		setList("statements", statements(node.Statements))
+	case *ast.Identifier:
+		o = newObject("Identifier", node.Token)
+		o = append(o, field{"value", node.Value})
+	case *ast.IntegerLiteral:
+		o = newObject("IntegerLiteral", node.Token)
+		o = append(o, field{"value", node.Value})
+	case *ast.StringLiteral:
+		o = newObject("StringLiteral", node.Token)
+		o = append(o, field{"value", node.Value})
+	case *ast.Boolean:
+		o = newObject("Boolean", node.Token)
+		o = append(o, field{"value", node.Value})
+	case *ast.PrefixExpression:
+		o = newObject("PrefixExpression", node.Token)
+		o = append(o, field{"operator", node.Operator})
+		set("right", node.Right)
+	case *ast.InfixExpression:
+		o = newObject("InfixExpression", node.Token)
+		o = append(o, field{"operator", node.Operator})
+		set("left", node.Left)
+		set("right", node.Right)
+	case *ast.RangeLiteral:
+		o = newObject("RangeLiteral", node.Token)
+		set("low", node.Low)
+		set("high", node.High)
+		o = append(o, field{"inclusive", node.Inclusive})
+	case *ast.IfExpression:
+		o = newObject("IfExpression", node.Token)
+		set("condition", node.Condition)
+		set("consequence", node.Consequence)
+		set("alternative", node.Alternative)
+	case *ast.TernaryExpression:
+		o = newObject("TernaryExpression", node.Token)
+		set("condition", node.Condition)
+		set("consequence", node.Consequence)
+		set("alternative", node.Alternative)
+	case *ast.FunctionLiteral:
+		o = newObject("FunctionLiteral", node.Token)
+		if node.Name != "" {
+			o = append(o, field{"name", node.Name})
+		}
+		setList("parameters", identifiers(node.Parameters))
+		set("body", node.Body)
+	case *ast.CallExpression:
+		o = newObject("CallExpression", node.Token)
+		set("function", node.Function)
+		setList("arguments", expressions(node.Arguments))
+	case *ast.ArrayLiteral:
+		o = newObject("ArrayLiteral", node.Token)
+		setList("elements", expressions(node.Elements))
+	case *ast.IndexExpression:
+		o = newObject("IndexExpression", node.Token)
+		set("left", node.Left)
+		set("index", node.Index)
+	case *ast.MemberExpression:
+		o = newObject("MemberExpression", node.Token)
+		set("object", node.Object)
+		set("property", node.Property)
+	case *ast.HashLiteral:
+		o = newObject("HashLiteral", node.Token)
+		pairs := []interface{}{}
+		for _, pair := range node.Pairs {
+			key, err := convertChild(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := convertChild(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, object{{"key", key}, {"value", value}})
+		}
+		o = append(o, field{"pairs", pairs})
+	default:
+		return nil, fmt.Errorf("ast/json: unsupported node %T", node)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// convertChild converts a child that the parser may have left as nil, which
+// is encoded as null.
+func convertChild(node ast.Node) (interface{}, error) {
+	switch node := node.(type) {
+	case nil:
+		return nil, nil
+	case *ast.Identifier:
+		if node == nil {
+			return nil, nil
+		}
+	case *ast.BlockStatement:
+		if node == nil {
+			return nil, nil
+		}
+	case *ast.CallExpression:
+		if node == nil {
+			return nil, nil
+		}
+	}
+	return convert(node)
+}
+
+// SelectCase isn't a node itself, but is encoded like one
+func convertSelectCase(c ast.SelectCase) (interface{}, error) {
+	o := newObject("SelectCase", c.Token)
+	children := []struct {
+		key  string
+		node ast.Node
+	}{
+		{"variable", c.Var},
+		{"channel", c.Chan},
+		{"body", c.Body},
+	}
+	for _, child := range children {
+		v, err := convertChild(child.node)
+		if err != nil {
+			return nil, err
+		}
+		o = append(o, field{child.key, v})
+	}
+	return o, nil
+}
+
+// MatchArm isn't a node itself either
+func convertMatchArm(arm ast.MatchArm) (interface{}, error) {
+	o := newObject("MatchArm", arm.Token)
+	children := []struct {
+		key  string
+		node ast.Node
+	}{
+		{"pattern", arm.Pattern},
+		{"guard", arm.Guard},
+		{"body", arm.Body},
+	}
+	for _, child := range children {
+		v, err := convertChild(child.node)
+		if err != nil {
+			return nil, err
+		}
+		o = append(o, field{child.key, v})
+	}
+	return o, nil
+}
+
+// Nor is SwitchCase
+func convertSwitchCase(c ast.SwitchCase) (interface{}, error) {
+	o := newObject("SwitchCase", c.Token)
+	for _, child := range []struct {
+		key  string
+		node ast.Node
+	}{
+		{"value", c.Value},
+		{"body", c.Body},
+	} {
+		v, err := convertChild(child.node)
+		if err != nil {
+			return nil, err
+		}
+		o = append(o, field{child.key, v})
+	}
+	return o, nil
+}
+
+func statements(stmts []ast.Statement) []ast.Node {
+	nodes := make([]ast.Node, len(stmts))
+	for i, stmt := range stmts {
+		nodes[i] = stmt
+	}
+	return nodes
+}
+
+func expressions(exps []ast.Expression) []ast.Node {
+	nodes := make([]ast.Node, len(exps))
+	for i, exp := range exps {
+		nodes[i] = exp
+	}
+	return nodes
+}
+
+func identifiers(idents []*ast.Identifier) []ast.Node {
+	nodes := make([]ast.Node, len(idents))
+	for i, ident := range idents {
+		nodes[i] = ident
+	}
+	return nodes
+}
@@ -0,0 +1,51 @@
+package json
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/parser"
+)
+
+func TestMarshalFixture(t *testing.T) {
+	input, err := ioutil.ReadFile("testdata/program.mky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile("testdata/program.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(input)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser had errors: %v", p.Errors())
+	}
+
+	got, err := Marshal(program)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(got), bytes.TrimSpace(expected)) {
+		t.Errorf("JSON does not match testdata/program.json. got=\n%s", got)
+	}
+}
+
+// Embedding a node gives a type the node's methods, but Marshal won't know it
+type unknownExpression struct {
+	*ast.Identifier
+}
+
+func TestMarshalUnsupportedNode(t *testing.T) {
+	stmt := &ast.ExpressionStatement{Expression: unknownExpression{&ast.Identifier{Value: "x"}}}
+
+	_, err := Marshal(&ast.Program{Statements: []ast.Statement{stmt}})
+	if err == nil || err.Error() != "ast/json: unsupported node json.unknownExpression" {
+		t.Errorf("expected an unsupported node error, got=%v", err)
+	}
+}
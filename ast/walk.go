@@ -0,0 +1,169 @@
+package ast
+
+// Walk traverses the tree rooted at `node` depth first, calling `fn` for each
+// node. The children of a node are skipped when `fn` returns false.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, s := range node.Statements {
+			Walk(s, fn)
+		}
+	case *LetStatement:
+		walkIdentifier(node.Name, fn)
+		walkExpression(node.Value, fn)
+	case *DestructuringStatement:
+		walkExpression(node.Pattern, fn)
+		walkExpression(node.Value, fn)
+	case *DefaultPattern:
+		walkExpression(node.Target, fn)
+		walkExpression(node.Default, fn)
+	case *LetInExpression:
+		walkIdentifier(node.Name, fn)
+		walkExpression(node.Value, fn)
+		walkExpression(node.Body, fn)
+	case *RangeLiteral:
+		walkExpression(node.Low, fn)
+		walkExpression(node.High, fn)
+	case *MatchExpression:
+		walkExpression(node.Subject, fn)
+		for _, arm := range node.Arms {
+			walkExpression(arm.Pattern, fn)
+			walkExpression(arm.Guard, fn)
+			Walk(arm.Body, fn)
+		}
+		Walk(node.Default, fn)
+	case *SwitchExpression:
+		walkExpression(node.Subject, fn)
+		for _, c := range node.Cases {
+			walkExpression(c.Value, fn)
+			Walk(c.Body, fn)
+		}
+		Walk(node.Default, fn)
+	case *SpreadExpression:
+		walkExpression(node.Value, fn)
+	case *ReturnStatement:
+		walkExpression(node.ReturnValue, fn)
+	case *ExpressionStatement:
+		walkExpression(node.Expression, fn)
+	case *GoStatement:
+		if node.Call != nil {
+			Walk(node.Call, fn)
+		}
+	case *TypeAliasStatement:
+		walkIdentifier(node.Name, fn)
+		walkExpression(node.Type, fn)
+	case *WithStatement:
+		walkExpression(node.Init, fn)
+		walkIdentifier(node.Name, fn)
+		walkBlock(node.Body, fn)
+	case *TryCatchStatement:
+		walkBlock(node.Body, fn)
+		walkIdentifier(node.CatchName, fn)
+		walkBlock(node.Catch, fn)
+		walkBlock(node.Finally, fn)
+	case *ForStatement:
+		walkIdentifier(node.Variable, fn)
+		walkExpression(node.Iterable, fn)
+		walkBlock(node.Body, fn)
+	case *SelectStatement:
+		for _, c := range node.Cases {
+			walkExpression(c.Chan, fn)
+			walkIdentifier(c.Var, fn)
+			walkBlock(c.Body, fn)
+		}
+		walkBlock(node.Default, fn)
+	case *BlockStatement:
+		for _, s := range node.Statements {
+			Walk(s, fn)
+		}
+	case *PrefixExpression:
+		walkExpression(node.Right, fn)
+	case *InfixExpression:
+		walkExpression(node.Left, fn)
+		walkExpression(node.Right, fn)
+	case *IfExpression:
+		walkExpression(node.Condition, fn)
+		walkBlock(node.Consequence, fn)
+		walkBlock(node.Alternative, fn)
+	case *TryExpression:
+		walkExpression(node.Body, fn)
+	case *TernaryExpression:
+		walkExpression(node.Condition, fn)
+		walkExpression(node.Consequence, fn)
+		walkExpression(node.Alternative, fn)
+	case *FunctionLiteral:
+		for _, p := range node.Parameters {
+			walkIdentifier(p, fn)
+		}
+		walkBlock(node.Body, fn)
+	case *CallExpression:
+		walkExpression(node.Function, fn)
+		for _, a := range node.Arguments {
+			walkExpression(a, fn)
+		}
+	case *ArrayLiteral:
+		for _, el := range node.Elements {
+			walkExpression(el, fn)
+		}
+	case *IndexExpression:
+		walkExpression(node.Left, fn)
+		walkExpression(node.Index, fn)
+	case *MemberExpression:
+		walkExpression(node.Object, fn)
+		walkIdentifier(node.Property, fn)
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			walkExpression(pair.Key, fn)
+			walkExpression(pair.Value, fn)
+		}
+	}
+}
+
+// PatternNames returns the identifiers a destructuring pattern binds, in the
+// order they appear, leaving out the expressions in its defaults
+func PatternNames(pattern Expression) []*Identifier {
+	switch pattern := pattern.(type) {
+	case *Identifier:
+		return []*Identifier{pattern}
+	case *ArrayLiteral:
+		names := []*Identifier{}
+		for _, el := range pattern.Elements {
+			names = append(names, PatternNames(el)...)
+		}
+		return names
+	case *HashLiteral:
+		names := []*Identifier{}
+		for _, pair := range pattern.Pairs {
+			names = append(names, PatternNames(pair.Value)...)
+		}
+		return names
+	case *DefaultPattern:
+		return PatternNames(pattern.Target)
+	}
+	return nil
+}
+
+// The parser can leave nil children behind when it hits an error. A nil
+// pointer passed as a Node wouldn't compare equal to nil in Walk, so the typed
+// children are checked before they are walked.
+func walkExpression(exp Expression, fn func(Node) bool) {
+	if exp != nil {
+		Walk(exp, fn)
+	}
+}
+
+func walkIdentifier(ident *Identifier, fn func(Node) bool) {
+	if ident != nil {
+		Walk(ident, fn)
+	}
+}
+
+func walkBlock(block *BlockStatement, fn func(Node) bool) {
+	if block != nil {
+		Walk(block, fn)
+	}
+}
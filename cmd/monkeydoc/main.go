@@ -0,0 +1,98 @@
+// Command monkeydoc writes Markdown documentation for Monkey programs.
+//
+//	monkeydoc file.mky ...
+//
+// Every top level let with a `##` doc comment gets a section holding its
+// signature and its documentation, in the order they appear in the file.
+// With no files it reads stdin. It exits with 1 if a file couldn't be read
+// or parsed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/format"
+	"github.com/vishen/go-monkeylang/parser"
+)
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		os.Exit(docFile("", os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	code := 0
+	for i, path := range flag.Args() {
+		if i > 0 {
+			io.WriteString(os.Stdout, "\n")
+		}
+		if c := docFile(path, nil, os.Stdout, os.Stderr); c > code {
+			code = c
+		}
+	}
+	os.Exit(code)
+}
+
+// docFile writes the documentation for the program in `path`, or `stdin` if
+// there is no path, to `out`. Files get a heading with their name. It returns
+// the process exit code.
+func docFile(path string, stdin io.Reader, out, errOut io.Writer) int {
+	var (
+		input []byte
+		err   error
+	)
+	if path == "" {
+		input, err = ioutil.ReadAll(stdin)
+	} else {
+		input, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+	name := path
+	if name == "" {
+		name = "<stdin>"
+	}
+
+	program, errs := parser.ParseString(string(input))
+	if len(errs) > 0 {
+		fmt.Fprintf(errOut, "%s: %s\n", name, strings.Join(errs, "; "))
+		return 1
+	}
+
+	sections := []string{}
+	if path != "" {
+		sections = append(sections, "# "+filepath.Base(path)+"\n")
+	}
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let.Doc != "" {
+			sections = append(sections, section(let))
+		}
+	}
+	io.WriteString(out, strings.Join(sections, "\n"))
+	return 0
+}
+
+// section documents one binding, with the parameters of a function or the
+// value of anything else as its signature
+func section(let *ast.LetStatement) string {
+	signature := "let " + let.Name.Value + " = " + format.Node(let.Value)
+	if fn, ok := let.Value.(*ast.FunctionLiteral); ok {
+		params := []string{}
+		for _, p := range fn.Parameters {
+			params = append(params, p.Value)
+		}
+		signature = "fn " + let.Name.Value + "(" + strings.Join(params, ", ") + ")"
+	}
+
+	return fmt.Sprintf("## %s\n\n```\n%s\n```\n\n%s\n", let.Name.Value, signature, let.Doc)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocFile(t *testing.T) {
+	tests := []struct {
+		input    string
+		code     int
+		expected string
+	}{
+		{
+			"## Adds one\n## to x.\nlet inc = fn(x) { x + 1 };\nlet hidden = 1;\n## The answer.\nlet answer = 6*7;\n## Halves x.\nfn half(x) { x / 2 }",
+			0,
+			"## inc\n\n```\nfn inc(x)\n```\n\nAdds one\nto x.\n\n" +
+				"## answer\n\n```\nlet answer = 6 * 7\n```\n\nThe answer.\n\n" +
+				"## half\n\n```\nfn half(x)\n```\n\nHalves x.\n",
+		},
+		{"let x = 1;", 0, ""},
+		{"let = 1", 1, ""},
+	}
+
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		code := docFile("", strings.NewReader(tt.input), &out, &errOut)
+		if code != tt.code {
+			t.Errorf("wrong exit code for %q. expected=%d, got=%d (%s)", tt.input, tt.code, code, errOut.String())
+		}
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+}
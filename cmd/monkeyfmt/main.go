@@ -0,0 +1,101 @@
+// Command monkeyfmt formats Monkey programs with the format package.
+//
+//	monkeyfmt file.mky          print the formatted file
+//	monkeyfmt -w file.mky       rewrite the file in place
+//	monkeyfmt --diff file.mky   print a unified diff of what would change
+//
+// Comments are dropped, apart from `##` doc comments on let statements, so -w
+// and --diff refuse files with any other comments rather than lose them.
+//
+// With no files it formats stdin. It exits with 1 when --diff finds a file
+// that isn't formatted, so it can be used in a pre-commit hook, and with 2 if
+// a file couldn't be read or parsed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/vishen/go-monkeylang/format"
+	"github.com/vishen/go-monkeylang/lexer"
+)
+
+var (
+	write = flag.Bool("w", false, "write the formatted source back to each file instead of printing it")
+	diff  = flag.Bool("diff", false, "print a unified diff of the changes instead of the formatted source")
+)
+
+func main() {
+	flag.Parse()
+
+	if *write && *diff {
+		fmt.Fprintln(os.Stderr, "-w and --diff can't be used together")
+		os.Exit(2)
+	}
+
+	if flag.NArg() == 0 {
+		os.Exit(formatFile("", os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	code := 0
+	for _, path := range flag.Args() {
+		if c := formatFile(path, nil, os.Stdout, os.Stderr); c > code {
+			code = c
+		}
+	}
+	os.Exit(code)
+}
+
+// formatFile formats the program in `path`, or `stdin` if there is no path,
+// acting on the -w and --diff flags. It returns the process exit code.
+func formatFile(path string, stdin io.Reader, out, errOut io.Writer) int {
+	var (
+		input []byte
+		err   error
+	)
+	if path == "" {
+		input, err = ioutil.ReadAll(stdin)
+	} else {
+		input, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+	name := path
+	if name == "" {
+		name = "<stdin>"
+	}
+
+	formatted, err := format.Source(string(input))
+	if err != nil {
+		fmt.Fprintf(errOut, "%s: %s\n", name, err)
+		return 2
+	}
+
+	if (*diff || (*write && path != "")) && lexer.HasComments(string(input)) {
+		fmt.Fprintf(errOut, "%s: has comments that formatting would remove, only ## doc comments are kept\n", name)
+		return 2
+	}
+
+	switch {
+	case *diff:
+		if d := unifiedDiff(name, string(input), formatted); d != "" {
+			io.WriteString(out, d)
+			return 1
+		}
+	case *write && path != "":
+		if formatted != string(input) {
+			if err := ioutil.WriteFile(path, []byte(formatted), 0644); err != nil {
+				fmt.Fprintln(errOut, err)
+				return 2
+			}
+		}
+	default:
+		io.WriteString(out, formatted)
+	}
+	return 0
+}
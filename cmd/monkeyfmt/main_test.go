@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{"a\n", "a\n", ""},
+		{
+			"a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n",
+			"a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nL\nm\nn\n",
+			"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -9,5 +9,6 @@\n i\n j\n k\n-l\n+L\n m\n+n\n",
+		},
+		{
+			"a\nb\nc\nd\ne\nf\ng\n",
+			"a\nB\nc\nd\ne\nf\nG\n",
+			"@@ -1,7 +1,7 @@\n a\n-b\n+B\n c\n d\n e\n f\n-g\n+G\n",
+		},
+		{"one", "one\n", "@@ -1 +1 @@\n-one\n\\ No newline at end of file\n+one\n"},
+		{"", "one\n", "@@ -0,0 +1 @@\n+one\n"},
+	}
+
+	for _, tt := range tests {
+		expected := tt.expected
+		if expected != "" {
+			expected = "--- f.mky.orig\n+++ f.mky\n" + expected
+		}
+		if got := unifiedDiff("f.mky", tt.a, tt.b); got != expected {
+			t.Errorf("wrong diff of %q and %q. expected=\n%s\ngot=\n%s", tt.a, tt.b, expected, got)
+		}
+	}
+}
+
+func TestFormatFileDiff(t *testing.T) {
+	*diff = true
+	defer func() { *diff = false }()
+
+	tests := []struct {
+		input    string
+		code     int
+		expected string
+	}{
+		{"let x = 1;\n", 0, ""},
+		{"let x=1", 1, "--- <stdin>.orig\n+++ <stdin>\n@@ -1 +1 @@\n-let x=1\n\\ No newline at end of file\n+let x = 1;\n"},
+		{"let = 1", 2, ""},
+		{"let x=1 # one\n", 2, ""},
+	}
+
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		code := formatFile("", strings.NewReader(tt.input), &out, &errOut)
+		if code != tt.code {
+			t.Errorf("wrong exit code for %q. expected=%d, got=%d (%s)", tt.input, tt.code, code, errOut.String())
+		}
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+}
+
+func TestFormatFileWriteKeepsComments(t *testing.T) {
+	*write = true
+	defer func() { *write = false }()
+
+	dir, err := ioutil.TempDir("", "monkeyfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "commented.mky")
+	input := "// Adds one\nlet x=1 /* kept */\n"
+	if err := ioutil.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := formatFile(path, nil, &out, &errOut); code != 2 {
+		t.Errorf("wrong exit code. expected=2, got=%d", code)
+	}
+	expected := path + ": has comments that formatting would remove, only ## doc comments are kept\n"
+	if errOut.String() != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, errOut.String())
+	}
+	if contents, _ := ioutil.ReadFile(path); string(contents) != input {
+		t.Errorf("file was rewritten. got=%q", contents)
+	}
+}
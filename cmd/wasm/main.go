@@ -0,0 +1,41 @@
+//go:build js && wasm
+
+// Command wasm builds the interpreter for the browser:
+//
+//	GOOS=js GOARCH=wasm go build -o monkey.wasm ./cmd/wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" cmd/wasm/
+//
+// then serve cmd/wasm over HTTP and open index.html. Older Go releases keep
+// wasm_exec.js in misc/wasm instead of lib/wasm.
+package main
+
+import (
+	"bytes"
+	"syscall/js"
+
+	"github.com/vishen/go-monkeylang/interpreter"
+	"github.com/vishen/go-monkeylang/repl"
+)
+
+// Shared by every call so variables are kept between them, like the REPL
+var interp = interpreter.New()
+
+// RunString evaluates `src` and returns what the REPL would have printed for
+// it. Anything written with `puts` goes to the JavaScript console.
+func RunString(src string) string {
+	var out bytes.Buffer
+	repl.Run(&out, interp, src, false)
+	return out.String()
+}
+
+func main() {
+	js.Global().Get("Go").Set("RunMonkey", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 {
+			return "RunMonkey expects one argument, the source to run"
+		}
+		return RunString(args[0].String())
+	}))
+
+	// Keep the Go runtime alive so RunMonkey can still be called
+	select {}
+}
@@ -0,0 +1,450 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// flatten(arr) returns the elements of `arr` with every nested array replaced
+// by its elements. flatten(arr, depth) only flattens `depth` levels.
+func builtinFlatten(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `flatten` must be ARRAY, got %s", args[0].Type())
+	}
+
+	depth := int64(-1) // No limit
+	if len(args) == 2 {
+		d, ok := args[1].(*object.Integer)
+		if !ok {
+			return newError("depth for `flatten` must be INTEGER, got %s", args[1].Type())
+		}
+		if d.Value < 0 {
+			return newError("depth for `flatten` must not be negative, got %d", d.Value)
+		}
+		depth = d.Value
+	}
+
+	elements, err := flattenInto(nil, array, depth, map[*object.Array]bool{})
+	if err != nil {
+		return err
+	}
+	return e.track(&object.Array{Elements: elements})
+}
+
+// flattenInto appends the elements of `array` to `elements`, flattening nested
+// arrays until `depth` reaches 0. `seen` holds the arrays being flattened, as
+// with no depth limit an array that contains itself would never finish.
+func flattenInto(elements []object.Object, array *object.Array, depth int64, seen map[*object.Array]bool) ([]object.Object, *object.Error) {
+	if seen[array] {
+		return nil, newError("cannot flatten circular reference: ARRAY")
+	}
+	seen[array] = true
+	defer delete(seen, array)
+
+	for _, el := range array.Elements {
+		nested, ok := el.(*object.Array)
+		if !ok || depth == 0 {
+			elements = append(elements, el)
+			continue
+		}
+
+		var err *object.Error
+		if elements, err = flattenInto(elements, nested, depth-1, seen); err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
+// chunk(arr, size) splits `arr` into arrays of `size` elements. The last one
+// holds what's left over, so it can be shorter.
+func builtinChunk(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `chunk` must be ARRAY, got %s", args[0].Type())
+	}
+	size, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("size for `chunk` must be INTEGER, got %s", args[1].Type())
+	}
+	if size.Value <= 0 {
+		return newError("size for `chunk` must be positive, got %d", size.Value)
+	}
+
+	count := int64(1)
+	if size.Value < int64(len(array.Elements)) {
+		count = (int64(len(array.Elements)) + size.Value - 1) / size.Value
+	}
+	if err := e.reserve(8 * (int64(len(array.Elements)) + count)); err != nil {
+		return err
+	}
+
+	chunks := []object.Object{}
+	for low := 0; low < len(array.Elements); low += int(size.Value) {
+		high := low + int(size.Value)
+		if high > len(array.Elements) || high < low {
+			high = len(array.Elements)
+		}
+
+		chunk := e.track(&object.Array{Elements: append([]object.Object{}, array.Elements[low:high]...)})
+		if isError(chunk) {
+			return chunk
+		}
+		chunks = append(chunks, chunk)
+	}
+	return e.track(&object.Array{Elements: chunks})
+}
+
+// take(arr, n) returns the first `n` elements of `arr`, or all of them if
+// there are fewer than `n`
+func builtinTake(e *Evaluator, args ...object.Object) object.Object {
+	array, n, err := arrayAndCount("take", args)
+	if err != nil {
+		return err
+	}
+
+	return e.track(&object.Array{Elements: append([]object.Object{}, array.Elements[:n]...)})
+}
+
+// drop(arr, n) returns the elements of `arr` after the first `n`
+func builtinDrop(e *Evaluator, args ...object.Object) object.Object {
+	array, n, err := arrayAndCount("drop", args)
+	if err != nil {
+		return err
+	}
+
+	return e.track(&object.Array{Elements: append([]object.Object{}, array.Elements[n:]...)})
+}
+
+// arrayAndCount checks the built-in `name` was called with an array and a
+// non-negative integer, which is capped at the length of the array
+func arrayAndCount(name string, args []object.Object) (*object.Array, int, *object.Error) {
+	if len(args) != 2 {
+		return nil, 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, 0, newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	n, ok := args[1].(*object.Integer)
+	if !ok {
+		return nil, 0, newError("count for `%s` must be INTEGER, got %s", name, args[1].Type())
+	}
+	if n.Value < 0 {
+		return nil, 0, newError("count for `%s` must not be negative, got %d", name, n.Value)
+	}
+
+	if n.Value > int64(len(array.Elements)) {
+		return array, len(array.Elements), nil
+	}
+	return array, int(n.Value), nil
+}
+
+// takeWhile(arr, pred) returns the elements at the front of `arr` that `pred`
+// is truthy for, stopping at the first that it isn't
+func builtinTakeWhile(e *Evaluator, args ...object.Object) object.Object {
+	array, n, err := e.countWhile("takeWhile", args)
+	if err != nil {
+		return err
+	}
+
+	return e.track(&object.Array{Elements: append([]object.Object{}, array.Elements[:n]...)})
+}
+
+// dropWhile(arr, pred) returns the elements of `arr` from the first that
+// `pred` is falsy for
+func builtinDropWhile(e *Evaluator, args ...object.Object) object.Object {
+	array, n, err := e.countWhile("dropWhile", args)
+	if err != nil {
+		return err
+	}
+
+	return e.track(&object.Array{Elements: append([]object.Object{}, array.Elements[n:]...)})
+}
+
+// countWhile returns the number of elements at the front of the array in
+// args[0] that the predicate in args[1] is truthy for. The predicate isn't
+// called on the elements after the first that it's falsy for.
+func (e *Evaluator) countWhile(name string, args []object.Object) (*object.Array, int, object.Object) {
+	if len(args) != 2 {
+		return nil, 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, 0, newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+
+	for i, el := range array.Elements {
+		result := e.applyFunction(args[1], []object.Object{el})
+		if isError(result) {
+			return nil, 0, result
+		}
+		if !isTruthy(result) {
+			return array, i, nil
+		}
+	}
+	return array, len(array.Elements), nil
+}
+
+// unique(arr) returns the elements of `arr` without the ones equal to an
+// earlier element
+func builtinUnique(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	return e.uniqueBy("unique", args[0], func(el object.Object) object.Object { return el })
+}
+
+// uniqueBy(arr, fn) returns the elements of `arr` without the ones that `fn`
+// gives the same key as an earlier element
+func builtinUniqueBy(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	return e.uniqueBy("uniqueBy", args[0], func(el object.Object) object.Object {
+		return e.applyFunction(args[1], []object.Object{el})
+	})
+}
+
+// uniqueBy keeps the first element of `arg` for each distinct key, comparing
+// keys with object.DeepEqual
+func (e *Evaluator) uniqueBy(name string, arg object.Object, key func(object.Object) object.Object) object.Object {
+	array, ok := arg.(*object.Array)
+	if !ok {
+		return newError("argument to `%s` must be ARRAY, got %s", name, arg.Type())
+	}
+
+	elements := []object.Object{}
+	keys := []object.Object{}
+	for _, el := range array.Elements {
+		k := key(el)
+		if isError(k) {
+			return k
+		}
+		if !containsEqual(keys, k) {
+			keys = append(keys, k)
+			elements = append(elements, el)
+		}
+	}
+	return e.track(&object.Array{Elements: elements})
+}
+
+func containsEqual(objs []object.Object, obj object.Object) bool {
+	for _, o := range objs {
+		if object.DeepEqual(o, obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// zip(a, b, ...) returns an array of arrays, the first holding the first
+// element of each argument, the second the second, and so on. It stops at the
+// end of the shortest argument.
+func builtinZip(e *Evaluator, args ...object.Object) object.Object {
+	arrays := make([]*object.Array, len(args))
+	for i, arg := range args {
+		array, ok := arg.(*object.Array)
+		if !ok {
+			return newError("arguments to `zip` must be ARRAY, got %s", arg.Type())
+		}
+		arrays[i] = array
+	}
+
+	return e.zip(arrays)
+}
+
+// unzip(arrays) is the reverse of zip, so unzip([[1, "a"], [2, "b"]]) returns
+// [[1, 2], ["a", "b"]]
+func builtinUnzip(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	outer, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `unzip` must be ARRAY, got %s", args[0].Type())
+	}
+
+	arrays := make([]*object.Array, len(outer.Elements))
+	for i, el := range outer.Elements {
+		array, ok := el.(*object.Array)
+		if !ok {
+			return newError("elements of the argument to `unzip` must be ARRAY, got %s", el.Type())
+		}
+		arrays[i] = array
+	}
+
+	return e.zip(arrays)
+}
+
+func (e *Evaluator) zip(arrays []*object.Array) object.Object {
+	length := 0
+	for i, array := range arrays {
+		if i == 0 || len(array.Elements) < length {
+			length = len(array.Elements)
+		}
+	}
+
+	if err := e.reserve(8 * int64(length) * int64(len(arrays)+1)); err != nil {
+		return err
+	}
+
+	tuples := make([]object.Object, length)
+	for i := range tuples {
+		tuple := make([]object.Object, len(arrays))
+		for j, array := range arrays {
+			tuple[j] = array.Elements[i]
+		}
+		tuples[i] = e.track(&object.Array{Elements: tuple})
+		if isError(tuples[i]) {
+			return tuples[i]
+		}
+	}
+	return e.track(&object.Array{Elements: tuples})
+}
+
+// groupBy(arr, fn) returns a hash from each result of calling `fn` on the
+// elements of `arr` to the elements that gave it. Results are turned into
+// string keys with Inspect, so `true` and "true" share a group.
+func builtinGroupBy(e *Evaluator, args ...object.Object) object.Object {
+	return e.aggregate("groupBy", args, func(group, el object.Object) object.Object {
+		if group == nil {
+			return &object.Array{Elements: []object.Object{el}}
+		}
+		array := group.(*object.Array)
+		array.Elements = append(array.Elements, el)
+		return array
+	})
+}
+
+// countBy(arr, fn) is groupBy, but counts the elements in each group
+func builtinCountBy(e *Evaluator, args ...object.Object) object.Object {
+	return e.aggregate("countBy", args, func(count, el object.Object) object.Object {
+		if count == nil {
+			return object.NewInteger(1)
+		}
+		return object.NewInteger(count.(*object.Integer).Value + 1)
+	})
+}
+
+// aggregate calls `fn` on each element of the array in args[0] and folds the
+// element into the hash value for the result with `add`. `add` is passed nil
+// for the first element of a group.
+func (e *Evaluator) aggregate(name string, args []object.Object, add func(group, el object.Object) object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+
+	hash := object.NewHash()
+	for _, el := range array.Elements {
+		result := e.applyFunction(args[1], []object.Object{el})
+		if isError(result) {
+			return result
+		}
+
+		key := &object.String{Value: result.Inspect()}
+		group, _ := hash.Get(key)
+		hash.Set(key, add(group, el))
+	}
+
+	for _, pair := range hash.Entries() {
+		if tracked := e.track(pair.Value); isError(tracked) {
+			return tracked
+		}
+	}
+	return e.track(hash)
+}
+
+// any(arr, pred) reports whether `pred` is truthy for an element of `arr`. It
+// stops calling `pred` at the first element that it's truthy for.
+func builtinAny(e *Evaluator, args ...object.Object) object.Object {
+	return e.quantify("any", args, true)
+}
+
+// all(arr, pred) reports whether `pred` is truthy for every element of `arr`.
+// It stops calling `pred` at the first element that it's falsy for.
+func builtinAll(e *Evaluator, args ...object.Object) object.Object {
+	return e.quantify("all", args, false)
+}
+
+// none(arr, pred) reports whether `pred` is falsy for every element of `arr`.
+// It stops calling `pred` at the first element that it's truthy for.
+func builtinNone(e *Evaluator, args ...object.Object) object.Object {
+	found := e.quantify("none", args, true)
+	if isError(found) {
+		return found
+	}
+	return nativeBoolToBooleanObject(found == FALSE)
+}
+
+// count(arr, pred) returns the number of elements of `arr` that `pred` is
+// truthy for. Without a predicate, or with a null one, it counts every
+// element.
+func builtinCount(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `count` must be ARRAY, got %s", args[0].Type())
+	}
+
+	if len(args) == 1 || args[1] == NULL {
+		return object.NewInteger(int64(len(array.Elements)))
+	}
+
+	count := int64(0)
+	for _, el := range array.Elements {
+		result := e.applyFunction(args[1], []object.Object{el})
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			count++
+		}
+	}
+	return object.NewInteger(count)
+}
+
+// quantify calls the predicate in args[1] on the elements of the array in
+// args[0] until it returns `stopAt`, returning whether it did
+func (e *Evaluator) quantify(name string, args []object.Object, stopAt bool) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+
+	for _, el := range array.Elements {
+		result := e.applyFunction(args[1], []object.Object{el})
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) == stopAt {
+			return nativeBoolToBooleanObject(stopAt)
+		}
+	}
+	return nativeBoolToBooleanObject(!stopAt)
+}
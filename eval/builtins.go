@@ -0,0 +1,589 @@
+package eval
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/format"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// builtinFunc is the signature of every built-in. The evaluator that looked up
+// the built-in is passed in so built-ins can call back into Monkey functions.
+type builtinFunc func(e *Evaluator, args ...object.Object) object.Object
+
+// Populated in init() as some built-ins end up calling back into `Eval`, which
+// would otherwise be an initialisation loop. Plugins can add to builtins and
+// modules at any time, so all three are guarded by builtinsMu.
+var (
+	builtins   map[string]builtinFunc
+	modules    = map[string]*object.Module{}
+	registered = map[string]bool{} // Built-ins added by RegisterBuiltin
+	builtinsMu sync.RWMutex
+)
+
+func init() {
+	builtins = map[string]builtinFunc{
+		"puts":     builtinPuts,
+		"printErr": builtinPrintErr,
+		"read":     builtinRead,
+		"import":   builtinImport,
+		"debug":    (*Evaluator).builtinDebug,
+		"clone":    builtinClone,
+		"freeze":   builtinFreeze,
+		"isFrozen": builtinIsFrozen,
+		"weakRef":  builtinWeakRef,
+		"deref":    builtinDeref,
+
+		"stackTrace": builtinStackTrace,
+		"__line__":   builtinLine,
+		"__col__":    builtinCol,
+		"source":     builtinSource,
+		"repeat":     builtinRepeat,
+		"hash":       builtinHash,
+		"comparable": builtinComparable,
+		"flatten":    builtinFlatten,
+		"chunk":      builtinChunk,
+		"take":       builtinTake,
+		"drop":       builtinDrop,
+		"takeWhile":  builtinTakeWhile,
+		"dropWhile":  builtinDropWhile,
+		"unique":     builtinUnique,
+		"uniqueBy":   builtinUniqueBy,
+
+		"mapKeys":   builtinMapKeys,
+		"mapValues": builtinMapValues,
+		"mergeHash": builtinMergeHash,
+		"toPairs":   builtinToPairs,
+		"fromPairs": builtinFromPairs,
+		"zip":       builtinZip,
+		"unzip":     builtinUnzip,
+		"groupBy":   builtinGroupBy,
+		"countBy":   builtinCountBy,
+		"any":       builtinAny,
+		"all":       builtinAll,
+		"none":      builtinNone,
+		"count":     builtinCount,
+
+		"contains":   builtinContains,
+		"startsWith": builtinStartsWith,
+		"endsWith":   builtinEndsWith,
+		"indexOf":    builtinIndexOf,
+
+		"sleep":  builtinSleep,
+		"chan":   builtinChan,
+		"send":   builtinSend,
+		"recv":   builtinRecv,
+		"close":  builtinClose,
+		"wg":     builtinWaitGroup,
+		"wgAdd":  builtinWaitGroupAdd,
+		"wgDone": builtinWaitGroupDone,
+		"wgWait": builtinWaitGroupWait,
+
+		"mutex":    builtinMutex,
+		"lock":     builtinLock,
+		"unlock":   builtinUnlock,
+		"withLock": builtinWithLock,
+
+		"spawn":   builtinSpawn,
+		"await":   builtinAwait,
+		"isReady": builtinIsReady,
+
+		"semaphore":     builtinSemaphore,
+		"acquire":       builtinAcquire,
+		"release":       builtinRelease,
+		"withSemaphore": builtinWithSemaphore,
+
+		"observable":  builtinObservable,
+		"subscribe":   builtinSubscribe,
+		"unsubscribe": builtinUnsubscribe,
+		"map":         builtinMap,
+		"filter":      builtinFilter,
+	}
+}
+
+// RegisterBuiltin makes `fn` available to every evaluator as `name`,
+// replacing any built-in that already has that name
+func RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+
+	builtins[name] = func(e *Evaluator, args ...object.Object) object.Object {
+		return fn(args...)
+	}
+	registered[name] = true
+}
+
+// RegisterModule makes `bindings` available to Monkey programs through
+// `import(name)`
+func RegisterModule(name string, bindings map[string]object.Object) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+
+	modules[name] = &object.Module{Name: name, Bindings: bindings}
+}
+
+func (e *Evaluator) lookupBuiltin(name string) (*object.Builtin, bool) {
+	builtinsMu.RLock()
+	fn, ok := builtins[name]
+	external := registered[name]
+	builtinsMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	if !e.builtinAllowed(name, external) {
+		return unavailableBuiltin(name), true
+	}
+
+	return &object.Builtin{
+		Name: name,
+		Fn: func(args ...object.Object) object.Object {
+			return fn(e, args...)
+		},
+	}, true
+}
+
+// puts(args...) writes each argument on its own line
+func builtinPuts(e *Evaluator, args ...object.Object) object.Object {
+	for _, arg := range args {
+		io.WriteString(e.stdout, arg.Inspect()+"\n")
+	}
+
+	return NULL
+}
+
+// printErr(args...) writes each argument on its own line to stderr
+func builtinPrintErr(e *Evaluator, args ...object.Object) object.Object {
+	for _, arg := range args {
+		io.WriteString(e.stderr, arg.Inspect()+"\n")
+	}
+
+	return NULL
+}
+
+// read(prompt) writes `prompt` to stdout, then returns the next line of stdin
+// without surrounding whitespace, or null at the end of the input. The prompt
+// is optional.
+func builtinRead(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+
+	if len(args) == 1 {
+		prompt, ok := args[0].(*object.String)
+		if !ok {
+			return newError("argument to `read` must be STRING, got %s", args[0].Type())
+		}
+		io.WriteString(e.stdout, prompt.Value)
+		if f, ok := e.stdout.(interface{ Flush() error }); ok {
+			f.Flush()
+		}
+	}
+
+	line, err := readLine(e.stdin)
+	if err != nil && line == "" {
+		return NULL
+	}
+	return &object.String{Value: strings.TrimSpace(line)}
+}
+
+// readLine reads up to and including the next newline a byte at a time, so
+// nothing after the line is consumed from `r`
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			line = append(line, b[0])
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+		}
+		if err != nil {
+			return string(line), err
+		}
+	}
+}
+
+// import(name) returns the module registered as `name`
+func builtinImport(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	name, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to `import` must be STRING, got %s", args[0].Type())
+	}
+
+	if !e.moduleAllowed(name.Value) {
+		return newError("module not available in sandbox: %s", name.Value)
+	}
+
+	builtinsMu.RLock()
+	module, ok := modules[name.Value]
+	builtinsMu.RUnlock()
+	if !ok {
+		return newError("module not found: %s", name.Value)
+	}
+	return module
+}
+
+// debug(fn, args...) calls `fn` with `args`, pausing before each statement
+func (e *Evaluator) builtinDebug(args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want>=1", len(args))
+	}
+
+	if e.debugger == nil {
+		e.debugger = newDebugger(e.stdin, e.stdout)
+		defer func() { e.debugger = nil }()
+	}
+
+	return e.applyFunction(args[0], args[1:])
+}
+
+// clone(val) returns a deep copy of arrays and hashes
+func builtinClone(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	return e.cloneObject(args[0], map[object.Object]bool{})
+}
+
+// `seen` holds the arrays and hashes currently being copied, so finding one
+// of them again means the value refers back to itself.
+func (e *Evaluator) cloneObject(obj object.Object, seen map[object.Object]bool) object.Object {
+	switch obj := obj.(type) {
+	case *object.Array:
+		if seen[obj] {
+			return newError("cannot clone circular reference: ARRAY")
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		elements := make([]object.Object, len(obj.Elements))
+		for i, el := range obj.Elements {
+			elements[i] = e.cloneObject(el, seen)
+			if isError(elements[i]) {
+				return elements[i]
+			}
+		}
+		return e.track(&object.Array{Elements: elements})
+	case *object.Hash:
+		if seen[obj] {
+			return newError("cannot clone circular reference: HASH")
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		hash := object.NewHash()
+		for _, pair := range obj.Entries() {
+			value := e.cloneObject(pair.Value, seen)
+			if isError(value) {
+				return value
+			}
+			hash.Set(pair.Key.(object.Hashable), value)
+		}
+		return e.track(hash)
+	default:
+		// Everything else is immutable
+		return obj
+	}
+}
+
+// freeze(obj) makes an array or hash, and everything nested in it, immutable
+func builtinFreeze(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	freezeObject(args[0])
+	return args[0]
+}
+
+func freezeObject(obj object.Object) {
+	switch obj := obj.(type) {
+	case *object.Array:
+		if obj.IsFrozen {
+			return
+		}
+		obj.IsFrozen = true
+		for _, el := range obj.Elements {
+			freezeObject(el)
+		}
+	case *object.Hash:
+		if obj.IsFrozen {
+			return
+		}
+		obj.IsFrozen = true
+		for _, pair := range obj.Pairs {
+			freezeObject(pair.Value)
+		}
+	}
+}
+
+// isFrozen(obj) returns false for arrays and hashes that can still be changed
+func builtinIsFrozen(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch obj := args[0].(type) {
+	case *object.Array:
+		return nativeBoolToBooleanObject(obj.IsFrozen)
+	case *object.Hash:
+		return nativeBoolToBooleanObject(obj.IsFrozen)
+	default:
+		return TRUE
+	}
+}
+
+// weakRef(obj) returns a reference to `obj` that doesn't keep it alive
+func builtinWeakRef(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	return &object.WeakRef{Value: args[0]}
+}
+
+// deref(wref) returns the referenced object, or null if it has been collected
+func builtinDeref(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	ref, ok := args[0].(*object.WeakRef)
+	if !ok {
+		return newError("argument to `deref` must be WEAK_REF, got %s", args[0].Type())
+	}
+
+	if obj, ok := ref.Deref(); ok {
+		return obj
+	}
+	return NULL
+}
+
+// stackTrace(err) returns the calls that led to `err`, an error caught by
+// `try`, as an array of hashes with "function", "line" and "col" keys,
+// innermost call first
+func builtinStackTrace(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	var errObj *object.Error
+	switch arg := args[0].(type) {
+	case *object.Error:
+		errObj = arg
+	case *object.ErrorValue:
+		errObj = arg.Error
+	default:
+		return newError("argument to `stackTrace` must be ERROR_VALUE, got %s", args[0].Type())
+	}
+
+	frames := []object.Object{}
+	for _, frame := range errObj.StackTrace {
+		hash := object.NewHash()
+		hash.Set(object.InternString("function"), &object.String{Value: frame.FunctionName})
+		hash.Set(object.InternString("line"), object.NewInteger(int64(frame.Line)))
+		hash.Set(object.InternString("col"), object.NewInteger(int64(frame.Col)))
+		frames = append(frames, hash)
+	}
+
+	return &object.Array{Elements: frames}
+}
+
+// __line__() returns the line it was called on
+func builtinLine(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+
+	return object.NewInteger(int64(e.callSite().Line))
+}
+
+// __col__() returns the column it was called at
+func builtinCol(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+
+	return object.NewInteger(int64(e.callSite().Col))
+}
+
+// source(fn) returns the formatted source of `fn`, after its `##` doc comment
+// if it has one, or "<built-in>" for a built-in
+func builtinSource(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch fn := args[0].(type) {
+	case *object.Function:
+		literal := fn.Literal
+		if literal == nil {
+			literal = &ast.FunctionLiteral{Parameters: fn.Parameters, Body: fn.Body}
+		}
+		src := format.Node(literal)
+		if literal.Doc != "" {
+			src = strings.Join(format.DocLines(literal.Doc), "\n") + "\n" + src
+		}
+		return &object.String{Value: src}
+	case *object.Builtin:
+		return &object.String{Value: "<built-in>"}
+	default:
+		return newError("argument to `source` must be FUNCTION, got %s", args[0].Type())
+	}
+}
+
+// maxRepeatLength caps the bytes or elements `repeat` will build, so a huge
+// count fails with an error rather than exhausting memory
+const maxRepeatLength = 1 << 28
+
+// repeat(val, n) returns a new string or array holding `val` repeated `n`
+// times
+func builtinRepeat(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	n, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("count for `repeat` must be INTEGER, got %s", args[1].Type())
+	}
+	if n.Value < 0 {
+		return newError("count for `repeat` must not be negative, got %d", n.Value)
+	}
+
+	switch val := args[0].(type) {
+	case *object.String:
+		if len(val.Value) == 0 {
+			return val
+		}
+		if n.Value > maxRepeatLength/int64(len(val.Value)) {
+			return newError("result of `repeat` is too large")
+		}
+		if err := e.reserve(int64(len(val.Value)) * n.Value); err != nil {
+			return err
+		}
+		return e.track(&object.String{Value: strings.Repeat(val.Value, int(n.Value))})
+	case *object.Array:
+		if len(val.Elements) == 0 {
+			return e.track(&object.Array{Elements: []object.Object{}})
+		}
+		if n.Value > maxRepeatLength/int64(len(val.Elements)) {
+			return newError("result of `repeat` is too large")
+		}
+		if err := e.reserve(8 * int64(len(val.Elements)) * n.Value); err != nil {
+			return err
+		}
+		elements := make([]object.Object, 0, len(val.Elements)*int(n.Value))
+		for i := int64(0); i < n.Value; i++ {
+			elements = append(elements, val.Elements...)
+		}
+		return e.track(&object.Array{Elements: elements})
+	default:
+		return newError("argument to `repeat` must be STRING or ARRAY, got %s", args[0].Type())
+	}
+}
+
+// hash(val) returns an integer hash of `val`. Equal values always hash the
+// same, but the hash can change between versions of Monkey.
+func builtinHash(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	return object.NewInteger(int64(hashObject(args[0])))
+}
+
+// hashObject returns the FNV-1a hash of a canonical form of `obj`. Values are
+// tagged with their type, and array elements and hash entries are hashed on
+// their own, with the entries sorted by the hash of their key so that the
+// order they were added in doesn't matter.
+func hashObject(obj object.Object) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(obj.Type()))
+	h.Write([]byte{0})
+
+	var buf [8]byte
+	write := func(v uint64) {
+		binary.BigEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		for _, el := range obj.Elements {
+			write(hashObject(el))
+		}
+	case *object.Hash:
+		entries := make([][2]uint64, 0, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			entries = append(entries, [2]uint64{hashObject(pair.Key), hashObject(pair.Value)})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i][0] != entries[j][0] {
+				return entries[i][0] < entries[j][0]
+			}
+			return entries[i][1] < entries[j][1]
+		})
+		for _, entry := range entries {
+			write(entry[0])
+			write(entry[1])
+		}
+	default:
+		h.Write([]byte(obj.Inspect()))
+	}
+	return h.Sum64()
+}
+
+// comparable(a, b) returns -1, 0 or 1 when `a` is less than, equal to or
+// greater than `b`. Integers and strings can be compared with values of the
+// same type. Monkey has no floats, so there's no promotion between types.
+func builtinComparable(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	switch a := args[0].(type) {
+	case *object.Integer:
+		if b, ok := args[1].(*object.Integer); ok {
+			return object.NewInteger(compare(a.Value < b.Value, a.Value > b.Value))
+		}
+	case *object.String:
+		if b, ok := args[1].(*object.String); ok {
+			return object.NewInteger(int64(strings.Compare(a.Value, b.Value)))
+		}
+	}
+	return newError("cannot compare %s and %s", args[0].Type(), args[1].Type())
+}
+
+func compare(less, greater bool) int64 {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// callSite returns the position of the call currently being made, which for a
+// built-in is the call to the built-in itself
+func (e *Evaluator) callSite() object.StackFrame {
+	if len(e.frames) == 0 {
+		return object.StackFrame{}
+	}
+	return e.frames[len(e.frames)-1]
+}
@@ -0,0 +1,370 @@
+package eval
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// evalGoStatement evaluates the function and its arguments straight away, as
+// Go does, then calls the function in a new goroutine. The result of the call
+// is discarded, including any error.
+func (e *Evaluator) evalGoStatement(node *ast.GoStatement, env *object.Environment) object.Object {
+	function := e.evalNode(node.Call.Function, env)
+	if isError(function) {
+		return function
+	}
+	args := e.evalExpressions(node.Call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	forked := e.fork()
+	go forked.callFunction(node.Call, function, args)
+
+	return nil
+}
+
+// fork returns a copy of the evaluator for a new goroutine. The call stack is
+// copied so stack traces and the call depth limit include the `go` statement's
+// callers. Call profiling and debugging only follow the original goroutine.
+func (e *Evaluator) fork() *Evaluator {
+	forked := *e
+	forked.frames = append([]object.StackFrame(nil), e.frames...)
+	forked.calls = nil
+	forked.debugger = nil
+	return &forked
+}
+
+// evalForStatement runs the body once for each element of an array or range,
+// key of a hash or value received from a channel, binding it to the loop
+// variable. A channel is read until it is closed.
+func (e *Evaluator) evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := e.evalNode(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	run := func(value object.Object) object.Object {
+		if err := e.contextError(); err != nil {
+			return err
+		}
+
+		env.Set(node.Variable.Value, value)
+
+		if result := e.evalNode(node.Body, env); isSignal(result) {
+			return result
+		}
+		return nil
+	}
+
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		for _, el := range iterable.Elements {
+			if result := run(el); result != nil {
+				return result
+			}
+		}
+	case *object.Hash:
+		for _, pair := range iterable.Entries() {
+			if result := run(pair.Key); result != nil {
+				return result
+			}
+		}
+	case *object.Range:
+		last, ok := iterable.Last()
+		for i := iterable.Low; ok; i++ {
+			if result := run(object.NewInteger(i)); result != nil {
+				return result
+			}
+			// Stop before i++ can overflow at the largest integer
+			ok = i < last
+		}
+	case *object.Channel:
+		for {
+			value, ok, err := e.receive(iterable)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+			if result := run(value); result != nil {
+				return result
+			}
+		}
+	default:
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	return NULL
+}
+
+// receive waits for a value from `ch`, or for the evaluator's context to be
+// done. `ok` is false once the channel has been closed.
+func (e *Evaluator) receive(ch *object.Channel) (value object.Object, ok bool, err object.Object) {
+	select {
+	case value, ok = <-ch.Ch:
+		return value, ok, nil
+	case <-e.ctx.Done():
+		return nil, false, e.contextError()
+	}
+}
+
+// sleep(ms) pauses for `ms` milliseconds, returning early with an error if the
+// program is cancelled or times out. It returns straight away for 0 or less.
+func builtinSleep(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	ms, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("argument to `sleep` must be INTEGER, got %s", args[0].Type())
+	}
+	if ms.Value <= 0 {
+		return NULL
+	}
+
+	timer := time.NewTimer(time.Duration(ms.Value) * time.Millisecond)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return NULL
+	case <-e.ctx.Done():
+		return e.contextError()
+	}
+}
+
+// chan() or chan(size) returns a new channel, unbuffered unless a size is given
+func builtinChan(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+
+	size := int64(0)
+	if len(args) == 1 {
+		n, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `chan` must be INTEGER, got %s", args[0].Type())
+		}
+		if n.Value < 0 {
+			return newError("channel size must not be negative, got %d", n.Value)
+		}
+		if n.Value > maxChanSize {
+			return newError("channel size must be at most %d, got %d", maxChanSize, n.Value)
+		}
+		size = n.Value
+	}
+
+	if err := e.reserve(chanSlotSize * size); err != nil {
+		return err
+	}
+	return e.track(&object.Channel{Ch: make(chan object.Object, size)})
+}
+
+// maxChanSize caps the buffer `chan` will allocate, so a huge size fails with
+// an error rather than a panic from make. Each slot of the buffer holds an
+// interface value, chanSlotSize bytes.
+const (
+	maxChanSize  = 1 << 28
+	chanSlotSize = 16
+)
+
+// send(ch, val) blocks until `val` has been sent on `ch`
+func builtinSend(e *Evaluator, args ...object.Object) (result object.Object) {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to `send` must be CHANNEL, got %s", args[0].Type())
+	}
+
+	defer recoverError(&result)
+
+	select {
+	case ch.Ch <- args[1]:
+		return NULL
+	case <-e.ctx.Done():
+		return e.contextError()
+	}
+}
+
+// recv(ch) blocks until a value is received from `ch`. It returns null once
+// the channel has been closed.
+func builtinRecv(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to `recv` must be CHANNEL, got %s", args[0].Type())
+	}
+
+	value, ok, err := e.receive(ch)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NULL
+	}
+	return value
+}
+
+// close(ch) closes `ch`, ending any `for` loops reading from it
+func builtinClose(e *Evaluator, args ...object.Object) (result object.Object) {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to `close` must be CHANNEL, got %s", args[0].Type())
+	}
+
+	defer recoverError(&result)
+
+	close(ch.Ch)
+	return NULL
+}
+
+// wg() returns a new wait group, used with wgAdd, wgDone and wgWait like Go's
+// sync.WaitGroup
+func builtinWaitGroup(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+
+	return &object.WaitGroup{}
+}
+
+// wgAdd(wg, n) adds `n` to the number of goroutines `wg` waits for
+func builtinWaitGroupAdd(e *Evaluator, args ...object.Object) (result object.Object) {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	wg, ok := args[0].(*object.WaitGroup)
+	if !ok {
+		return newError("argument to `wgAdd` must be WAIT_GROUP, got %s", args[0].Type())
+	}
+	n, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("argument to `wgAdd` must be INTEGER, got %s", args[1].Type())
+	}
+
+	defer recoverError(&result)
+
+	wg.WG.Add(int(n.Value))
+	return NULL
+}
+
+// wgDone(wg) marks one goroutine as finished
+func builtinWaitGroupDone(e *Evaluator, args ...object.Object) (result object.Object) {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	wg, ok := args[0].(*object.WaitGroup)
+	if !ok {
+		return newError("argument to `wgDone` must be WAIT_GROUP, got %s", args[0].Type())
+	}
+
+	defer recoverError(&result)
+
+	wg.WG.Done()
+	return NULL
+}
+
+// wgWait(wg) blocks until every goroutine added to `wg` is done
+func builtinWaitGroupWait(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	wg, ok := args[0].(*object.WaitGroup)
+	if !ok {
+		return newError("argument to `wgWait` must be WAIT_GROUP, got %s", args[0].Type())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.WG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return NULL
+	case <-e.ctx.Done():
+		return e.contextError()
+	}
+}
+
+// recoverError turns the panics Go raises for misused channels and wait
+// groups, such as sending on a closed channel, into a Monkey error
+func recoverError(result *object.Object) {
+	if r := recover(); r != nil {
+		*result = newError("%s", fmt.Sprint(r))
+	}
+}
+
+// evalSelectStatement waits until one of the cases can receive from its
+// channel and runs that case's body, like Go's select. With a default the
+// select doesn't wait, running the default if no channel is ready. The value
+// received is bound to the case's variable, or null if the channel was closed.
+func (e *Evaluator) evalSelectStatement(node *ast.SelectStatement, env *object.Environment) object.Object {
+	cases := make([]reflect.SelectCase, 0, len(node.Cases)+2)
+	for _, c := range node.Cases {
+		value := e.evalNode(c.Chan, env)
+		if isError(value) {
+			return value
+		}
+		ch, ok := value.(*object.Channel)
+		if !ok {
+			return newError("select case must be CHANNEL, got %s", value.Type())
+		}
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.Ch)})
+	}
+
+	// Stop waiting when the evaluator's context is done
+	done := len(cases)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(e.ctx.Done())})
+
+	if node.Default != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	chosen, value, ok := reflect.Select(cases)
+	switch {
+	case chosen == done:
+		return e.contextError()
+	case chosen > done:
+		return e.evalSelectBody(node.Default, env)
+	}
+
+	c := node.Cases[chosen]
+	if c.Var != nil {
+		var received object.Object = NULL
+		if ok {
+			received = value.Interface().(object.Object)
+		}
+		env.Set(c.Var.Value, received)
+	}
+	return e.evalSelectBody(c.Body, env)
+}
+
+func (e *Evaluator) evalSelectBody(body *ast.BlockStatement, env *object.Environment) object.Object {
+	result := e.evalNode(body, env)
+	if result == nil {
+		return NULL
+	}
+	return result
+}
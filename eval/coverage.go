@@ -0,0 +1,154 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/vishen/go-monkeylang/ast"
+)
+
+// CoverageMap holds whether each line with a statement on it has been run,
+// by file and then line
+type CoverageMap map[string]map[int]bool
+
+// WithCoverage makes the evaluator record the lines run of programs it is
+// given, which were read from `file`. Read the results with Coverage.
+func WithCoverage(file string) Option {
+	return func(e *Evaluator) {
+		e.coverage = &coverage{file: file, lines: map[int]bool{}}
+	}
+}
+
+// coverage is shared with goroutines started by the program, so it is locked
+type coverage struct {
+	mu    sync.Mutex
+	file  string
+	lines map[int]bool
+}
+
+// instrument adds every line of `program` with a statement on it as not yet
+// run, so lines that never run are still reported
+func (c *coverage) instrument(program *ast.Program) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ast.Walk(program, func(node ast.Node) bool {
+		if stmt, ok := node.(ast.Statement); ok {
+			if line := statementLine(stmt); line > 0 && !c.lines[line] {
+				c.lines[line] = false
+			}
+		}
+		return true
+	})
+}
+
+func (c *coverage) mark(stmt ast.Statement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if line := statementLine(stmt); line > 0 {
+		c.lines[line] = true
+	}
+}
+
+func statementLine(stmt ast.Statement) int {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token.Line
+	case *ast.DestructuringStatement:
+		return stmt.Token.Line
+	case *ast.ReturnStatement:
+		return stmt.Token.Line
+	case *ast.ExpressionStatement:
+		return stmt.Token.Line
+	case *ast.GoStatement:
+		return stmt.Token.Line
+	case *ast.TypeAliasStatement:
+		return stmt.Token.Line
+	case *ast.WithStatement:
+		return stmt.Token.Line
+	case *ast.TryCatchStatement:
+		return stmt.Token.Line
+	case *ast.ForStatement:
+		return stmt.Token.Line
+	case *ast.SelectStatement:
+		return stmt.Token.Line
+	}
+	// Blocks are covered by the statements in them
+	return 0
+}
+
+// Coverage returns the lines that have been run so far. Nothing is returned
+// unless the evaluator was created WithCoverage.
+func (e *Evaluator) Coverage() CoverageMap {
+	if e.coverage == nil {
+		return nil
+	}
+
+	e.coverage.mu.Lock()
+	defer e.coverage.mu.Unlock()
+
+	lines := map[int]bool{}
+	for line, covered := range e.coverage.lines {
+		lines[line] = covered
+	}
+	return CoverageMap{e.coverage.file: lines}
+}
+
+// Percent returns the percentage of lines that have been run, across every
+// file. It is 100 when there are no lines at all.
+func (c CoverageMap) Percent() float64 {
+	found, hit := 0, 0
+	for _, lines := range c {
+		for _, covered := range lines {
+			found++
+			if covered {
+				hit++
+			}
+		}
+	}
+	if found == 0 {
+		return 100
+	}
+	return float64(hit) / float64(found) * 100
+}
+
+// WriteLCOV writes the coverage in the LCOV tracefile format read by tools
+// such as genhtml. Lines are only known to have run, not how many times, so
+// their counts are 0 or 1.
+func (c CoverageMap) WriteLCOV(w io.Writer) error {
+	files := []string{}
+	for file := range c {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		lines := []int{}
+		for line := range c[file] {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+
+		if _, err := fmt.Fprintf(w, "TN:\nSF:%s\n", file); err != nil {
+			return err
+		}
+		hit := 0
+		for _, line := range lines {
+			count := 0
+			if c[file][line] {
+				count = 1
+				hit++
+			}
+			if _, err := fmt.Fprintf(w, "DA:%d,%d\n", line, count); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
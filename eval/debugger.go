@@ -0,0 +1,102 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+const DEBUG_PROMPT = "(debug) "
+
+const debugHelp = `commands:
+  n          run the next statement
+  c          continue to the end without pausing
+  p <name>   print the value bound to <name>
+  v          list the bound names and their types, prefixed by scope depth
+  q          abort the call
+`
+
+// debugger steps through the statements of a block, asking the user what to
+// do before each one is evaluated.
+type debugger struct {
+	// Read a line at a time without buffering, like `read`, so input meant
+	// for the REPL or the program after the debugger is left alone
+	in  io.Reader
+	out io.Writer
+
+	// When false, the debugger has been told to continue and no longer pauses
+	stepping bool
+}
+
+func newDebugger(in io.Reader, out io.Writer) *debugger {
+	return &debugger{in: in, out: out, stepping: true}
+}
+
+// pause prints the statement that is about to be evaluated and waits for a
+// command. An error object is returned if the user aborted.
+func (d *debugger) pause(stmt ast.Statement, env *object.Environment) object.Object {
+	if !d.stepping {
+		return nil
+	}
+
+	fmt.Fprintf(d.out, "-> %s\n", stmt.String())
+
+	for {
+		io.WriteString(d.out, DEBUG_PROMPT)
+
+		line, err := readLine(d.in)
+		if err != nil && line == "" {
+			// Nothing left to read, so run to the end
+			d.stepping = false
+			return nil
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return nil
+		}
+
+		switch fields[0] {
+		case "n":
+			return nil
+		case "c":
+			d.stepping = false
+			return nil
+		case "p":
+			if len(fields) != 2 {
+				io.WriteString(d.out, "usage: p <name>\n")
+				continue
+			}
+			if val, ok := env.Get(fields[1]); ok {
+				fmt.Fprintf(d.out, "%s = %s\n", fields[1], val.Inspect())
+			} else {
+				fmt.Fprintf(d.out, "%s is not bound\n", fields[1])
+			}
+		case "v":
+			d.listVariables(env)
+		case "q":
+			return newError("debug: aborted")
+		default:
+			io.WriteString(d.out, debugHelp)
+		}
+	}
+}
+
+// listVariables writes each name visible from `env` with the depth of the
+// scope it's bound in, innermost scope first. Shadowed names are left out.
+func (d *debugger) listVariables(env *object.Environment) {
+	seen := map[string]bool{}
+	for ; env != nil; env = env.Outer() {
+		for _, name := range env.Keys() {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			val, _ := env.Get(name)
+			fmt.Fprintf(d.out, "[%d] %s: %s\n", env.Depth(), name, val.Type())
+		}
+	}
+}
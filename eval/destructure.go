@@ -0,0 +1,67 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// bindPattern binds the names in a destructuring pattern to the parts of
+// `value` they match, returning an error if the value doesn't have the shape
+// of the pattern. `_` matches anything without binding it.
+func (e *Evaluator) bindPattern(pattern ast.Expression, value object.Object, env *object.Environment) object.Object {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			env.Set(pattern.Value, value)
+		}
+	case *ast.ArrayLiteral:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return newError("cannot destructure %s as an array", value.Type())
+		}
+		for i, el := range pattern.Elements {
+			var v object.Object = NULL
+			if i < len(array.Elements) {
+				v = array.Elements[i]
+			}
+			if err := e.bindElement(el, v, env); err != nil {
+				return err
+			}
+		}
+	case *ast.HashLiteral:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return newError("cannot destructure %s as a hash", value.Type())
+		}
+		for _, pair := range pattern.Pairs {
+			key := pair.Key.(*ast.StringLiteral)
+			v, ok := hash.Get(object.InternString(key.Value))
+			if !ok {
+				v = NULL
+			}
+			if err := e.bindElement(pair.Value, v, env); err != nil {
+				return err
+			}
+		}
+	default:
+		return newError("cannot destructure into %s", pattern.String())
+	}
+	return nil
+}
+
+// bindElement binds one element of an array or hash pattern. A missing element
+// is null, which makes a default pattern evaluate its default instead.
+func (e *Evaluator) bindElement(el ast.Expression, value object.Object, env *object.Environment) object.Object {
+	dp, ok := el.(*ast.DefaultPattern)
+	if !ok {
+		return e.bindPattern(el, value, env)
+	}
+
+	if value == NULL {
+		value = e.evalNode(dp.Default, env)
+		if isError(value) {
+			return value
+		}
+	}
+	return e.bindPattern(dp.Target, value, env)
+}
@@ -1,7 +1,11 @@
 package eval
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/vishen/go-monkeylang/ast"
 	"github.com/vishen/go-monkeylang/object"
@@ -9,69 +13,263 @@ import (
 
 // Commonly used objects
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NullValue
+	TRUE  = object.TrueValue
+	FALSE = object.FalseValue
 )
 
+type Option func(*Evaluator)
+
+// WithTrace makes the evaluator write a line to `w` for every node it
+// evaluates, and another for the result of that node.
+func WithTrace(w io.Writer) Option {
+	return func(e *Evaluator) {
+		e.trace = w
+	}
+}
+
+// WithIO sets where built-ins such as `puts` and `debug` read and write. By
+// default that is stdin and stdout, or the browser console under WASM.
+func WithIO(in io.Reader, out io.Writer) Option {
+	return func(e *Evaluator) {
+		e.stdin = in
+		e.stdout = out
+	}
+}
+
+// WithStdout sets where `puts` and `debug` write, leaving input as it is
+func WithStdout(w io.Writer) Option {
+	return func(e *Evaluator) {
+		e.stdout = w
+	}
+}
+
+// WithStderr sets where `printErr` writes. By default that is stderr, or
+// errors in the browser console under WASM.
+func WithStderr(w io.Writer) Option {
+	return func(e *Evaluator) {
+		e.stderr = w
+	}
+}
+
+type Evaluator struct {
+	trace io.Writer
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	// Set while a function is being run by the `debug` built-in
+	debugger *debugger
+
+	calls    *callProfile
+	coverage *coverage
+
+	// The calls currently being evaluated, outermost first
+	frames []object.StackFrame
+
+	sandbox         bool
+	allowedBuiltins map[string]bool // nil allows every built-in
+	maxCallDepth    int
+	timeout         time.Duration
+
+	ctx context.Context // From the outermost call to Eval
+
+	memory *memoryCounter
+}
+
+func New(opts ...Option) *Evaluator {
+	e := &Evaluator{ctx: context.Background()}
+	e.stdin, e.stdout, e.stderr = defaultStdio()
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Eval evaluates `node` using an evaluator with the default options.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(context.Background(), node, env)
+}
+
+// Eval evaluates `node` in `env`. Once `ctx` is done the statement being run
+// is the last one, and an error is returned.
+func (e *Evaluator) Eval(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	prev := e.ctx
+	e.ctx = ctx
+	defer func() { e.ctx = prev }()
+
+	if program, ok := node.(*ast.Program); ok && e.coverage != nil {
+		e.coverage.instrument(program)
+	}
+
+	return e.evalNode(node, env)
+}
+
+func (e *Evaluator) evalNode(node ast.Node, env *object.Environment) object.Object {
+	if e.trace != nil {
+		return e.traceEval(node, env)
+	}
+	return e.eval(node, env)
+}
+
+func (e *Evaluator) eval(node ast.Node, env *object.Environment) object.Object {
 	//	fmt.Printf("Node=%#v\n", node)
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalProgram(node.Statements, env)
+		return e.evalProgram(node.Statements, env)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		if node.Name == "" {
+			return &object.Function{Parameters: params, Env: env, Body: body, Literal: node}
+		}
+
+		// Bind the function to its own name in its closure, so it can
+		// recurse even if the name is later bound to something else
+		closure := object.NewEnclosedEnvironment(env)
+		fn := &object.Function{Parameters: params, Env: closure, Body: body, Literal: node}
+		closure.Set(node.Name, fn)
+		return fn
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		function := e.evalNode(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
+		args := e.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return e.callFunction(node, function, args)
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := e.evalNode(node.Value, env)
 		if isError(val) {
 			return val
 		}
 		env.Set(node.Name.Value, val)
+	case *ast.DestructuringStatement:
+		val := e.evalNode(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		if err := e.bindPattern(node.Pattern, val, env); err != nil {
+			return err
+		}
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return e.evalIdentifier(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.evalNode(node.Expression, env)
+	case *ast.GoStatement:
+		return e.evalGoStatement(node, env)
+	case *ast.TypeAliasStatement:
+		// Only for documentation until there is a type checker
+		return nil
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env)
+	case *ast.WithStatement:
+		return e.evalWithStatement(node, env)
+	case *ast.TryCatchStatement:
+		return e.evalTryCatchStatement(node, env)
+	case *ast.RangeLiteral:
+		return e.evalRangeLiteral(node, env)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, env)
+	case *ast.SwitchExpression:
+		return e.evalSwitchExpression(node, env)
+	case *ast.SpreadExpression:
+		return newError("... can only be used in a match pattern")
+	case *ast.LetInExpression:
+		val := e.evalNode(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		inner := object.NewEnclosedEnvironment(env)
+		inner.Set(node.Name.Value, val)
+		return e.evalNode(node.Body, inner)
+	case *ast.SelectStatement:
+		return e.evalSelectStatement(node, env)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.evalNode(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return e.track(evalPrefixExpression(node.Operator, right))
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := e.evalNode(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return e.evalLogicalExpression(node, left, env)
+		}
+		right := e.evalNode(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		if l, ok := left.(*object.String); ok && node.Operator == "+" {
+			if r, ok := right.(*object.String); ok {
+				if err := e.reserve(int64(len(l.Value) + len(r.Value))); err != nil {
+					return err
+				}
+			}
+		}
+		return e.track(evalInfixExpression(node.Operator, left, right))
 	case *ast.BlockStatement:
-		return evalBlockStatement(node.Statements, env)
+		return e.evalBlockStatement(node.Statements, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
+	case *ast.TernaryExpression:
+		return e.evalTernaryExpression(node, env)
+	case *ast.TryExpression:
+		val := e.evalNode(node.Body, env)
+		if errObj, ok := val.(*object.Error); ok {
+			return &object.ErrorValue{Error: errObj}
+		}
+		return val
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := e.evalNode(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return object.NewInteger(node.Value)
+	case *ast.StringLiteral:
+		return object.InternString(node.Value)
+	case *ast.ArrayLiteral:
+		if err := e.reserve(8 * int64(len(node.Elements))); err != nil {
+			return err
+		}
+		elements := e.evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return e.track(&object.Array{Elements: elements})
+	case *ast.IndexExpression:
+		left := e.evalNode(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := e.evalNode(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.MemberExpression:
+		obj := e.evalNode(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, node.Property.Value)
+	case *ast.HashLiteral:
+		return e.track(e.evalHashLiteral(node, env))
 	case *ast.Boolean:
 		if node.Value {
 			return TRUE
@@ -83,56 +281,74 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	return nil
 }
 
-func evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, stmt := range statements {
-		result = Eval(stmt, env)
+		if err := e.contextError(); err != nil {
+			return err
+		}
 
-		switch result := result.(type) {
-		case *object.ReturnValue:
-			return result.Value
-		case *object.Error:
-			return result
+		if e.coverage != nil {
+			e.coverage.mark(stmt)
+		}
+
+		result = e.evalNode(stmt, env)
+		if isSignal(result) {
+			return object.Unwrap(result)
 		}
 	}
 
 	return result
 }
 
-func evalBlockStatement(statements []ast.Statement, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, stmt := range statements {
-		result = Eval(stmt, env)
-		//		fmt.Printf("i=%d stmt=%#v result=%#v", i, stmt, result)
+		if err := e.contextError(); err != nil {
+			return err
+		}
 
-		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE || rt == object.ERROR {
-				return result
+		if e.debugger != nil {
+			if err := e.debugger.pause(stmt, env); err != nil {
+				return err
 			}
 		}
 
+		if e.coverage != nil {
+			e.coverage.mark(stmt)
+		}
+
+		result = e.evalNode(stmt, env)
+		//		fmt.Printf("i=%d stmt=%#v result=%#v", i, stmt, result)
+
+		if isSignal(result) {
+			return result
+		}
+
 	}
 
 	return result
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	val, ok := env.Get(node.Value)
-	if !ok {
-		return newError("identifier not found: " + node.Value)
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	if builtin, ok := e.lookupBuiltin(node.Value); ok {
+		return builtin
 	}
 
-	return val
+	return newError("identifier not found: %s", node.Value)
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
-	var result []object.Object
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	result := []object.Object{}
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		evaluated := e.evalNode(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -143,22 +359,34 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.evalNode(ie.Condition, env)
 
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return e.evalNode(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.evalNode(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
+func (e *Evaluator) evalTernaryExpression(te *ast.TernaryExpression, env *object.Environment) object.Object {
+	condition := e.evalNode(te.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return e.evalNode(te.Consequence, env)
+	}
+	return e.evalNode(te.Alternative, env)
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -173,6 +401,17 @@ func isTruthy(obj object.Object) bool {
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
+	switch operator {
+	case "in":
+		return evalInExpression(left, right)
+	case "not in":
+		result := evalInExpression(left, right)
+		if isError(result) {
+			return result
+		}
+		return nativeBoolToBooleanObject(!isTruthy(result))
+	}
+
 	if left.Type() == object.INTEGER && right.Type() == object.INTEGER {
 		leftVal := left.(*object.Integer).Value
 		rightVal := right.(*object.Integer).Value
@@ -180,13 +419,16 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 		switch operator {
 		// Return Integers
 		case "+":
-			return &object.Integer{Value: leftVal + rightVal}
+			return object.NewInteger(leftVal + rightVal)
 		case "-":
-			return &object.Integer{Value: leftVal - rightVal}
+			return object.NewInteger(leftVal - rightVal)
 		case "*":
-			return &object.Integer{Value: leftVal * rightVal}
+			return object.NewInteger(leftVal * rightVal)
 		case "/":
-			return &object.Integer{Value: leftVal / rightVal}
+			if rightVal == 0 {
+				return newError("division by zero")
+			}
+			return object.NewInteger(leftVal / rightVal)
 			// Return Boolean
 		case "<":
 			return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -197,10 +439,12 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 		case "!=":
 			return nativeBoolToBooleanObject(leftVal != rightVal)
 		}
+	} else if left.Type() == object.STRING && right.Type() == object.STRING {
+		return evalStringInfixExpression(operator, left, right)
 	} else if operator == "==" {
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.DeepEqual(left, right))
 	} else if operator == "!=" {
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.DeepEqual(left, right))
 	} else if left.Type() != right.Type() {
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	}
@@ -208,6 +452,174 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 }
 
+// evalInExpression reports whether `left` is an element of an array, a key of
+// a hash or a substring of a string
+func evalInExpression(left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Array:
+		for _, el := range right.Elements {
+			if object.DeepEqual(left, el) {
+				return TRUE
+			}
+		}
+		return FALSE
+	case *object.Hash:
+		key, ok := left.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", left.Type())
+		}
+		_, ok = right.Get(key)
+		return nativeBoolToBooleanObject(ok)
+	case *object.String:
+		substr, ok := left.(*object.String)
+		if !ok {
+			return newError("type mismatch: %s in STRING", left.Type())
+		}
+		return nativeBoolToBooleanObject(strings.Contains(right.Value, substr.Value))
+	default:
+		return newError("unknown operator: %s in %s", left.Type(), right.Type())
+	}
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		// Interned strings can be compared by pointer
+		return nativeBoolToBooleanObject(left == right || leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(left != right && leftVal != rightVal)
+	}
+
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY && index.Type() == object.RANGE:
+		return sliceArray(left.(*object.Array), index.(*object.Range))
+	case left.Type() == object.ARRAY && index.Type() == object.INTEGER:
+		elements := left.(*object.Array).Elements
+		i := index.(*object.Integer).Value
+		if i < 0 || i >= int64(len(elements)) {
+			return NULL
+		}
+		return elements[i]
+	case left.Type() == object.HASH:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		if val, ok := left.(*object.Hash).Get(key); ok {
+			return val
+		}
+		return NULL
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
+
+// evalLogicalExpression only evaluates the right operand of `&&` and `||` when
+// the left one doesn't decide the result. Like `if`, any value can be used as
+// a condition, and the operand that decided the result is returned, so
+// `name || "anonymous"` gives a default.
+func (e *Evaluator) evalLogicalExpression(node *ast.InfixExpression, left object.Object, env *object.Environment) object.Object {
+	if isTruthy(left) == (node.Operator == "||") {
+		return left
+	}
+	return e.evalNode(node.Right, env)
+}
+
+func (e *Evaluator) evalRangeLiteral(node *ast.RangeLiteral, env *object.Environment) object.Object {
+	bounds := []object.Object{}
+	for _, exp := range []ast.Expression{node.Low, node.High} {
+		bound := e.evalNode(exp, env)
+		if isError(bound) {
+			return bound
+		}
+		if bound.Type() != object.INTEGER {
+			return newError("range bounds must be INTEGER, got %s", bound.Type())
+		}
+		bounds = append(bounds, bound)
+	}
+
+	return &object.Range{
+		Low:       bounds[0].(*object.Integer).Value,
+		High:      bounds[1].(*object.Integer).Value,
+		Inclusive: node.Inclusive,
+	}
+}
+
+// sliceArray returns the elements of `array` at the indexes in `r`. The range
+// is clamped to the array, like an index out of range gives null rather than
+// an error.
+func sliceArray(array *object.Array, r *object.Range) object.Object {
+	high, ok := r.Last()
+	if !ok {
+		return &object.Array{Elements: []object.Object{}}
+	}
+
+	low, last := r.Low, int64(len(array.Elements))-1
+	if low < 0 {
+		low = 0
+	}
+	if high < last {
+		last = high
+	}
+	if last < low {
+		return &object.Array{Elements: []object.Object{}}
+	}
+
+	return &object.Array{Elements: append([]object.Object{}, array.Elements[low:last+1]...)}
+}
+
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	switch obj := obj.(type) {
+	case *object.Module:
+		if member, ok := obj.Bindings[name]; ok {
+			return member
+		}
+		return newError("module %s has no member %s", obj.Name, name)
+	case *object.GoObject:
+		member, err := obj.Member(name)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return member
+	default:
+		return newError("member access not supported: %s", obj.Type())
+	}
+}
+
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := e.evalNode(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := e.evalNode(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		hash.Set(hashKey, value)
+	}
+
+	return hash
+}
+
 func evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
@@ -225,7 +637,7 @@ func evalMinusOperatorExpression(right object.Object) object.Object {
 	}
 
 	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return object.NewInteger(-value)
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
@@ -241,31 +653,121 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	function, ok := fn.(*object.Function)
-	if !ok {
-		return newError("not a function: %s", fn.Type())
+// callFunction keeps track of the call stack around `applyFunction`, so any
+// error coming out of the call can be given a stack trace.
+func (e *Evaluator) callFunction(node *ast.CallExpression, fn object.Object, args []object.Object) object.Object {
+	line, col := callPosition(node)
+	e.frames = append(e.frames, object.StackFrame{FunctionName: callName(node), Line: line, Col: col})
+	defer func() { e.frames = e.frames[:len(e.frames)-1] }()
+
+	var result object.Object
+	if err := e.checkLimits(); err != nil {
+		result = err
+	} else if e.calls != nil {
+		result = e.profileCall(fn, args)
+	} else {
+		result = e.applyFunction(fn, args)
 	}
 
-	extendedEnv := extendFunctionEnv(function, args)
-	evaluated := Eval(function.Body, extendedEnv)
+	if errObj, ok := result.(*object.Error); ok && errObj.StackTrace == nil {
+		errObj.StackTrace = e.stackTrace()
+	}
 
-	return unwrapReturnValue(evaluated)
+	return result
 }
 
+// contextError returns an error once the context passed to Eval is done
+func (e *Evaluator) contextError() *object.Error {
+	switch e.ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return newError("execution timed out")
+	default:
+		return newError("execution cancelled")
+	}
+}
+
+// stackTrace returns a copy of the call stack, innermost call first
+func (e *Evaluator) stackTrace() []object.StackFrame {
+	trace := make([]object.StackFrame, len(e.frames))
+	for i, frame := range e.frames {
+		trace[len(e.frames)-1-i] = frame
+	}
+	return trace
+}
+
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
+	switch function := fn.(type) {
+	case *object.Function:
+		if len(args) != len(function.Parameters) {
+			return arityError(function, len(args))
+		}
+		extendedEnv := extendFunctionEnv(function, args)
+		evaluated := e.evalNode(function.Body, extendedEnv)
+
+		return object.Unwrap(evaluated)
+	case *object.Builtin:
+		return function.Fn(args...)
+	default:
+		return newError("not a function: %s", fn.Type())
+	}
+}
+
+// extendFunctionEnv binds the arguments to the function's parameters. `self`
+// is bound to the function itself so anonymous functions can recurse, unless a
+// parameter is called `self`.
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
 	env := object.NewEnclosedEnvironment(fn.Env)
+	env.Set("self", fn)
 	for i, param := range fn.Parameters {
 		env.Set(param.Value, args[i])
 	}
 	return env
 }
 
-func unwrapReturnValue(obj object.Object) object.Object {
-	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue.Value
+// arityError names the function when it has a name, for
+// "function 'add' expects 2 arguments, got 3"
+func arityError(fn *object.Function, got int) *object.Error {
+	name := "function"
+	if fn.Literal != nil && fn.Literal.Name != "" {
+		name = fmt.Sprintf("function '%s'", fn.Literal.Name)
+	}
+
+	arguments := "arguments"
+	if len(fn.Parameters) == 1 {
+		arguments = "argument"
+	}
+	return newError("%s expects %d %s, got %d", name, len(fn.Parameters), arguments, got)
+}
+
+// isSignal reports whether `obj` stops the statements it's in from running,
+// such as a return value or an error
+func isSignal(obj object.Object) bool {
+	_, ok := obj.(object.Signal)
+	return ok
+}
+
+// callName is the name a function was called by. Functions that weren't
+// called through an identifier are labelled by where they are in the source.
+func callName(node *ast.CallExpression) string {
+	if ident, ok := node.Function.(*ast.Identifier); ok {
+		return ident.Value
+	}
+
+	line, col := callPosition(node)
+	return fmt.Sprintf("fn@%d:%d", line, col)
+}
+
+func callPosition(node *ast.CallExpression) (int, int) {
+	switch fn := node.Function.(type) {
+	case *ast.Identifier:
+		return fn.Token.Line, fn.Token.Col
+	case *ast.FunctionLiteral:
+		return fn.Token.Line, fn.Token.Col
+	default:
+		return node.Token.Line, node.Token.Col
 	}
-	return obj
 }
 
 func nativeBoolToBooleanObject(truthy bool) object.Object {
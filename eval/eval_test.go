@@ -1,7 +1,12 @@
 package eval
 
 import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/vishen/go-monkeylang/lexer"
 	"github.com/vishen/go-monkeylang/object"
@@ -30,6 +35,11 @@ func TestFunctionObject(t *testing.T) {
 	if fn.Body.String() != expectedBody {
 		t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
 	}
+
+	expectedInspect := "fn(x) {\n    x + 2;\n}"
+	if fn.Inspect() != expectedInspect {
+		t.Fatalf("inspect is not %q. got=%q", expectedInspect, fn.Inspect())
+	}
 }
 
 func TestFunctionApplication(t *testing.T) {
@@ -69,6 +79,7 @@ func TestLetStatements(t *testing.T) {
 		{"let a = 5 * 5; a;", 25},
 		{"let a = 5; let b = a; b;", 5},
 		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"type Vector = [Int]; let a = 5; a;", 5}, // Type aliases are ignored
 	}
 
 	for _, tt := range tests {
@@ -76,6 +87,49 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, b] = [1]; b", nil},
+		{"let [a, _, c] = [1, 2, 3]; c", 3},
+		{"let [x = 0, y = 0] = [5]; x + y", 5},
+		{"let [x = 0, y = 0] = [if (false) { 1 }, 7]; x + y", 7},
+		{"let [x = 1, y = x + 1] = []; x * 10 + y", 12},
+		{"let [[a, b], c] = [[1, 2], 3]; a + b + c", 6},
+		{"let [[a, b] = [3, 4]] = []; a + b", 7},
+		{"let [x = missing] = [1]; x", 1}, // Defaults are only evaluated when needed
+		{"let [x = missing] = []; x", "identifier not found: missing"},
+		{"let [a, b] = 5; a", "cannot destructure INTEGER as an array"},
+		{"let [[a]] = [1]; a", "cannot destructure INTEGER as an array"},
+		{`let {x: a, y} = {"x": 1, "y": 2}; a * 10 + y`, 12},
+		{`let {"first name": first} = {"first name": 3}; first`, 3},
+		{`let {x} = {}; x`, nil},
+		{`let {x = 4, y: z = 5} = {"y": 6}; x * 10 + z`, 46},
+		{`let {a: {b: c}} = {"a": {"b": 5}}; c`, 5},
+		{`let {pos: [x, y]} = {"pos": [1, 2]}; x + y`, 3},
+		{`let [{x}] = [{"x": 8}]; x`, 8},
+		{"let {x} = [1]; x", "cannot destructure ARRAY as a hash"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("expected error %q for %q. got=%T(%+v)", expected, tt.input, evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -93,6 +147,10 @@ func TestErrorHandling(t *testing.T) {
 			"-true",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			"let x = 0; 1 / x",
+			"division by zero",
+		},
 		{
 			"true + false;",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -113,6 +171,30 @@ func TestErrorHandling(t *testing.T) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			`{"name": "Monkey"}[fn(x) { x }];`,
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			`{[1]: 2}`,
+			"unusable as hash key: ARRAY",
+		},
+		{
+			`1[0]`,
+			"index operator not supported: INTEGER",
+		},
+		{
+			"let add = fn(x, y) { x + y }; add(1, 2, 3)",
+			"function 'add' expects 2 arguments, got 3",
+		},
+		{
+			"fn double(x) { x * 2 }; double()",
+			"function 'double' expects 1 argument, got 0",
+		},
+		{
+			"fn() { 1 }(2)",
+			"function expects 0 arguments, got 1",
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -157,6 +239,9 @@ func TestBangOperator(t *testing.T) {
 		{"!!true", true},
 		{"!!false", false},
 		{"!!5", true},
+		{"not true", false},
+		{"not not 5", true},
+		{"not 1 == 2", false},
 	}
 
 	for _, tt := range tests {
@@ -217,6 +302,16 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, [2, 3]] != [1, [2, 3]]", false},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{"[1] == 1", false},
+		{"if (false) { 1 } == if (false) { 2 }", true},
+		{"let f = fn() { 1 }; f == f", true},
+		{"fn() { 1 } == fn() { 1 }", false},
 	}
 
 	for _, tt := range tests {
@@ -250,6 +345,144 @@ func TestIfElseExpressions(t *testing.T) {
 		}
 	}
 }
+func TestStringExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"Hello World!"`, "Hello World!"},
+		{`"Hello" + " " + "World!"`, "Hello World!"},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" - "b"`, "unknown operator: STRING - STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+
+	for i, pair := range result.Entries() {
+		if pair.Key.Inspect() != expected[i].key.Inspect() {
+			t.Errorf("pair %d has wrong key. expected=%s, got=%s", i, expected[i].key.Inspect(), pair.Key.Inspect())
+		}
+		testIntegerObject(t, pair.Value, expected[i].value)
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != NULL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
@@ -298,3 +531,2066 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 
 	return true
 }
+
+func TestTrace(t *testing.T) {
+	var out bytes.Buffer
+
+	l := lexer.NewLexer("let f = fn(x) { x }; 1 + f(2)")
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+
+	evaluated := New(WithTrace(&out)).Eval(context.Background(), program, object.NewEnvironment())
+	testIntegerObject(t, evaluated, 3)
+
+	// Only the nodes evaluated inside the call to f are indented, however
+	// deeply nested the expression around the call is
+	expected := `[0] *ast.Program: let f = fn(x) x;(1 + f(2))
+[0] *ast.LetStatement: let f = fn(x) x;
+[0] *ast.FunctionLiteral: fn f(x) x
+[0] => fn f(x) {
+    x;
+}
+[0] => <nil>
+[0] *ast.ExpressionStatement: (1 + f(2))
+[0] *ast.InfixExpression: (1 + f(2))
+[0] *ast.IntegerLiteral: 1
+[0] => 1
+[0] *ast.CallExpression: f(2)
+[0] *ast.Identifier: f
+[0] => fn f(x) {
+    x;
+}
+[0] *ast.IntegerLiteral: 2
+[0] => 2
+  [1] *ast.BlockStatement: x
+  [1] *ast.ExpressionStatement: x
+  [1] *ast.Identifier: x
+  [1] => 2
+  [1] => 2
+  [1] => 2
+[0] => 2
+[0] => 3
+[0] => 3
+[0] => 3
+`
+	if out.String() != expected {
+		t.Errorf("wrong trace output. expected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestDebugBuiltin(t *testing.T) {
+	tests := []struct {
+		commands       string
+		expected       interface{}
+		expectedOutput string
+	}{
+		{
+			"n\nn\n",
+			6,
+			"-> let y = (x * 2);\n(debug) -> y\n(debug) ",
+		},
+		{
+			"p x\np z\nc\n",
+			6,
+			"-> let y = (x * 2);\n(debug) x = 3\n(debug) z is not bound\n(debug) ",
+		},
+		{
+			"n\nv\nc\n",
+			6,
+			"-> let y = (x * 2);\n(debug) -> y\n(debug) [2] self: FUNCTION\n[2] x: INTEGER\n[2] y: INTEGER\n[1] double: FUNCTION\n(debug) ",
+		},
+		{
+			"q\n",
+			"debug: aborted",
+			"-> let y = (x * 2);\n(debug) ",
+		},
+	}
+
+	input := "let double = fn(x) { let y = x * 2; y }; debug(double, 3);"
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+
+		l := lexer.NewLexer(input)
+		p := parser.NewParser(l)
+		program := p.ParseProgram()
+
+		e := New()
+		e.stdin = strings.NewReader(tt.commands)
+		e.stdout = &out
+		evaluated := e.Eval(context.Background(), program, object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			} else if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+
+		if out.String() != tt.expectedOutput {
+			t.Errorf("wrong debugger output. expected=%q, got=%q", tt.expectedOutput, out.String())
+		}
+	}
+
+	// The debugger only reads its own commands, leaving the rest for `read`
+	program := parser.NewParser(lexer.NewLexer(input + " read()")).ParseProgram()
+	e := New(WithIO(strings.NewReader("c\nafter\n"), &bytes.Buffer{}))
+	if evaluated := e.Eval(context.Background(), program, object.NewEnvironment()); evaluated.Inspect() != "after" {
+		t.Errorf("wrong line read after debugging. got=%q", evaluated.Inspect())
+	}
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func TestCloneBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`clone(1)`, "1"},
+		{`clone("monkey")`, "monkey"},
+		{`clone([1, [2, 3], {"a": [4]}])`, `[1, [2, 3], {a: [4]}]`},
+		{`clone({"a": 1, "b": [2]})`, `{a: 1, b: [2]}`},
+		{`let a = [1, [2]]; clone(a) == a`, "true"},
+		{`clone()`, "ERROR: wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	nested := &object.Array{Elements: []object.Object{&object.Integer{Value: 1}}}
+	original := &object.Array{Elements: []object.Object{nested}}
+	cloned, ok := New().cloneObject(original, map[object.Object]bool{}).(*object.Array)
+	if !ok {
+		t.Fatalf("clone did not return an Array")
+	}
+	if cloned == original || cloned.Elements[0] == nested {
+		t.Errorf("clone returned the original arrays")
+	}
+
+	circular := &object.Array{}
+	circular.Elements = []object.Object{&object.Array{Elements: []object.Object{circular}}}
+	errObj, ok := New().cloneObject(circular, map[object.Object]bool{}).(*object.Error)
+	if !ok {
+		t.Fatalf("clone of circular array did not return an error")
+	}
+	if errObj.Message != "cannot clone circular reference: ARRAY" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestFreezeBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`isFrozen([1])`, false},
+		{`isFrozen({})`, false},
+		{`isFrozen(1)`, true},
+		{`isFrozen("monkey")`, true},
+		{`isFrozen(freeze([1]))`, true},
+		{`let a = [1]; freeze(a); isFrozen(a)`, true},
+		{`let a = [[1], {"b": [2]}]; freeze(a); isFrozen(a[0])`, true},
+		{`let a = [[1], {"b": [2]}]; freeze(a); isFrozen(a[1]["b"])`, true},
+		{`let h = {"a": [1]}; freeze(h); isFrozen(h["a"])`, true},
+		{`let a = freeze([[1]]); isFrozen(clone(a))`, false},
+		{`let a = freeze([[1]]); isFrozen(clone(a)[0])`, false},
+		{`freeze([1, 2]) == [1, 2]`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestWeakRefBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`weakRef([1, 2])`, "weakRef([1, 2])"},
+		{`let a = {"a": 1}; deref(weakRef(a)) == a`, "true"},
+		{`deref(weakRef(5))`, "5"},
+		{`deref(5)`, "ERROR: argument to `deref` must be WEAK_REF, got INTEGER"},
+		{`weakRef()`, "ERROR: wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStringLiteralsAreInterned(t *testing.T) {
+	defer object.PurgeInternCache()
+
+	evaluated := testEval(`["monkey", "monkey"]`)
+	array, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if array.Elements[0] != array.Elements[1] {
+		t.Errorf("string literals with the same value are different objects")
+	}
+}
+
+func TestCallProfile(t *testing.T) {
+	input := `let double = fn(x) { x * 2 };
+let twice = fn(f, x) { f(f(x)) };
+twice(double, 1) + fn(x) { x }(1);
+double(1);
+let fns = [fn(x) { x }];
+fns[0](1) + fns[0](1);`
+
+	l := lexer.NewLexer(input)
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+
+	e := New(WithCallProfile())
+	testIntegerObject(t, e.Eval(context.Background(), program, object.NewEnvironment()), 2)
+
+	expected := []struct {
+		name  string
+		calls int64
+	}{
+		{"double", 3},
+		{"fn@5:12", 2},
+		{"fn@3:20", 1},
+		{"twice", 1},
+	}
+
+	profile := e.CallProfile()
+	if len(profile) != len(expected) {
+		t.Fatalf("wrong number of functions profiled. expected=%d, got=%d (%+v)", len(expected), len(profile), profile)
+	}
+
+	for i, tt := range expected {
+		if profile[i].Name != tt.name || profile[i].Calls != tt.calls {
+			t.Errorf("profile[%d] wrong. expected=%s:%d, got=%s:%d", i, tt.name, tt.calls, profile[i].Name, profile[i].Calls)
+		}
+	}
+
+	if New().CallProfile() != nil {
+		t.Errorf("CallProfile should be nil when not enabled")
+	}
+
+	// Each call site looks up its own *object.Builtin, but they share a row
+	l = lexer.NewLexer("clone([1]); clone([2]); let f = fn() { clone([3]) }; f();")
+	p = parser.NewParser(l)
+	e = New(WithCallProfile())
+	e.Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+
+	profile = e.CallProfile()
+	if len(profile) != 2 || profile[0].Name != "clone" || profile[0].Calls != 3 {
+		t.Errorf("expected one row for clone with 3 calls. got=%+v", profile)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	input := `let f = fn(x) {
+  if (x > 1) {
+    return 1;
+  }
+  2;
+};
+f(0);`
+
+	l := lexer.NewLexer(input)
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+
+	e := New(WithCoverage("f.mky"))
+	testIntegerObject(t, e.Eval(context.Background(), program, object.NewEnvironment()), 2)
+
+	coverage := e.Coverage()
+	expected := map[int]bool{1: true, 2: true, 3: false, 5: true, 7: true}
+	if len(coverage["f.mky"]) != len(expected) {
+		t.Errorf("wrong lines covered. expected=%v, got=%v", expected, coverage["f.mky"])
+	}
+	for line, covered := range expected {
+		if got, ok := coverage["f.mky"][line]; !ok || got != covered {
+			t.Errorf("wrong coverage of line %d. expected=%t, got=%v", line, covered, coverage["f.mky"])
+		}
+	}
+
+	if coverage.Percent() != 80 {
+		t.Errorf("wrong percentage. expected=80, got=%v", coverage.Percent())
+	}
+
+	var out bytes.Buffer
+	if err := coverage.WriteLCOV(&out); err != nil {
+		t.Fatal(err)
+	}
+	lcov := "TN:\nSF:f.mky\nDA:1,1\nDA:2,1\nDA:3,0\nDA:5,1\nDA:7,1\nLF:5\nLH:4\nend_of_record\n"
+	if out.String() != lcov {
+		t.Errorf("wrong LCOV. expected=%q, got=%q", lcov, out.String())
+	}
+
+	if New().Coverage() != nil {
+		t.Errorf("Coverage should be nil when not enabled")
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	input := `let inner = fn(x) { x + true };
+let outer = fn(x) {
+  inner(x)
+};
+outer(1);`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expected := []object.StackFrame{
+		{FunctionName: "inner", Line: 3, Col: 3},
+		{FunctionName: "outer", Line: 5, Col: 1},
+	}
+	if len(errObj.StackTrace) != len(expected) {
+		t.Fatalf("wrong stack trace length. expected=%d, got=%d (%+v)", len(expected), len(errObj.StackTrace), errObj.StackTrace)
+	}
+	for i, frame := range expected {
+		if errObj.StackTrace[i] != frame {
+			t.Errorf("frame %d wrong. expected=%s, got=%s", i, frame, errObj.StackTrace[i])
+		}
+	}
+
+	evaluated = testEval("1 + true")
+	if errObj, ok := evaluated.(*object.Error); !ok || len(errObj.StackTrace) != 0 {
+		t.Errorf("errors outside of functions should have no stack trace. got=%+v", evaluated)
+	}
+
+	trace := builtinStackTrace(New(), errObj)
+	if trace.Inspect() != "[{function: inner, line: 3, col: 3}, {function: outer, line: 5, col: 1}]" {
+		t.Errorf("wrong stackTrace result. got=%s", trace.Inspect())
+	}
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"try 1 + 2", 3},
+		{"try 1 + true", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{"let r = try 1 + true; 5", 5},
+		{"let r = try [1][true]; r", "ERROR: index operator not supported: ARRAY"},
+		{"let f = fn() { 1 + true }; let r = try f(); count(stackTrace(r))", 1},
+		{"let f = fn() { let r = try missing; return 2; }; f()", 2},
+		{"let e = try 1/0; e", "ERROR: division by zero"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if _, ok := evaluated.(*object.ErrorValue); !ok || evaluated.Inspect() != expected {
+				t.Errorf("wrong caught error for %q. expected=%q, got=%T(%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+
+	// Without a try the error still stops the program
+	evaluated := testEval("let r = 1 + true; 5")
+	if _, ok := evaluated.(*object.Error); !ok {
+		t.Errorf("expected an error without try. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestTryCatchStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+		expected       interface{}
+	}{
+		{`try { puts("body"); 1 } catch (err) { puts("catch") } finally { puts("finally") }`, "body\nfinally\n", 1},
+		{`try { 1 + true; puts("unreachable") } catch (err) { puts(err); 2 }`, "ERROR: type mismatch: INTEGER + BOOLEAN\n", 2},
+		{`try { 1 + true } catch (err) { 2 } finally { puts("finally") }`, "finally\n", 2},
+		{`try { 1 + true } finally { puts("finally") }`, "finally\n", "type mismatch: INTEGER + BOOLEAN"},
+		{`try { 1 + true } catch (err) { missing } finally { puts("finally") }`, "finally\n", "identifier not found: missing"},
+		{`try { 1 } finally { missing }`, "", "identifier not found: missing"},
+		{`try { 1 + true } catch (err) { 2 } finally { missing }`, "", "identifier not found: missing"},
+		{`let f = fn() { try { return 1 } finally { puts("finally") } }; f()`, "finally\n", 1},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, "", 2},
+		{`let f = fn() { try { 1 + true } catch (err) { return 1 } finally { return 2 } }; f()`, "", 2},
+		{`try { 1 + true } catch (err) { 2 }; count(stackTrace(err))`, "", 0},
+		{`try { try { 1 + true } finally { puts("inner") } } catch (err) { puts("outer") }`, "inner\nouter\n", nil},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+
+		p := parser.NewParser(lexer.NewLexer(tt.input))
+		evaluated := New(WithIO(nil, &out)).Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+
+		if out.String() != tt.expectedOutput {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expectedOutput, out.String())
+		}
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("expected error %q for %q. got=%T(%+v)", expected, tt.input, evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestPutsBuiltin(t *testing.T) {
+	var out bytes.Buffer
+
+	l := lexer.NewLexer(`puts("hello", 1 + 2, [true]); puts();`)
+	p := parser.NewParser(l)
+	evaluated := New(WithIO(nil, &out)).Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+
+	testNullObject(t, evaluated)
+	if out.String() != "hello\n3\n[true]\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
+
+func TestWithStatements(t *testing.T) {
+	resource := `let open = fn(name) { {"name": name, "__close__": fn() { puts("close " + name) }} };`
+	tests := []struct {
+		input          string
+		expectedOutput string
+		expectedError  string
+	}{
+		{`with open("a") as fh { puts(fh["name"]) }`, "a\nclose a\n", ""},
+		{`with open("a") as fh { with open("b") as other { puts("body") } }`, "body\nclose b\nclose a\n", ""},
+		{`with open("a") as fh { 1 + true; puts("unreachable") }`, "close a\n", "type mismatch: INTEGER + BOOLEAN"},
+		{`let f = fn() { with open("a") as fh { return 1 }; puts("unreachable") }; puts(f())`, "close a\n1\n", ""},
+		{`with [1] as xs { puts(xs[0]) }`, "1\n", ""},
+		{`with {"__close__": fn() { 1 + true }} as fh { puts("body") }`, "body\n", "type mismatch: INTEGER + BOOLEAN"},
+		{`with {"__close__": fn() { 1 + true }} as fh { missing }`, "", "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+
+		p := parser.NewParser(lexer.NewLexer(resource + tt.input))
+		evaluated := New(WithIO(nil, &out)).Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+
+		if out.String() != tt.expectedOutput {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expectedOutput, out.String())
+		}
+		errObj, ok := evaluated.(*object.Error)
+		if tt.expectedError == "" && ok {
+			t.Errorf("unexpected error for %q: %s", tt.input, errObj.Message)
+		} else if tt.expectedError != "" && (!ok || errObj.Message != tt.expectedError) {
+			t.Errorf("expected error %q for %q. got=%T(%+v)", tt.expectedError, tt.input, evaluated, evaluated)
+		}
+	}
+}
+
+func TestPrintErrBuiltin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	l := lexer.NewLexer(`printErr("oops", 1 + 2); puts("fine"); printErr();`)
+	p := parser.NewParser(l)
+	evaluated := New(WithStdout(&stdout), WithStderr(&stderr)).Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+
+	testNullObject(t, evaluated)
+	if stderr.String() != "oops\n3\n" {
+		t.Errorf("wrong stderr output. got=%q", stderr.String())
+	}
+	if stdout.String() != "fine\n" {
+		t.Errorf("wrong stdout output. got=%q", stdout.String())
+	}
+}
+
+func TestReadBuiltin(t *testing.T) {
+	var out bytes.Buffer
+	in := strings.NewReader("  monkey \nlast")
+
+	l := lexer.NewLexer(`[read("name? "), read(), read()]`)
+	p := parser.NewParser(l)
+	evaluated := New(WithIO(in, &out)).Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+
+	if evaluated.Inspect() != "[monkey, last, null]" {
+		t.Errorf("wrong result. got=%s", evaluated.Inspect())
+	}
+	if out.String() != "name? " {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+
+	l = lexer.NewLexer(`read(1)`)
+	p = parser.NewParser(l)
+	evaluated = New(WithIO(in, &out)).Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "argument to `read` must be STRING, got INTEGER" {
+		t.Errorf("expected an error for read(1), got=%v", evaluated)
+	}
+}
+
+type point struct {
+	X, Y int
+}
+
+func (p point) Add(other point) point {
+	return point{p.X + other.X, p.Y + other.Y}
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"p.X", 1},
+		{"p.Add(p).Y", 4},
+		{"let q = p.Add({\"X\": 2, \"y\": 3}); q.X + q.Y", 8},
+		{"p.Z", "eval.point has no exported field or method Z"},
+		{"[1].X", "member access not supported: ARRAY"},
+		{"p.Add(1)", "argument 1: cannot convert INTEGER to eval.point"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := parser.NewParser(l)
+		env := object.NewEnvironment()
+		env.Set("p", &object.GoObject{Value: point{1, 2}})
+
+		evaluated := Eval(p.ParseProgram(), env)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	RegisterModule("os", map[string]object.Object{})
+	RegisterModule("shapes", map[string]object.Object{"sides": object.NewInteger(4)})
+	RegisterBuiltin("hostName", func(args ...object.Object) object.Object {
+		return object.NewInteger(1)
+	})
+
+	tests := []struct {
+		input    string
+		opts     []Option
+		expected interface{}
+	}{
+		{`debug(fn() { 1 })`, []Option{WithSandbox()}, "built-in not available in sandbox"},
+		{`let d = debug; 5`, []Option{WithSandbox()}, 5},
+		{`import("os")`, []Option{WithSandbox()}, "module not available in sandbox: os"},
+		{`clone(1)`, []Option{WithAllowedBuiltins([]string{"freeze"})}, "built-in not available in sandbox"},
+		{`freeze(1)`, []Option{WithAllowedBuiltins([]string{"freeze"})}, 1},
+		{`debug(fn() { 1 })`, []Option{WithSandbox(), WithAllowedBuiltins([]string{"debug"})}, "built-in not available in sandbox"},
+		{`hostName()`, nil, 1},
+		{`hostName()`, []Option{WithSandbox()}, "built-in not available in sandbox"},
+		{`hostName()`, []Option{WithSandbox(), WithAllowedBuiltins([]string{"hostName"})}, 1},
+		{`import("shapes").sides`, nil, 4},
+		{`import("shapes")`, []Option{WithSandbox()}, "module not available in sandbox: shapes"},
+		{`import("shapes").sides`, []Option{WithSandbox(), WithAllowedBuiltins([]string{"import", "shapes"})}, 4},
+		{
+			`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(20)`,
+			[]Option{WithSandbox(), WithMaxCallDepth(10)},
+			"maximum call depth of 10 exceeded",
+		},
+		{
+			`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(10)`,
+			[]Option{WithSandbox(), WithMaxCallDepth(11)},
+			0,
+		},
+		{
+			`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) + f(n - 1) } }; f(30)`,
+			[]Option{WithSandbox(), WithTimeout(10 * time.Millisecond)},
+			"execution timed out",
+		},
+		{`1 / 0`, []Option{WithSandbox()}, "division by zero"},
+		{`repeat([1], 40000000)`, []Option{WithSandbox()}, "memory limit exceeded"},
+		{`count(repeat([1], 200000))`, []Option{WithMaxMemoryBytes(1 << 20), WithSandbox()}, "memory limit exceeded"},
+		{`count(repeat([1], 200000))`, []Option{WithSandbox()}, 200000},
+		{`chan(100000)`, []Option{WithMaxMemoryBytes(1 << 20)}, "memory limit exceeded"},
+		{`chan(9223372036854775807)`, []Option{WithSandbox()}, "channel size must be at most 268435456, got 9223372036854775807"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := parser.NewParser(l)
+		evaluated := New(tt.opts...).Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	input := "let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) + f(n - 1) } }; f(40)"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	l := lexer.NewLexer(input)
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+
+	evaluated := New().Eval(ctx, program, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "execution timed out" {
+		t.Fatalf("expected a timeout error. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	evaluated = New().Eval(ctx, program, object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "execution cancelled" {
+		t.Errorf("expected a cancelled error. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestMaxMemoryBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		limit    int64
+		expected interface{}
+	}{
+		{`let a = [1, 2, 3]; a[2]`, 1024, 3},
+		{`let grow = fn(a, n) { if (n == 0) { a } else { grow([a, a, a, a], n - 1) } }; grow([], 40); 1`, 1024, "memory limit exceeded"},
+		{`"abc" + "def"`, 5, "memory limit exceeded"},
+		{`{"a": 1, "b": 2}["b"]`, 128, 2},
+		{`{"a": 1, "b": 2, "c": 3}`, 128, "memory limit exceeded"},
+		{`clone([[1], [2], [3]])`, 24, "memory limit exceeded"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := parser.NewParser(l)
+		e := New(WithMaxMemoryBytes(tt.limit))
+		evaluated := e.Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+
+	if usage := New().MemoryUsage(); usage != 0 {
+		t.Errorf("memory should not be tracked without a limit. got=%d", usage)
+	}
+}
+
+func TestMemoryIsReleased(t *testing.T) {
+	e := New(WithMaxMemoryBytes(1 << 20))
+
+	func() {
+		l := lexer.NewLexer(`let a = [1, 2, 3, 4]; let b = {"a": a}; 1`)
+		p := parser.NewParser(l)
+		e.Eval(context.Background(), p.ParseProgram(), object.NewEnvironment())
+	}()
+
+	if e.MemoryUsage() != 8*4+64 {
+		t.Fatalf("wrong memory usage. expected=%d, got=%d", 8*4+64, e.MemoryUsage())
+	}
+
+	// Finalizers run some time after the objects are collected
+	for i := 0; i < 100 && e.MemoryUsage() != 0; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if e.MemoryUsage() != 0 {
+		t.Errorf("memory was not released. got=%d", e.MemoryUsage())
+	}
+}
+
+func TestMemoryReservedBeforeAllocation(t *testing.T) {
+	const limit = 1024
+	tests := []string{
+		`repeat("a", 10000000)`,
+		"repeat([1], 10000000)",
+		`let s = repeat("a", 600); s + s`,
+		"let a = repeat([1], 100); zip(a, a)",
+		"let a = repeat([1], 100); chunk(a, 1)",
+	}
+
+	for _, input := range tests {
+		l := lexer.NewLexer(input)
+		p := parser.NewParser(l)
+		program := p.ParseProgram()
+		e := New(WithMaxMemoryBytes(limit))
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		evaluated := e.Eval(context.Background(), program, object.NewEnvironment())
+		runtime.ReadMemStats(&after)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != "memory limit exceeded" {
+			t.Errorf("expected memory limit error for %q. got=%s", input, evaluated.Inspect())
+			continue
+		}
+		if usage := e.MemoryUsage(); usage > limit {
+			t.Errorf("memory usage passed the limit for %q. got=%d", input, usage)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64*limit {
+			t.Errorf("too much allocated for %q. got=%d", input, allocated)
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for (x in [1, 2, 3]) { let sum = sum + x; }; sum", 6},
+		{`let keys = ""; for (k in {"a": 1, "b": 2}) { let keys = keys + k; }; keys`, "ab"},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } 0 }; f()", 20},
+		{"for (x in [1, 2]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"for (x in []) { x }", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestGoroutinesAndChannels(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let ch = chan(1); send(ch, 5); recv(ch)", 5},
+		{"let ch = chan(); go send(ch, 7); recv(ch)", 7},
+		{"let ch = chan(); close(ch); recv(ch)", nil},
+		{
+			`let ch = chan();
+let produce = fn(n) { for (x in [1, 2, 3]) { send(ch, x * n); } close(ch); };
+go produce(10);
+let sum = 0;
+for (x in ch) { let sum = sum + x; }
+sum`,
+			60,
+		},
+		{
+			`let results = chan(10);
+let group = wg();
+let work = fn(n) { send(results, n * n); wgDone(group); };
+for (n in [1, 2, 3, 4]) { wgAdd(group, 1); go work(n); }
+wgWait(group);
+close(results);
+let total = 0;
+for (r in results) { let total = total + r; }
+total`,
+			30,
+		},
+		{"let ch = chan(); close(ch); close(ch)", "close of closed channel"},
+		{"let ch = chan(1); close(ch); send(ch, 1)", "send on closed channel"},
+		{"wgDone(wg())", "sync: negative WaitGroup counter"},
+		{"chan(-1)", "channel size must not be negative, got -1"},
+		{"chan(9223372036854775807)", "channel size must be at most 268435456, got 9223372036854775807"},
+		{"recv(1)", "argument to `recv` must be CHANNEL, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestReceiveIsCancelled(t *testing.T) {
+	l := lexer.NewLexer("recv(chan())")
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	evaluated := New().Eval(ctx, program, object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "execution timed out" {
+		t.Errorf("expected a timeout error. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestSleepBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"sleep(1)", nil},
+		{"sleep(0)", nil},
+		{"sleep(-5)", nil},
+		{`sleep("1")`, "argument to `sleep` must be INTEGER, got STRING"},
+		{"sleep()", "wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, expected, evaluated)
+			}
+		}
+	}
+
+	l := lexer.NewLexer("sleep(10000)")
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	evaluated := New().Eval(ctx, program, object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "execution cancelled" {
+		t.Errorf("expected a cancelled error. got=%T(%+v)", evaluated, evaluated)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sleep wasn't interrupted, took %s", elapsed)
+	}
+}
+
+// Run with -race. The loop binds n and last in the global environment while
+// the goroutines read it.
+func TestGoroutinesShareGlobals(t *testing.T) {
+	input := `let results = chan(100);
+let group = wg();
+for (n in 1..100) {
+  wgAdd(group, 1);
+  go fn(n) { send(results, n * 2); wgDone(group); }(n);
+  let last = n;
+}
+wgWait(group);
+close(results);
+let total = 0;
+for (r in results) { let total = total + r; }
+total + last`
+
+	testIntegerObject(t, testEval(input), 10100+100)
+}
+
+func TestMutexBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			`let m = mutex();
+let total = chan(1);
+send(total, 0);
+let group = wg();
+let add = fn(n) { withLock(m, fn() { send(total, recv(total) + n); }); wgDone(group); };
+for (n in [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]) { wgAdd(group, 1); go add(n); }
+wgWait(group);
+recv(total)`,
+			55,
+		},
+		{"let m = mutex(); lock(m); unlock(m); lock(m); unlock(m)", nil},
+		{"let m = mutex(); lock(m); lock(m)", "mutex is already locked by this goroutine"},
+		{"let m = mutex(); withLock(m, fn() { lock(m) })", "mutex is already locked by this goroutine"},
+		{"let m = mutex(); withLock(m, fn() { return 1; }); lock(m); 2", 2},
+		{"unlock(mutex())", "unlock of unlocked mutex"},
+		{"lock(1)", "argument to `lock` must be MUTEX, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestFutures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = spawn(fn() { 1 + 2 }); await(f)", 3},
+		{
+			`let calls = chan(10);
+let f = spawn(fn() { send(calls, 1); 5 });
+let result = await(f) + await(f);
+close(calls);
+let n = 0;
+for (c in calls) { let n = n + c; }
+result * 10 + n`,
+			101,
+		},
+		{
+			`let ch = chan();
+let f = spawn(fn() { recv(ch) });
+let before = isReady(f);
+send(ch, 4);
+let v = await(f);
+if (!before && isReady(f)) { v } else { 0 }`,
+			4,
+		},
+		{"await(spawn(fn() { return 6; 7 }))", 6},
+		{"await(spawn(fn() { }))", nil},
+		{"await(spawn(fn() { missing }))", "identifier not found: missing"},
+		{"spawn(1)", "argument to `spawn` must be FUNCTION, got INTEGER"},
+		{"await(1)", "argument to `await` must be FUTURE, got INTEGER"},
+		{"isReady(1)", "argument to `isReady` must be FUTURE, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("expected error %q for %q. got=%T(%+v)", expected, tt.input, evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestSemaphores(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			`let sem = semaphore(2);
+let state = chan(1);
+send(state, [0, 0]);
+let group = wg();
+let work = fn() {
+  withSemaphore(sem, fn() {
+    let [running, peak] = recv(state);
+    send(state, [running + 1, if (running + 1 > peak) { running + 1 } else { peak }]);
+    sleep(5);
+    let [running, peak] = recv(state);
+    send(state, [running - 1, peak]);
+  });
+  wgDone(group);
+};
+for (n in [1, 2, 3, 4, 5, 6]) { wgAdd(group, 1); go work(); }
+wgWait(group);
+let [running, peak] = recv(state);
+peak`,
+			2,
+		},
+		{"let sem = semaphore(1); acquire(sem); release(sem); acquire(sem); release(sem)", nil},
+		{"let sem = semaphore(1); withSemaphore(sem, fn() { return 1; }); acquire(sem); 2", 2},
+		{"let sem = semaphore(1); let err = try withSemaphore(sem, fn() { missing }); acquire(sem); 3", 3},
+		{"release(semaphore(1))", "release of unacquired semaphore"},
+		{"semaphore(0)", "semaphore size must be positive, got 0"},
+		{"acquire(1)", "argument to `acquire` must be SEMAPHORE, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("expected error %q for %q. got=%T(%+v)", expected, tt.input, evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestObservables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			`let out = chan(10);
+let numbers = observable(fn(next) { for (x in [1, 2, 3, 4]) { next(x); } });
+subscribe(map(filter(numbers, fn(x) { x > 2 }), fn(x) { x * 10 }), fn(x) { send(out, x) });
+recv(out) + recv(out)`,
+			70,
+		},
+		{
+			`let out = chan(100);
+let done = chan(1);
+let counter = observable(fn(next) {
+  let loop = fn(i) { next(i); loop(i + 1) };
+  send(done, try loop(1));
+});
+let sub = subscribe(counter, fn(x) { send(out, x) });
+let total = recv(out) + recv(out);
+unsubscribe(sub);
+unsubscribe(sub);
+recv(done);
+total`,
+			3,
+		},
+		{
+			`let done = chan(1);
+let numbers = observable(fn(next) { send(done, try next(1)); });
+subscribe(numbers, fn(x) { missing });
+recv(done);
+5`,
+			5,
+		},
+		{"observable(1)", "argument to `observable` must be FUNCTION, got INTEGER"},
+		{"subscribe(1, fn(x) { x })", "argument to `subscribe` must be OBSERVABLE, got INTEGER"},
+		{"map([1], fn(x) { x })", "argument to `map` must be OBSERVABLE, got ARRAY"},
+		{"filter(observable(fn(next) { }), 1)", "argument to `filter` must be FUNCTION, got INTEGER"},
+		{"unsubscribe(1)", "argument to `unsubscribe` must be SUBSCRIPTION, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("expected error %q for %q. got=%T(%+v)", expected, tt.input, evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestSelectStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = chan(1); let b = chan(1); send(b, 2); select { case (x in a) { x } case (y in b) { y * 10 } }", 20},
+		{"let a = chan(); go fn() { send(a, 5) }(); select { case (x in a) { x } }", 5},
+		{"let a = chan(); select { case (x in a) { x } default { 3 } }", 3},
+		{"let a = chan(); close(a); select { case (x in a) { x } }", nil},
+		{"let a = chan(1); send(a, 1); select { case (a) { 4 } }", 4},
+		{"let f = fn(a) { select { case (x in a) { return x + 1; } }; 0 }; let a = chan(1); send(a, 1); f(a)", 2},
+		{"select { case (1) { 1 } }", "select case must be CHANNEL, got INTEGER"},
+		{"select { default { } }", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestSelectStatementTimeout(t *testing.T) {
+	program := parser.NewParser(lexer.NewLexer("let a = chan(); select { case (x in a) { x } }")).ParseProgram()
+
+	evaluated := New(WithTimeout(10*time.Millisecond)).Eval(context.Background(), program, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "execution timed out" {
+		t.Errorf("expected a timeout error. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	tests := []string{
+		"let m = mutex(); go fn() { lock(m) }(); sleep(50); lock(m); 1",
+		"let m = mutex(); go fn() { lock(m) }(); sleep(50); withLock(m, fn() { 1 })",
+	}
+
+	for _, input := range tests {
+		program := parser.NewParser(lexer.NewLexer(input)).ParseProgram()
+
+		evaluated := New(WithTimeout(200*time.Millisecond)).Eval(context.Background(), program, object.NewEnvironment())
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != "execution timed out" {
+			t.Errorf("expected a timeout error for %q. got=%T(%+v)", input, evaluated, evaluated)
+		}
+	}
+}
+
+func TestCallSiteBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"__line__()", 1},
+		{"__col__()", 1},
+		{"let x = 1;\n\nlet y = __line__();\ny", 3},
+		{"let x = 1;\nlet y =   __col__();\ny", 11},
+		{"let where = fn() { __line__() };\n\nwhere()", 1},
+		{"let f = __col__;\n  f()", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestSourceBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"source(fn(x, y) { let z = x+y; z*2 })", "fn(x, y) {\n    let z = x + y;\n    z * 2;\n}"},
+		{"let f = fn() {}; source(f)", "fn f() {}"},
+		{"source(puts)", "<built-in>"},
+		{"## Adds one.\n##\n## Really.\nlet inc = fn(x) { x + 1 }; source(inc)", "## Adds one.\n##\n## Really.\nfn inc(x) {\n    x + 1;\n}"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("source(1)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "argument to `source` must be FUNCTION, got INTEGER" {
+		t.Errorf("expected an error for a non-function. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"fn add(x, y) { x + y }; add(1, 2)", 3},
+		{"fn fib(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) } fib(10)", 55},
+		{"fn outer() { fn inner() { 4 } inner() } outer()", 4},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("fn add(x, y) { x + y }; add")
+	if evaluated.Inspect() != "fn add(x, y) {\n    x + y;\n}" {
+		t.Errorf("wrong inspect for a declared function. got=%q", evaluated.Inspect())
+	}
+
+	evaluated = testEval("fn f(a, b) { if (a > b) { a } else { b } }; f")
+	expected := "fn f(a, b) {\n    if (a > b) {\n        a;\n    } else {\n        b;\n    }\n}"
+	if evaluated.Inspect() != expected {
+		t.Errorf("wrong inspect for a function with if/else. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+}
+
+func TestSelf(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"fn(n) { if (n < 2) { return 1; } n * self(n - 1) }(5)", 120},
+		{"let outer = fn() { let inner = fn(n) { if (n == 0) { 0 } else { self(n - 1) } }; inner(3) }; outer()", 0},
+		{"fn(self) { self }(7)", 7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	// self shadows an outer binding
+	evaluated := testEval("let self = 1; fn() { self }()")
+	if _, ok := evaluated.(*object.Function); !ok {
+		t.Errorf("expected self to be the function. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestFunctionNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let add = fn(x, y) { x + y }; add", "add"},
+		{"fn add(x, y) { x + y }; add", "add"},
+		{"let f = fn g() { 1 }; f", "g"},
+		{"fn() { 1 }", ""},
+	}
+
+	for _, tt := range tests {
+		fn, ok := testEval(tt.input).(*object.Function)
+		if !ok {
+			t.Errorf("object is not Function for %q", tt.input)
+			continue
+		}
+		if fn.Name() != tt.expected {
+			t.Errorf("wrong name for %q. expected=%q, got=%q", tt.input, tt.expected, fn.Name())
+		}
+	}
+
+	// A named function can call itself even once its name is bound to
+	// something else
+	input := "let count = fn(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } }; let f = count; let count = 10; f(3)"
+	testIntegerObject(t, testEval(input), 3)
+}
+
+func TestClosuresShareEnvironment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; let f = fn() { x }; let x = 2; f()", 2},
+		{"let make = fn() { let n = 1; let get = fn() { n }; let n = 2; get }; make()()", 2},
+		{"let f = fn() { later }; let later = 3; f()", 3},
+		{"let x = 1; let f = fn() { let x = 5; x }; f(); x", 1},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestLetInExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 5 in x * x", 25},
+		{"let x = 1; let y = let x = 2 in x * 10; x + y", 21},
+		{"let sq = fn(n) { let m = n in m * m }; sq(4)", 16},
+		{"let a = 1 in let b = 2 in a + b", 3},
+		{"(let x = 3 in x) + 1", 4},
+		// At the start of a statement, the same as in a let value
+		{"let y = let x = 1 in x + 1; y", 2},
+		{"let x = 1 in x + 1", 2},
+		{"let y = 2; let x = 5 in x * y", 10},
+		{"let x = 5 in x * x; 3", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("let y = let x = 1 in x; x")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "identifier not found: x" {
+		t.Errorf("expected the let-in binding to not be visible afterwards. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestSwitchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let s = switch 2 { case 1: "one", case 2: "two", default: "other" }; s`, "two"},
+		{`switch 5 { case 1: "one", default: "other" }`, "other"},
+		{`switch 5 { case 1: "one" }`, nil},
+		{`switch [1, 2] { case [1]: 1, case [1, 2]: 2 }`, 2},
+		{`let x = 3; switch x * 2 { case x + x: { let y = x; y * 10 } }`, 30},
+		{`let f = fn(x) { switch x { case 1: { return "early" } }; "late" }; f(1)`, "early"},
+		{`switch 1 { case 1: 1, case 1: 2 }`, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	evaluated := testEval(`switch 1 { case y: 1 }`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "identifier not found: y" {
+		t.Errorf("expected an error from the case value. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	// An empty block arm is null, so it can be used as a value
+	for _, input := range []string{
+		`switch 1 { case 1: {} } + 1`,
+		`match 1 { case 1: {} } + 1`,
+	} {
+		evaluated := testEval(input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != "type mismatch: NULL + INTEGER" {
+			t.Errorf("expected a type mismatch for %q. got=%T(%+v)", input, evaluated, evaluated)
+		}
+	}
+	for _, input := range []string{
+		`clone(switch 1 { case 1: {} })`,
+		`clone(match 1 { case _: {} })`,
+		`clone(switch 1 { default: {} })`,
+		`{"a": switch 1 { case 1: {} }}["a"]`,
+	} {
+		testNullObject(t, testEval(input))
+	}
+	evaluated = testEval(`countBy([1, 2], fn(x) { switch x { case 1: {} } })`)
+	if hash, ok := evaluated.(*object.Hash); !ok || len(hash.Pairs) != 1 {
+		t.Errorf("expected one group from countBy. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match 1 { case 1: "one", case 2: "two" }`, "one"},
+		{`match 2 { case 1: "one", case 2: "two" }`, "two"},
+		{`match 3 { case 1: "one", default: "many" }`, "many"},
+		{`match 3 { case 1: "one" }`, nil},
+		{`match "b" { case "a": 1, case "b": 2 }`, 2},
+		{`match true { case false: 1, case true: 2 }`, 2},
+		{`match -1 { case -1: 1, case _: 2 }`, 1},
+		{`match 5 { case _: 1 }`, 1},
+		{`match 5 { case n: n * 2 }`, 10},
+		{`match [1, 2, 3] { case [h, ...t]: h + t[1] }`, 4},
+		{`match [1, 2, 3] { case [a, b]: 1, case [a, b, c]: a + b + c }`, 6},
+		{`match [] { case [h, ...t]: 1, case []: 2 }`, 2},
+		{`match [1, [2, 3]] { case [a, [b, c]]: a + b + c }`, 6},
+		{`match [1] { case [_, ...rest]: rest }`, []int64{}},
+		{`match {"a": 1, "b": 2} { case {"a": 2}: 0, case {"a": a}: a }`, 1},
+		{`match {"a": 1} { case {"c": c}: c, default: 9 }`, 9},
+		{`match 1 { case [a]: a, case {"a": a}: a, default: 0 }`, 0},
+		{`let x = 1; match [2] { case [x, y]: x, default: x }`, 1},
+		{`match 5 { case n if n < 0: "negative", case n if n > 0: "positive", case _: "zero" }`, "positive"},
+		{`match 0 { case n if n < 0: "negative", case n if n > 0: "positive", case _: "zero" }`, "zero"},
+		{`match [1, 2] { case [a, b] if a > b: a, case [a, b]: b }`, 2},
+		{`match 1 { case n if missing: 1 }`, "identifier not found: missing"},
+		{`match 1 { case a + b: 1 }`, "invalid pattern: (a + b)"},
+		{`match [1, 2] { case [...a, b]: 1 }`, "... must be the last element of an array pattern"},
+		{`...a`, "... can only be used in a match pattern"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int64:
+			array, ok := evaluated.(*object.Array)
+			if !ok || len(array.Elements) != len(expected) {
+				t.Errorf("expected an array of %d elements for %q. got=%T(%+v)", len(expected), tt.input, evaluated, evaluated)
+			}
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestInOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"2 in [1, 2, 3]", true},
+		{"4 in [1, 2, 3]", false},
+		{"[1] in [[1], [2]]", true},
+		{`"a" in {"a": 1}`, true},
+		{`"b" in {"a": 1}`, false},
+		{`"ell" in "hello"`, true},
+		{`"z" in "hello"`, false},
+		{"4 not in [1, 2, 3]", true},
+		{`"a" not in {"a": 1}`, false},
+		{"let found = (2 in [1, 2]); found", true},
+		{"1 in 2", "unknown operator: INTEGER in INTEGER"},
+		{`1 in "a"`, "type mismatch: INTEGER in STRING"},
+		{`[1] in {"a": 1}`, "unusable as hash key: ARRAY"},
+		{"1 not in 2", "unknown operator: INTEGER in INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1..5", "1..5"},
+		{"let n = 3; 0...n", "0...3"},
+		{"(1..5) == (1..5)", true},
+		{"(1..5) == (1...5)", false},
+		{"let sum = 0; for (i in 1..4) { let sum = sum + i; }; sum", 10},
+		{"let sum = 0; for (i in 1...4) { let sum = sum + i; }; sum", 6},
+		{"for (i in 4..1) { i }", nil},
+		{"[1, 2, 3, 4][1..2]", "[2, 3]"},
+		{"[1, 2, 3, 4][1...2]", "[2]"},
+		{"[1, 2, 3, 4][2..10]", "[3, 4]"},
+		{"[1, 2, 3, 4][-5...1]", "[1]"},
+		{"[1, 2, 3, 4][3...1]", "[]"},
+		{"[1, 2, 3, 4][5..6]", "[]"},
+		{"[1, 2, 3][0..9223372036854775807]", "[1, 2, 3]"},
+		{"[1, 2, 3][1...9223372036854775807]", "[2, 3]"},
+		{"let n = 0; for (i in 9223372036854775806..9223372036854775807) { let n = n + 1; }; n", 2},
+		{"let n = 0; for (i in 9223372036854775806...9223372036854775807) { let n = i; }; n", 9223372036854775806},
+		{`1.."a"`, "range bounds must be INTEGER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && false", false},
+		{"true and true", true},
+		{"false || true", true},
+		{"false or false", false},
+		{"1 < 2 && 2 < 3", true},
+		{`let name = ""; name || "anonymous"`, ""},
+		{"let x = 0; x || 5", 0},
+		{"if (false) { 1 } || 5", 5},
+		{"1 && 2", 2},
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"false || undefined", "identifier not found: undefined"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestTernaryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true ? 1 : 2", 1},
+		{"false ? 1 : 2", 2},
+		{"1 < 2 ? 10 : 20", 10},
+		{"if (false) { 1 } ? 1 : 2", 2},
+		{"let x = -5; x < 0 ? -x : x", 5},
+		{"false ? 1 : true ? 2 : 3", 2},
+		{"true ? 1 : undefined", 1},
+		{"false ? undefined : 2", 2},
+		{"undefined ? 1 : 2", "identifier not found: undefined"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`contains("monkey", "key")`, true},
+		{`contains("monkey", "ape")`, false},
+		{`contains("monkey", "")`, true},
+		{`startsWith("monkey", "mon")`, true},
+		{`startsWith("monkey", "key")`, false},
+		{`endsWith("monkey", "key")`, true},
+		{`endsWith("monkey", "mon")`, false},
+		{`indexOf("monkey", "k")`, 3},
+		{`indexOf("monkey", "z")`, -1},
+		{`indexOf("héllo", "l")`, 3},
+		{`contains("monkey")`, "wrong number of arguments. got=1, want=2"},
+		{`contains(1, "a")`, "arguments to `contains` must be STRING, got INTEGER"},
+		{`startsWith("a", [])`, "arguments to `startsWith` must be STRING, got ARRAY"},
+		{`endsWith(true, "a")`, "arguments to `endsWith` must be STRING, got BOOLEAN"},
+		{`indexOf("a", 1)`, "arguments to `indexOf` must be STRING, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestRepeatBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`repeat("ha", 3)`, "hahaha"},
+		{`repeat("ha", 0)`, ""},
+		{"repeat([1], 3)", "[1, 1, 1]"},
+		{"repeat([1, 2], 2)", "[1, 2, 1, 2]"},
+		{"repeat([1], 0)", "[]"},
+		{"let a = [1]; let b = repeat(a, 2); a", "[1]"},
+		{`repeat("ha", -1)`, "count for `repeat` must not be negative, got -1"},
+		{`repeat("ha", "3")`, "count for `repeat` must be INTEGER, got STRING"},
+		{"repeat(1, 3)", "argument to `repeat` must be STRING or ARRAY, got INTEGER"},
+		{"repeat([1])", "wrong number of arguments. got=1, want=2"},
+		{`repeat("ab", 4611686018427387904)`, "result of `repeat` is too large"},
+		{"repeat([1, 2], 4611686018427387904)", "result of `repeat` is too large"},
+		{`repeat("", 4611686018427387904)`, ""},
+		{"repeat([], 4611686018427387904)", "[]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+			} else if _, ok := evaluated.(*object.String); ok {
+				testStringObject(t, evaluated, expected)
+			} else if evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestFlattenBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"flatten([1, [2, [3, [4]]], 5])", "[1, 2, 3, 4, 5]"},
+		{"flatten([1, [2, [3, [4]]], 5], 1)", "[1, 2, [3, [4]], 5]"},
+		{"flatten([1, [2, [3, [4]]], 5], 2)", "[1, 2, 3, [4], 5]"},
+		{"flatten([[1], [2]], 0)", "[[1], [2]]"},
+		{`flatten([[], ["a"], {"b": [1]}])`, "[a, {b: [1]}]"},
+		{"let a = [1]; let b = [a, a]; flatten(b)", "[1, 1]"},
+		{"flatten(1)", "argument to `flatten` must be ARRAY, got INTEGER"},
+		{"flatten([], -1)", "depth for `flatten` must not be negative, got -1"},
+		{`flatten([], "1")`, "depth for `flatten` must be INTEGER, got STRING"},
+		{"flatten()", "wrong number of arguments. got=0, want=1 or 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestZipBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`zip([1, 2, 3], ["a", "b", "c"])`, "[[1, a], [2, b], [3, c]]"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{"zip([1, 2], [3, 4], [5, 6])", "[[1, 3, 5], [2, 4, 6]]"},
+		{"zip([1, 2])", "[[1], [2]]"},
+		{"zip([1, 2], [])", "[]"},
+		{"zip()", "[]"},
+		{`unzip([[1, "a"], [2, "b"]])`, "[[1, 2], [a, b]]"},
+		{"unzip([[1, 2, 3], [4, 5]])", "[[1, 4], [2, 5]]"},
+		{"unzip([])", "[]"},
+		{"unzip(zip([1, 2], [3, 4]))", "[[1, 2], [3, 4]]"},
+		{"zip([1], 2)", "arguments to `zip` must be ARRAY, got INTEGER"},
+		{"unzip(1)", "argument to `unzip` must be ARRAY, got INTEGER"},
+		{"unzip([[1], 2])", "elements of the argument to `unzip` must be ARRAY, got INTEGER"},
+		{"unzip([1], [2])", "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestGroupByBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"groupBy([1, 2, 3, 4], fn(x) { x > 2 })", "{false: [1, 2], true: [3, 4]}"},
+		{`groupBy(["ab", "c", "de"], fn(s) { s == "c" ? "short" : "long" })`, "{long: [ab, de], short: [c]}"},
+		{"groupBy([], fn(x) { x })", "{}"},
+		{`groupBy([1, 2], fn(x) { x })["1"]`, "[1]"},
+		{"countBy([1, 2, 3, 4, 5], fn(x) { x < 3 })", "{true: 2, false: 3}"},
+		{"countBy([1, 1, 2], fn(x) { x })", "{1: 2, 2: 1}"},
+		{"groupBy([1, 2], fn(x) { x + true })", "type mismatch: INTEGER + BOOLEAN"},
+		{"countBy([1], fn(x) { return undefined })", "identifier not found: undefined"},
+		{"groupBy(1, fn(x) { x })", "argument to `groupBy` must be ARRAY, got INTEGER"},
+		{"countBy([1], 1)", "not a function: INTEGER"},
+		{"countBy([1])", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestQuantifierBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"any([1, 2, 3], fn(x) { x > 2 })", true},
+		{"any([1, 2, 3], fn(x) { x > 3 })", false},
+		{"any([], fn(x) { true })", false},
+		{"all([1, 2, 3], fn(x) { x > 0 })", true},
+		{"all([1, 2, 3], fn(x) { x > 1 })", false},
+		{"all([], fn(x) { false })", true},
+		{"any([1], fn(x) { 0 })", true},
+		// Stops at the first element that decides the result
+		{"any([1, 2], fn(x) { x == 1 || undefined })", true},
+		{"all([1, 2], fn(x) { x != 1 && undefined })", false},
+		{"all([1, 2], fn(x) { x == 1 || undefined })", "identifier not found: undefined"},
+		{"any(1, fn(x) { x })", "argument to `any` must be ARRAY, got INTEGER"},
+		{"all([1])", "wrong number of arguments. got=1, want=2"},
+		{"none([1, 2, 3], fn(x) { x > 3 })", true},
+		{"none([1, 2, 3], fn(x) { x > 2 })", false},
+		{"none([], fn(x) { true })", true},
+		{"none([1, 2], fn(x) { x == 1 || undefined })", false},
+		{"none([1], fn(x) { undefined })", "identifier not found: undefined"},
+		{"none(1, fn(x) { x })", "argument to `none` must be ARRAY, got INTEGER"},
+		{"count([1, 2, 3, 4], fn(x) { x > 2 })", 2},
+		{"count([], fn(x) { true })", 0},
+		{"count([1, 2, 3])", 3},
+		{"count([1, 2, 3], if (false) { 1 })", 3},
+		{"count([1], fn(x) { undefined })", "identifier not found: undefined"},
+		{"count(1)", "argument to `count` must be ARRAY, got INTEGER"},
+		{"count()", "wrong number of arguments. got=0, want=1 or 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestChunkBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"chunk([1, 2, 3, 4, 5], 2)", "[[1, 2], [3, 4], [5]]"},
+		{"chunk([1, 2, 3, 4], 2)", "[[1, 2], [3, 4]]"},
+		{"chunk([1, 2], 5)", "[[1, 2]]"},
+		{"chunk([], 3)", "[]"},
+		{"chunk([1, 2], 0)", "size for `chunk` must be positive, got 0"},
+		{"chunk([1, 2], -1)", "size for `chunk` must be positive, got -1"},
+		{`chunk([1, 2], "1")`, "size for `chunk` must be INTEGER, got STRING"},
+		{"chunk(1, 1)", "argument to `chunk` must be ARRAY, got INTEGER"},
+		{"chunk([1])", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTakeAndDropBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"take([1, 2, 3, 4], 3)", "[1, 2, 3]"},
+		{"take([1, 2], 5)", "[1, 2]"},
+		{"take([1, 2], 0)", "[]"},
+		{"drop([1, 2, 3, 4], 3)", "[4]"},
+		{"drop([1, 2], 5)", "[]"},
+		{"drop([1, 2], 0)", "[1, 2]"},
+		{"let a = [1, 2]; let b = drop(a, 1); a", "[1, 2]"},
+		{"take([1], -1)", "count for `take` must not be negative, got -1"},
+		{`drop([1], "1")`, "count for `drop` must be INTEGER, got STRING"},
+		{"drop(1, 1)", "argument to `drop` must be ARRAY, got INTEGER"},
+		{"take([1])", "wrong number of arguments. got=1, want=2"},
+		{"takeWhile([1, 2, 3, 4, 1], fn(x) { x < 3 })", "[1, 2]"},
+		{"takeWhile([1, 2], fn(x) { x < 3 })", "[1, 2]"},
+		{"takeWhile([5, 1], fn(x) { x < 3 })", "[]"},
+		{"dropWhile([1, 2, 3, 4, 1], fn(x) { x < 3 })", "[3, 4, 1]"},
+		{"dropWhile([1, 2], fn(x) { x < 3 })", "[]"},
+		{"dropWhile([], fn(x) { x < 3 })", "[]"},
+		// Stops at the first element the predicate is falsy for
+		{"takeWhile([1, 5, 2], fn(x) { x == 2 ? undefined : x < 3 })", "[1]"},
+		{"dropWhile([1, 5, 2], fn(x) { x == 2 ? undefined : x < 3 })", "[5, 2]"},
+		{"takeWhile([1], fn(x) { undefined })", "identifier not found: undefined"},
+		{"dropWhile(1, fn(x) { x })", "argument to `dropWhile` must be ARRAY, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestUniqueBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"unique([1, 2, 2, 3, 1])", "[1, 2, 3]"},
+		{`unique([[1], [1], "a", "a", {"b": 2}, {"b": 2}])`, "[[1], a, {b: 2}]"},
+		{"unique([1, true, 1 == 1])", "[1, true]"},
+		{"unique([])", "[]"},
+		{`uniqueBy([{"id": 1, "n": "a"}, {"id": 1, "n": "b"}, {"id": 2, "n": "c"}], fn(x) { x["id"] })`, "[{id: 1, n: a}, {id: 2, n: c}]"},
+		{"uniqueBy([1, 2, 3, 4], fn(x) { x > 2 })", "[1, 3]"},
+		{"uniqueBy([1], fn(x) { undefined })", "identifier not found: undefined"},
+		{"unique(1)", "argument to `unique` must be ARRAY, got INTEGER"},
+		{"uniqueBy(1, fn(x) { x })", "argument to `uniqueBy` must be ARRAY, got INTEGER"},
+		{"uniqueBy([1])", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`mapKeys({"a": 1, "b": 2}, fn(k) { k + k })`, "{aa: 1, bb: 2}"},
+		{`mapKeys({1: "x", 2: "y"}, fn(k) { k * 10 })["10"]`, "x"},
+		{`mapKeys({"a": 1, "b": 2, "c": 3}, fn(k) { k == "c" ? "a" : k })`, "{a: 3, b: 2}"},
+		{`mapValues({"a": 1, "b": 2}, fn(v) { v * 2 })`, "{a: 2, b: 4}"},
+		{`mapValues({1: 1, true: 2}, fn(v) { v })`, "{1: 1, true: 2}"},
+		{"mapValues({}, fn(v) { v })", "{}"},
+		{`let h = {"a": 1}; let m = mapValues(h, fn(v) { v + 1 }); h`, "{a: 1}"},
+		{`mapKeys({"a": 1}, fn(k) { undefined })`, "identifier not found: undefined"},
+		{`mapValues({"a": 1}, fn(v) { v + true })`, "type mismatch: INTEGER + BOOLEAN"},
+		{"mapKeys([1], fn(k) { k })", "argument to `mapKeys` must be HASH, got ARRAY"},
+		{"mapValues({})", "wrong number of arguments. got=1, want=2"},
+		{`mergeHash({"a": 1}, {"b": 2}, {"a": 3})`, "{a: 3, b: 2}"},
+		{`mergeHash({"a": 1})`, "{a: 1}"},
+		{"mergeHash()", "{}"},
+		{`let h = {"a": 1}; let m = mergeHash(h, {"a": 2}); h`, "{a: 1}"},
+		{`let h = freeze({"a": 1}); isFrozen(mergeHash(h))`, "false"},
+		{`mergeHash({"a": 1}, [1])`, "arguments to `mergeHash` must be HASH, got ARRAY"},
+		{`toPairs({"a": 1, "b": 2})`, "[[a, 1], [b, 2]]"},
+		{"toPairs({})", "[]"},
+		{`fromPairs([["a", 1], ["b", 2]])`, "{a: 1, b: 2}"},
+		{`fromPairs([["a", 1], ["b", 2], ["a", 3]])`, "{a: 3, b: 2}"},
+		{`fromPairs(toPairs({1: "x", true: [2]}))`, "{1: x, true: [2]}"},
+		{"fromPairs([])", "{}"},
+		{"toPairs([1])", "argument to `toPairs` must be HASH, got ARRAY"},
+		{"fromPairs({})", "argument to `fromPairs` must be ARRAY, got HASH"},
+		{"fromPairs([[1, 2, 3]])", "elements of the argument to `fromPairs` must be [key, value] pairs, got [1, 2, 3]"},
+		{"fromPairs([1])", "elements of the argument to `fromPairs` must be [key, value] pairs, got 1"},
+		{"fromPairs([[[1], 2]])", "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHashBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"hash(1) == hash(1)", true},
+		{"hash(1) == hash(2)", false},
+		{`hash("monkey") == hash("mon" + "key")`, true},
+		{`hash([1, {"a": 2}]) == hash([1, {"a": 2}])`, true},
+		{"hash(true) == hash(1 < 2)", true},
+		{"hash(true) == hash(false)", false},
+		{`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, true},
+		{`hash({"a": 1, "b": 2}) == hash({"b": 2, "a": 1})`, true},
+		{`hash({"a": [1, {"x": 1, "y": 2}]}) == hash({"a": [1, {"y": 2, "x": 1}]})`, true},
+		{`hash("1") == hash(1)`, false},
+		{`hash(["a, b"]) == hash(["a", "b"])`, false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("hash()")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "wrong number of arguments. got=0, want=1" {
+		t.Errorf("expected an error for hash(), got=%v", evaluated)
+	}
+}
+
+func TestComparableBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"comparable(1, 2)", -1},
+		{"comparable(2, 2)", 0},
+		{"comparable(3, 2)", 1},
+		{"comparable(-5, 2)", -1},
+		{`comparable("a", "b")`, -1},
+		{`comparable("b", "b")`, 0},
+		{`comparable("b", "abc")`, 1},
+		{`comparable(1, "1")`, "cannot compare INTEGER and STRING"},
+		{"comparable(true, false)", "cannot compare BOOLEAN and BOOLEAN"},
+		{"comparable(1)", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// spawn(fn) calls `fn` in a new goroutine, returning a future for its result
+func builtinSpawn(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	if !isCallable(args[0]) {
+		return newError("argument to `spawn` must be FUNCTION, got %s", args[0].Type())
+	}
+
+	future := object.NewFuture()
+	forked := e.fork()
+	go func() {
+		result := forked.applyFunction(args[0], nil)
+		if result == nil {
+			result = NULL
+		}
+		future.Resolve(result)
+	}()
+
+	return future
+}
+
+// await(future) blocks until the future's function has returned, returning
+// its result. An error from the function is returned as the error of await.
+func builtinAwait(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	future, ok := args[0].(*object.Future)
+	if !ok {
+		return newError("argument to `await` must be FUTURE, got %s", args[0].Type())
+	}
+
+	select {
+	case <-future.Done():
+		return future.Result()
+	case <-e.ctx.Done():
+		return e.contextError()
+	}
+}
+
+// isReady(future) reports whether the future's function has returned, without
+// waiting for it
+func builtinIsReady(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	future, ok := args[0].(*object.Future)
+	if !ok {
+		return newError("argument to `isReady` must be FUTURE, got %s", args[0].Type())
+	}
+
+	return nativeBoolToBooleanObject(future.Ready())
+}
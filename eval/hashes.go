@@ -0,0 +1,122 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// mapKeys(hash, fn) returns a new hash with each key replaced by `fn(key)`.
+// Results that aren't strings are turned into strings with Inspect. When two
+// keys map to the same string, the later value wins.
+func builtinMapKeys(e *Evaluator, args ...object.Object) object.Object {
+	return e.mapHash("mapKeys", args, func(pair object.HashPair) (object.Hashable, object.Object, object.Object) {
+		key := e.applyFunction(args[1], []object.Object{pair.Key})
+		if isError(key) {
+			return nil, nil, key
+		}
+		if s, ok := key.(*object.String); ok {
+			return s, pair.Value, nil
+		}
+		return &object.String{Value: key.Inspect()}, pair.Value, nil
+	})
+}
+
+// mapValues(hash, fn) returns a new hash with each value replaced by
+// `fn(value)`
+func builtinMapValues(e *Evaluator, args ...object.Object) object.Object {
+	return e.mapHash("mapValues", args, func(pair object.HashPair) (object.Hashable, object.Object, object.Object) {
+		value := e.applyFunction(args[1], []object.Object{pair.Value})
+		if isError(value) {
+			return nil, nil, value
+		}
+		return pair.Key.(object.Hashable), value, nil
+	})
+}
+
+// mapHash builds a new hash from the pairs `fn` returns for each pair of the
+// hash in args[0], in the same order
+func (e *Evaluator) mapHash(name string, args []object.Object, fn func(object.HashPair) (object.Hashable, object.Object, object.Object)) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("argument to `%s` must be HASH, got %s", name, args[0].Type())
+	}
+
+	mapped := object.NewHash()
+	for _, pair := range hash.Entries() {
+		key, value, err := fn(pair)
+		if err != nil {
+			return err
+		}
+		mapped.Set(key, value)
+	}
+	return e.track(mapped)
+}
+
+// mergeHash(h1, h2, ...) returns a new hash with the pairs of every argument.
+// When a key is in more than one, the value from the last one wins.
+func builtinMergeHash(e *Evaluator, args ...object.Object) object.Object {
+	merged := object.NewHash()
+	for _, arg := range args {
+		hash, ok := arg.(*object.Hash)
+		if !ok {
+			return newError("arguments to `mergeHash` must be HASH, got %s", arg.Type())
+		}
+		for _, pair := range hash.Entries() {
+			merged.Set(pair.Key.(object.Hashable), pair.Value)
+		}
+	}
+	return e.track(merged)
+}
+
+// toPairs(hash) returns the [key, value] pairs of `hash` in insertion order
+func builtinToPairs(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("argument to `toPairs` must be HASH, got %s", args[0].Type())
+	}
+
+	pairs := []object.Object{}
+	for _, pair := range hash.Entries() {
+		p := e.track(&object.Array{Elements: []object.Object{pair.Key, pair.Value}})
+		if isError(p) {
+			return p
+		}
+		pairs = append(pairs, p)
+	}
+	return e.track(&object.Array{Elements: pairs})
+}
+
+// fromPairs(arr) returns a hash from an array of [key, value] pairs, the
+// reverse of toPairs. When a key is repeated, the last value wins.
+func builtinFromPairs(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `fromPairs` must be ARRAY, got %s", args[0].Type())
+	}
+
+	hash := object.NewHash()
+	for _, el := range array.Elements {
+		pair, ok := el.(*object.Array)
+		if !ok || len(pair.Elements) != 2 {
+			return newError("elements of the argument to `fromPairs` must be [key, value] pairs, got %s", el.Inspect())
+		}
+
+		key, ok := pair.Elements[0].(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", pair.Elements[0].Type())
+		}
+		hash.Set(key, pair.Elements[1])
+	}
+	return e.track(hash)
+}
@@ -0,0 +1,188 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// evalMatchExpression evaluates the body of the first arm whose pattern
+// matches the subject, with the names bound by the pattern in scope. It
+// evaluates to null if no arm matches and there is no default.
+func (e *Evaluator) evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := e.evalNode(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		// Each arm gets its own scope, so a pattern that only partly
+		// matches doesn't leave any names behind
+		armEnv := object.NewEnclosedEnvironment(env)
+		matched, err := e.matchPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+
+		// The guard can use the names bound by the pattern
+		if arm.Guard != nil {
+			guard := e.evalNode(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return e.evalArmBody(arm.Body, armEnv)
+	}
+
+	if node.Default != nil {
+		return e.evalArmBody(node.Default, env)
+	}
+	return NULL
+}
+
+// evalSwitchExpression evaluates the body of the first case whose value
+// equals the subject, trying them in order. Blocks run in the enclosing scope,
+// as they do for if. It evaluates to null if no case matches and there is no
+// default.
+func (e *Evaluator) evalSwitchExpression(node *ast.SwitchExpression, env *object.Environment) object.Object {
+	subject := e.evalNode(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, c := range node.Cases {
+		value := e.evalNode(c.Value, env)
+		if isError(value) {
+			return value
+		}
+		if object.DeepEqual(subject, value) {
+			return e.evalArmBody(c.Body, env)
+		}
+	}
+
+	if node.Default != nil {
+		return e.evalArmBody(node.Default, env)
+	}
+	return NULL
+}
+
+// evalArmBody evaluates a match arm or switch case body. An empty block
+// evaluates to null rather than nothing, since the arm is used as a value.
+func (e *Evaluator) evalArmBody(body ast.Node, env *object.Environment) object.Object {
+	result := e.evalNode(body, env)
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// matchPattern reports whether `value` matches `pattern`, binding any names in
+// the pattern in `env`
+func (e *Evaluator) matchPattern(pattern ast.Expression, value object.Object, env *object.Environment) (bool, object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			env.Set(pattern.Value, value)
+		}
+		return true, nil
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return e.matchLiteral(pattern, value, env)
+	case *ast.PrefixExpression:
+		// Negative numbers
+		if _, ok := pattern.Right.(*ast.IntegerLiteral); ok && pattern.Operator == "-" {
+			return e.matchLiteral(pattern, value, env)
+		}
+	case *ast.ArrayLiteral:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return false, nil
+		}
+		return e.matchArray(pattern, array, env)
+	case *ast.HashLiteral:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return false, nil
+		}
+		return e.matchHash(pattern, hash, env)
+	}
+
+	return false, newError("invalid pattern: %s", pattern.String())
+}
+
+func (e *Evaluator) matchLiteral(pattern ast.Expression, value object.Object, env *object.Environment) (bool, object.Object) {
+	literal := e.evalNode(pattern, env)
+	if isError(literal) {
+		return false, literal
+	}
+	return object.DeepEqual(literal, value), nil
+}
+
+// matchArray matches each element pattern in order. A final `...rest` matches
+// the remaining elements, otherwise the lengths have to be the same.
+func (e *Evaluator) matchArray(pattern *ast.ArrayLiteral, array *object.Array, env *object.Environment) (bool, object.Object) {
+	patterns := pattern.Elements
+	var rest *ast.SpreadExpression
+
+	for i, p := range patterns {
+		spread, ok := p.(*ast.SpreadExpression)
+		if !ok {
+			continue
+		}
+		if i != len(patterns)-1 {
+			return false, newError("... must be the last element of an array pattern")
+		}
+		rest = spread
+		patterns = patterns[:i]
+	}
+
+	if len(array.Elements) < len(patterns) || (rest == nil && len(array.Elements) != len(patterns)) {
+		return false, nil
+	}
+
+	for i, p := range patterns {
+		matched, err := e.matchPattern(p, array.Elements[i], env)
+		if err != nil || !matched {
+			return matched, err
+		}
+	}
+
+	if rest != nil {
+		remaining := e.track(&object.Array{Elements: append([]object.Object{}, array.Elements[len(patterns):]...)})
+		if isError(remaining) {
+			return false, remaining
+		}
+		return e.matchPattern(rest.Value, remaining, env)
+	}
+	return true, nil
+}
+
+// matchHash matches when the hash has every key in the pattern and the value
+// of each key matches its pattern. Other keys in the hash are ignored.
+func (e *Evaluator) matchHash(pattern *ast.HashLiteral, hash *object.Hash, env *object.Environment) (bool, object.Object) {
+	for _, pair := range pattern.Pairs {
+		key := e.evalNode(pair.Key, env)
+		if isError(key) {
+			return false, key
+		}
+		hashable, ok := key.(object.Hashable)
+		if !ok {
+			return false, newError("unusable as hash key: %s", key.Type())
+		}
+
+		value, ok := hash.Get(hashable)
+		if !ok {
+			return false, nil
+		}
+
+		matched, err := e.matchPattern(pair.Value, value, env)
+		if err != nil || !matched {
+			return matched, err
+		}
+	}
+	return true, nil
+}
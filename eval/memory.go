@@ -0,0 +1,93 @@
+package eval
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// WithMaxMemoryBytes stops a program with an error once the objects it has
+// allocated, and that haven't been garbage collected, add up to more than
+// `limit` bytes. Sizes are rough estimates and objects are only counted as
+// freed once Go's garbage collector has run their finalizers, so this guards
+// against runaway allocation rather than measuring memory exactly.
+func WithMaxMemoryBytes(limit int64) Option {
+	return func(e *Evaluator) {
+		e.memory = &memoryCounter{limit: limit}
+	}
+}
+
+type memoryCounter struct {
+	limit int64
+	used  int64 // Updated atomically, as finalizers run on their own goroutine
+}
+
+// MemoryUsage returns the estimated bytes held by objects the evaluator has
+// allocated. It is always 0 unless the evaluator was created
+// WithMaxMemoryBytes.
+func (e *Evaluator) MemoryUsage() int64 {
+	if e.memory == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&e.memory.used)
+}
+
+// reserve checks that `size` more bytes fit under the memory limit, so callers
+// can refuse a large allocation before making it rather than after. Nothing is
+// counted until the allocated object is passed to track.
+func (e *Evaluator) reserve(size int64) *object.Error {
+	if e.memory == nil {
+		return nil
+	}
+	if size > e.memory.limit-atomic.LoadInt64(&e.memory.used) {
+		return newError("memory limit exceeded")
+	}
+	return nil
+}
+
+// track counts a newly allocated `obj` against the memory limit, returning an
+// error in its place if the limit has been exceeded
+func (e *Evaluator) track(obj object.Object) object.Object {
+	if e.memory == nil {
+		return obj
+	}
+
+	size := objectSize(obj)
+	if size == 0 {
+		return obj
+	}
+
+	used := atomic.AddInt64(&e.memory.used, size)
+	counter := e.memory
+	runtime.SetFinalizer(obj, func(interface{}) {
+		atomic.AddInt64(&counter.used, -size)
+	})
+
+	if used > e.memory.limit {
+		return newError("memory limit exceeded")
+	}
+	return obj
+}
+
+// objectSize estimates the bytes used by `obj`, not counting the objects it
+// refers to as they are tracked separately. Shared objects are never freed,
+// so they count as 0.
+func objectSize(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		if object.NewInteger(obj.Value) == obj {
+			return 0 // From the shared pool
+		}
+		return 8
+	case *object.String:
+		return int64(len(obj.Value))
+	case *object.Array:
+		return 8 * int64(len(obj.Elements))
+	case *object.Hash:
+		return 64 * int64(len(obj.Pairs))
+	case *object.Channel:
+		return chanSlotSize * int64(cap(obj.Ch))
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// Each goroutine runs on its own fork of the evaluator, so the evaluator is
+// used as the owner of a locked mutex.
+
+// mutex() returns a new, unlocked mutex
+func builtinMutex(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+
+	return object.NewMutex()
+}
+
+// lock(m) blocks until `m` is locked by the calling goroutine
+func builtinLock(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	m, ok := args[0].(*object.Mutex)
+	if !ok {
+		return newError("argument to `lock` must be MUTEX, got %s", args[0].Type())
+	}
+
+	if err := e.lock(m); err != nil {
+		return err
+	}
+	return NULL
+}
+
+// unlock(m) unlocks `m`
+func builtinUnlock(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	m, ok := args[0].(*object.Mutex)
+	if !ok {
+		return newError("argument to `unlock` must be MUTEX, got %s", args[0].Type())
+	}
+
+	if !m.Unlock() {
+		return newError("unlock of unlocked mutex")
+	}
+	return NULL
+}
+
+// withLock(m, fn) calls `fn` while holding `m`, unlocking it however `fn`
+// returns
+func builtinWithLock(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	m, ok := args[0].(*object.Mutex)
+	if !ok {
+		return newError("argument to `withLock` must be MUTEX, got %s", args[0].Type())
+	}
+
+	if err := e.lock(m); err != nil {
+		return err
+	}
+	defer m.Unlock()
+
+	return e.applyFunction(args[1], nil)
+}
+
+// lock blocks until `m` is held by the calling goroutine, returning an error
+// if it already is or if evaluation is cancelled while waiting
+func (e *Evaluator) lock(m *object.Mutex) *object.Error {
+	if m.HeldBy(e) {
+		return newError("mutex is already locked by this goroutine")
+	}
+	if !m.Lock(e, e.ctx.Done()) {
+		return e.contextError()
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// Observables are run by subscribe, each subscription in its own goroutines.
+// The functions given to observable, map and filter are called from those
+// goroutines, so each call is made on a fork of the evaluator that created
+// the observable. The fork made at creation is never used itself, so copying
+// it again is safe while other goroutines run.
+
+// observable(producer) returns an observable that calls `producer` with a
+// `next` function for each subscription
+func builtinObservable(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	if !isCallable(args[0]) {
+		return newError("argument to `observable` must be FUNCTION, got %s", args[0].Type())
+	}
+
+	producer, base := args[0], e.fork()
+	return &object.Observable{Run: func(emit func(object.Object) object.Object) object.Object {
+		next := &object.Builtin{
+			Name: "next",
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				if err := emit(args[0]); err != nil {
+					return err
+				}
+				return NULL
+			},
+		}
+		return base.fork().applyFunction(producer, []object.Object{next})
+	}}
+}
+
+// map(obs, fn) returns an observable of `fn(value)` for each value of `obs`
+func builtinMap(e *Evaluator, args ...object.Object) object.Object {
+	return transform(e, "map", args, func(emit func(object.Object) object.Object, value, result object.Object) object.Object {
+		return emit(result)
+	})
+}
+
+// filter(obs, pred) returns an observable of the values of `obs` that `pred`
+// is truthy for
+func builtinFilter(e *Evaluator, args ...object.Object) object.Object {
+	return transform(e, "filter", args, func(emit func(object.Object) object.Object, value, result object.Object) object.Object {
+		if !isTruthy(result) {
+			return nil
+		}
+		return emit(value)
+	})
+}
+
+// transform returns an observable that calls `fn` with each value of an
+// observable, then `then` with the value and result to decide what to emit.
+// An error from `fn` stops the observable.
+func transform(e *Evaluator, name string, args []object.Object, then func(emit func(object.Object) object.Object, value, result object.Object) object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	source, ok := args[0].(*object.Observable)
+	if !ok {
+		return newError("argument to `%s` must be OBSERVABLE, got %s", name, args[0].Type())
+	}
+	if !isCallable(args[1]) {
+		return newError("argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+
+	fn, base := args[1], e.fork()
+	return &object.Observable{Run: func(emit func(object.Object) object.Object) object.Object {
+		forked := base.fork()
+		return source.Run(func(value object.Object) object.Object {
+			result := forked.applyFunction(fn, []object.Object{value})
+			if isError(result) {
+				return result
+			}
+			if result == nil {
+				result = NULL
+			}
+			return then(emit, value, result)
+		})
+	}}
+}
+
+// subscribe(obs, handler) runs `obs` in a new goroutine, sending the values it
+// emits over a channel to another goroutine that calls `handler` with each of
+// them. An error from `handler` ends the subscription.
+func builtinSubscribe(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	obs, ok := args[0].(*object.Observable)
+	if !ok {
+		return newError("argument to `subscribe` must be OBSERVABLE, got %s", args[0].Type())
+	}
+	if !isCallable(args[1]) {
+		return newError("argument to `subscribe` must be FUNCTION, got %s", args[1].Type())
+	}
+
+	sub := object.NewSubscription()
+	values := make(chan object.Object)
+
+	producer := e.fork()
+	go func() {
+		defer close(values)
+		obs.Run(func(value object.Object) object.Object {
+			select {
+			case values <- value:
+				return nil
+			case <-sub.Stopped():
+				return newError("unsubscribed")
+			case <-producer.ctx.Done():
+				return producer.contextError()
+			}
+		})
+	}()
+
+	handler, consumer := args[1], e.fork()
+	go func() {
+		defer sub.Unsubscribe()
+		for value := range values {
+			select {
+			case <-sub.Stopped():
+				return
+			default:
+			}
+			if isError(consumer.applyFunction(handler, []object.Object{value})) {
+				return
+			}
+		}
+	}()
+
+	return sub
+}
+
+// unsubscribe(sub) stops `sub`, so its handler gets no more values and its
+// producer's next calls return an error
+func builtinUnsubscribe(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	sub, ok := args[0].(*object.Subscription)
+	if !ok {
+		return newError("argument to `unsubscribe` must be SUBSCRIPTION, got %s", args[0].Type())
+	}
+
+	sub.Unsubscribe()
+	return NULL
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// WithCallProfile makes the evaluator count the calls made to each function
+// and the time spent in them. Read the results with CallProfile.
+func WithCallProfile() Option {
+	return func(e *Evaluator) {
+		e.calls = &callProfile{stats: map[interface{}]*CallStats{}}
+	}
+}
+
+type CallStats struct {
+	Name     string
+	Calls    int64
+	SelfTime time.Duration // Time spent in the function, minus its callees
+}
+
+type callProfile struct {
+	stats map[interface{}]*CallStats // Keyed by profileKey
+	stack []*profileFrame
+}
+
+type profileFrame struct {
+	start     time.Time
+	childTime time.Duration
+}
+
+// CallProfile returns the stats for every function called, most called first.
+// Nothing is returned unless the evaluator was created WithCallProfile.
+func (e *Evaluator) CallProfile() []CallStats {
+	if e.calls == nil {
+		return nil
+	}
+
+	result := []CallStats{}
+	for _, stats := range e.calls.stats {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Calls != result[j].Calls {
+			return result[i].Calls > result[j].Calls
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// WriteCallProfile writes the CallProfile as a table
+func (e *Evaluator) WriteCallProfile(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "function\tcalls\tself time")
+	for _, stats := range e.CallProfile() {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", stats.Name, stats.Calls, stats.SelfTime)
+	}
+	tw.Flush()
+}
+
+// profileCall wraps `applyFunction`, recording the call against the function
+// being called, whatever name it was called by.
+func (e *Evaluator) profileCall(fn object.Object, args []object.Object) object.Object {
+	key, name := profileKey(fn)
+
+	stats, ok := e.calls.stats[key]
+	if !ok {
+		stats = &CallStats{Name: name}
+		e.calls.stats[key] = stats
+	}
+	stats.Calls++
+
+	frame := &profileFrame{start: time.Now()}
+	e.calls.stack = append(e.calls.stack, frame)
+
+	result := e.applyFunction(fn, args)
+
+	e.calls.stack = e.calls.stack[:len(e.calls.stack)-1]
+	total := time.Since(frame.start)
+	stats.SelfTime += total - frame.childTime
+	if len(e.calls.stack) > 0 {
+		e.calls.stack[len(e.calls.stack)-1].childTime += total
+	}
+
+	return result
+}
+
+// builtinKey is the profileKey of a built-in. Looking a built-in up makes a new
+// *object.Builtin each time, so they are counted by name instead.
+type builtinKey string
+
+// profileKey returns what the calls to `fn` are counted against, and the name
+// to show for it. Functions are keyed by the literal they were created from, so
+// every closure made by one literal shares a row. Anonymous functions are
+// labelled by where the literal is in the source.
+func profileKey(fn object.Object) (interface{}, string) {
+	switch fn := fn.(type) {
+	case *object.Function:
+		literal := fn.Literal
+		if literal == nil {
+			return fn, "fn"
+		}
+		if literal.Name != "" {
+			return literal, literal.Name
+		}
+		return literal, fmt.Sprintf("fn@%d:%d", literal.Token.Line, literal.Token.Col)
+	case *object.Builtin:
+		return builtinKey(fn.Name), fn.Name
+	default:
+		return fn.Type(), string(fn.Type())
+	}
+}
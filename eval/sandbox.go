@@ -0,0 +1,114 @@
+package eval
+
+import (
+	"time"
+
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// Used by WithSandbox unless they are set separately
+const (
+	DefaultSandboxCallDepth   = 1000
+	DefaultSandboxTimeout     = 5 * time.Second
+	DefaultSandboxMemoryBytes = 256 << 20
+)
+
+// Built-ins that reach outside the interpreter, and so are removed in the
+// sandbox. `debug` and `read` are here as they block reading from stdin.
+var unsafeBuiltins = map[string]bool{
+	"debug": true,
+	"read":  true,
+}
+
+// Modules that give access to the host, which `import` refuses in the sandbox
+var unsafeModules = map[string]bool{
+	"io":   true,
+	"os":   true,
+	"http": true,
+}
+
+// WithSandbox makes the evaluator safe to run untrusted code. Built-ins and
+// modules that touch the filesystem, network or terminal are removed, as are
+// ones registered with RegisterBuiltin or RegisterModule, since the sandbox
+// can't tell what they reach, unless WithAllowedBuiltins names them. Calls
+// can only nest DefaultSandboxCallDepth deep, a program is stopped after
+// DefaultSandboxTimeout and it can hold DefaultSandboxMemoryBytes. Use
+// WithMaxCallDepth, WithTimeout and WithMaxMemoryBytes to change the limits.
+func WithSandbox() Option {
+	return func(e *Evaluator) {
+		e.sandbox = true
+		if e.maxCallDepth == 0 {
+			e.maxCallDepth = DefaultSandboxCallDepth
+		}
+		if e.timeout == 0 {
+			e.timeout = DefaultSandboxTimeout
+		}
+		if e.memory == nil {
+			WithMaxMemoryBytes(DefaultSandboxMemoryBytes)(e)
+		}
+	}
+}
+
+// WithAllowedBuiltins limits the built-ins a program can call to `names`.
+// In the sandbox, names of unsafe built-ins are still removed, and registered
+// built-ins and modules are only available if they are named here.
+func WithAllowedBuiltins(names []string) Option {
+	return func(e *Evaluator) {
+		e.allowedBuiltins = map[string]bool{}
+		for _, name := range names {
+			e.allowedBuiltins[name] = true
+		}
+	}
+}
+
+// WithMaxCallDepth limits how deeply function calls can nest
+func WithMaxCallDepth(depth int) Option {
+	return func(e *Evaluator) {
+		e.maxCallDepth = depth
+	}
+}
+
+// WithTimeout stops each call to Eval after `d`
+func WithTimeout(d time.Duration) Option {
+	return func(e *Evaluator) {
+		e.timeout = d
+	}
+}
+
+// builtinAllowed reports whether the program can call the built-in `name`.
+// `registered` is true for built-ins added with RegisterBuiltin.
+func (e *Evaluator) builtinAllowed(name string, registered bool) bool {
+	if e.sandbox && (unsafeBuiltins[name] || (registered && !e.allowedBuiltins[name])) {
+		return false
+	}
+	return e.allowedBuiltins == nil || e.allowedBuiltins[name]
+}
+
+// moduleAllowed reports whether the program can import the module `name`.
+// Every module is registered from outside the interpreter, so the sandbox
+// only allows the ones named with WithAllowedBuiltins.
+func (e *Evaluator) moduleAllowed(name string) bool {
+	if !e.sandbox {
+		return true
+	}
+	return !unsafeModules[name] && e.allowedBuiltins[name]
+}
+
+// unavailableBuiltin stands in for a built-in that has been removed, so the
+// program gets an error when it calls it rather than when it names it
+func unavailableBuiltin(name string) *object.Builtin {
+	return &object.Builtin{
+		Name: name,
+		Fn: func(args ...object.Object) object.Object {
+			return newError("built-in not available in sandbox")
+		},
+	}
+}
+
+// checkLimits returns an error once the call depth or time limits are hit
+func (e *Evaluator) checkLimits() *object.Error {
+	if e.maxCallDepth > 0 && len(e.frames) > e.maxCallDepth {
+		return newError("maximum call depth of %d exceeded", e.maxCallDepth)
+	}
+	return e.contextError()
+}
@@ -0,0 +1,97 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// semaphore(n) returns a new semaphore with `n` slots
+func builtinSemaphore(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	n, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("argument to `semaphore` must be INTEGER, got %s", args[0].Type())
+	}
+	if n.Value <= 0 {
+		return newError("semaphore size must be positive, got %d", n.Value)
+	}
+
+	return &object.Semaphore{Slots: make(chan struct{}, n.Value)}
+}
+
+// acquire(sem) blocks until `sem` has a free slot, then takes it
+func builtinAcquire(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	sem, ok := args[0].(*object.Semaphore)
+	if !ok {
+		return newError("argument to `acquire` must be SEMAPHORE, got %s", args[0].Type())
+	}
+
+	if err := e.acquire(sem); err != nil {
+		return err
+	}
+	return NULL
+}
+
+// release(sem) frees a slot taken by acquire
+func builtinRelease(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	sem, ok := args[0].(*object.Semaphore)
+	if !ok {
+		return newError("argument to `release` must be SEMAPHORE, got %s", args[0].Type())
+	}
+
+	if !release(sem) {
+		return newError("release of unacquired semaphore")
+	}
+	return NULL
+}
+
+// withSemaphore(sem, fn) calls `fn` while holding a slot of `sem`, releasing
+// it however `fn` returns
+func builtinWithSemaphore(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	sem, ok := args[0].(*object.Semaphore)
+	if !ok {
+		return newError("argument to `withSemaphore` must be SEMAPHORE, got %s", args[0].Type())
+	}
+
+	if err := e.acquire(sem); err != nil {
+		return err
+	}
+	defer release(sem)
+
+	return e.applyFunction(args[1], nil)
+}
+
+// acquire waits for a free slot in `sem`, or for the evaluator's context to
+// be done
+func (e *Evaluator) acquire(sem *object.Semaphore) *object.Error {
+	select {
+	case sem.Slots <- struct{}{}:
+		return nil
+	case <-e.ctx.Done():
+		return e.contextError()
+	}
+}
+
+// release frees a slot, returning false if none were taken
+func release(sem *object.Semaphore) bool {
+	select {
+	case <-sem.Slots:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !(js && wasm)
+
+package eval
+
+import (
+	"io"
+	"os"
+)
+
+func defaultStdio() (io.Reader, io.Writer, io.Writer) {
+	return os.Stdin, os.Stdout, os.Stderr
+}
@@ -0,0 +1,60 @@
+//go:build js && wasm
+
+package eval
+
+import (
+	"bytes"
+	"io"
+	"syscall/js"
+)
+
+// There is no stdin or stdout in the browser. Output goes to the JavaScript
+// console a line at a time, with stderr logged as errors, and input is whatever the page passes to the
+// global `monkeyInput(text)` function.
+var stdin = &jsReader{lines: make(chan string, 16)}
+
+func init() {
+	js.Global().Set("monkeyInput", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) > 0 {
+			stdin.lines <- args[0].String()
+		}
+		return nil
+	}))
+}
+
+func defaultStdio() (io.Reader, io.Writer, io.Writer) {
+	return stdin, &consoleWriter{method: "log"}, &consoleWriter{method: "error"}
+}
+
+type consoleWriter struct {
+	method string // The console method each line is passed to
+	buf    []byte
+}
+
+func (w *consoleWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		js.Global().Get("console").Call(w.method, string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// jsReader blocks until the page sends some input
+type jsReader struct {
+	lines   chan string
+	pending []byte
+}
+
+func (r *jsReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		r.pending = []byte(<-r.lines)
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,59 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// contains(s, sub) reports whether `sub` is in `s`
+func builtinContains(e *Evaluator, args ...object.Object) object.Object {
+	return stringPredicate("contains", strings.Contains, args)
+}
+
+// startsWith(s, prefix) reports whether `s` begins with `prefix`
+func builtinStartsWith(e *Evaluator, args ...object.Object) object.Object {
+	return stringPredicate("startsWith", strings.HasPrefix, args)
+}
+
+// endsWith(s, suffix) reports whether `s` ends with `suffix`
+func builtinEndsWith(e *Evaluator, args ...object.Object) object.Object {
+	return stringPredicate("endsWith", strings.HasSuffix, args)
+}
+
+// indexOf(s, sub) returns the byte offset of the first `sub` in `s`, or -1 if
+// there isn't one
+func builtinIndexOf(e *Evaluator, args ...object.Object) object.Object {
+	s, sub, err := stringPair("indexOf", args)
+	if err != nil {
+		return err
+	}
+
+	return object.NewInteger(int64(strings.Index(s, sub)))
+}
+
+func stringPredicate(name string, fn func(s, t string) bool, args []object.Object) object.Object {
+	s, t, err := stringPair(name, args)
+	if err != nil {
+		return err
+	}
+
+	return nativeBoolToBooleanObject(fn(s, t))
+}
+
+// stringPair checks the built-in `name` was called with two strings
+func stringPair(name string, args []object.Object) (string, string, *object.Error) {
+	if len(args) != 2 {
+		return "", "", newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	values := [2]string{}
+	for i, arg := range args {
+		s, ok := arg.(*object.String)
+		if !ok {
+			return "", "", newError("arguments to `%s` must be STRING, got %s", name, arg.Type())
+		}
+		values[i] = s.Value
+	}
+	return values[0], values[1], nil
+}
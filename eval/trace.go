@@ -0,0 +1,29 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// traceEval wraps `eval`, printing the node before it is evaluated and the
+// resulting object afterwards. Output is indented by the depth of the call
+// stack, so the nodes evaluated inside a function call stand out from the
+// call itself.
+func (e *Evaluator) traceEval(node ast.Node, env *object.Environment) object.Object {
+	depth := len(e.frames)
+	indent := strings.Repeat("  ", depth)
+	fmt.Fprintf(e.trace, "%s[%d] %T: %s\n", indent, depth, node, node.String())
+
+	result := e.eval(node, env)
+
+	if result != nil {
+		fmt.Fprintf(e.trace, "%s[%d] => %s\n", indent, depth, result.Inspect())
+	} else {
+		fmt.Fprintf(e.trace, "%s[%d] => <nil>\n", indent, depth)
+	}
+
+	return result
+}
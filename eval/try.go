@@ -0,0 +1,25 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// evalTryCatchStatement evaluates the body, then the catch with the error bound
+// to its name if the body failed, then the finally. An error or return in the
+// finally replaces whatever the body or catch gave, as in Java.
+func (e *Evaluator) evalTryCatchStatement(node *ast.TryCatchStatement, env *object.Environment) object.Object {
+	result := e.evalNode(node.Body, env)
+
+	if errObj, ok := result.(*object.Error); ok && node.Catch != nil {
+		env.Set(node.CatchName.Value, &object.ErrorValue{Error: errObj})
+		result = e.evalNode(node.Catch, env)
+	}
+
+	if node.Finally != nil {
+		if finally := e.evalNode(node.Finally, env); isSignal(finally) {
+			return finally
+		}
+	}
+	return result
+}
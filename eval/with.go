@@ -0,0 +1,41 @@
+package eval
+
+import (
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// evalWithStatement binds the value of the init expression to the statement's
+// name and evaluates the body, then calls the value's `__close__` function, if
+// it has one, however the body finished. An error from the body wins over one
+// from `__close__`.
+func (e *Evaluator) evalWithStatement(node *ast.WithStatement, env *object.Environment) object.Object {
+	resource := e.evalNode(node.Init, env)
+	if isError(resource) {
+		return resource
+	}
+	env.Set(node.Name.Value, resource)
+
+	result := e.evalNode(node.Body, env)
+
+	if closeFn, ok := closeMethod(resource); ok {
+		closed := e.applyFunction(closeFn, nil)
+		if isError(closed) && !isError(result) {
+			return closed
+		}
+	}
+	return result
+}
+
+// closeMethod returns the `__close__` function of a hash or module
+func closeMethod(obj object.Object) (object.Object, bool) {
+	switch obj := obj.(type) {
+	case *object.Hash:
+		fn, ok := obj.Get(object.InternString("__close__"))
+		return fn, ok
+	case *object.Module:
+		fn, ok := obj.Bindings["__close__"]
+		return fn, ok
+	}
+	return nil, false
+}
@@ -0,0 +1,475 @@
+// Package format prints Monkey programs in a canonical style: one statement
+// per line, blocks indented by four spaces, and only the parentheses needed
+// to keep the meaning of an expression.
+package format
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/parser"
+	"github.com/vishen/go-monkeylang/token"
+)
+
+const indentation = "    "
+
+// Source parses and formats a program. Parse errors are returned together,
+// separated by "; ".
+func Source(src string) (string, error) {
+	program, errs := parser.ParseString(src)
+	if len(errs) > 0 {
+		return "", errors.New(strings.Join(errs, "; "))
+	}
+	return Node(program), nil
+}
+
+// Node returns the formatted source of `node`. A program ends in a newline,
+// any other node doesn't.
+func Node(node ast.Node) string {
+	p := &printer{}
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			p.statement(stmt)
+			p.buf.WriteString("\n")
+		}
+	case ast.Statement:
+		p.statement(node)
+	case ast.Expression:
+		p.expression(node, lowest)
+	}
+	return p.buf.String()
+}
+
+// DocLines returns the `##` comment lines that document a let with `doc`
+func DocLines(doc string) []string {
+	if doc == "" {
+		return nil
+	}
+	lines := strings.Split(doc, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("## "+line, " ")
+	}
+	return lines
+}
+
+// The parser's precedences, which the printer brackets expressions by
+const (
+	lowest    = parser.LOWEST
+	ternary   = parser.TERNARY
+	rangePrec = parser.RANGE
+	prefix    = parser.PREFIX
+	postfix   = parser.INDEX // Calls, indexes and member access
+)
+
+func precedence(exp ast.Expression) parser.Precedence {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.PrecedenceOf(exp.Token)
+	case *ast.PrefixExpression:
+		return prefix
+	case *ast.TernaryExpression:
+		return ternary
+	case *ast.RangeLiteral:
+		return rangePrec
+	case *ast.LetInExpression, *ast.TryExpression:
+		// The body runs to the end of the expression
+		return lowest
+	}
+	return postfix
+}
+
+type printer struct {
+	buf    bytes.Buffer
+	indent int
+
+	// Set while printing the value of a let, where an `in` operator has to
+	// be in parentheses so it isn't read as a let-in expression
+	noIn bool
+}
+
+// allowIn clears noIn inside brackets, returning a func that restores it
+func (p *printer) allowIn() func() {
+	noIn := p.noIn
+	p.noIn = false
+	return func() { p.noIn = noIn }
+}
+
+func (p *printer) letValue(exp ast.Expression) {
+	noIn := p.noIn
+	p.noIn = true
+	p.expression(exp, lowest)
+	p.noIn = noIn
+}
+
+func (p *printer) write(s ...string) {
+	for _, s := range s {
+		p.buf.WriteString(s)
+	}
+}
+
+func (p *printer) newline() {
+	p.buf.WriteString("\n")
+	p.buf.WriteString(strings.Repeat(indentation, p.indent))
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		for _, line := range DocLines(stmt.Doc) {
+			p.write(line)
+			p.newline()
+		}
+		if stmt.Token.Type == token.FUNCTION {
+			p.expression(stmt.Value, lowest)
+			return
+		}
+		p.write("let ", stmt.Name.Value, " = ")
+		if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && fn.Name == stmt.Name.Value {
+			// The let already names the function
+			p.function(fn, false)
+		} else {
+			p.letValue(stmt.Value)
+		}
+		p.write(";")
+	case *ast.DestructuringStatement:
+		p.write("let ")
+		p.pattern(stmt.Pattern)
+		p.write(" = ")
+		p.expression(stmt.Value, lowest)
+		p.write(";")
+	case *ast.ReturnStatement:
+		p.write("return")
+		if stmt.ReturnValue != nil {
+			p.write(" ")
+			p.expression(stmt.ReturnValue, lowest)
+		}
+		p.write(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression, lowest)
+		// An if or match reads like a statement, so doesn't get a semicolon
+		switch stmt.Expression.(type) {
+		case *ast.IfExpression, *ast.MatchExpression, *ast.SwitchExpression:
+		default:
+			p.write(";")
+		}
+	case *ast.GoStatement:
+		p.write("go ")
+		p.expression(stmt.Call, lowest)
+		p.write(";")
+	case *ast.TypeAliasStatement:
+		p.write("type ", stmt.Name.Value, " = ")
+		p.expression(stmt.Type, lowest)
+		p.write(";")
+	case *ast.WithStatement:
+		p.write("with ")
+		p.expression(stmt.Init, lowest)
+		p.write(" as ", stmt.Name.Value, " ")
+		p.block(stmt.Body)
+	case *ast.TryCatchStatement:
+		p.write("try ")
+		p.block(stmt.Body)
+		if stmt.Catch != nil {
+			p.write(" catch (", stmt.CatchName.Value, ") ")
+			p.block(stmt.Catch)
+		}
+		if stmt.Finally != nil {
+			p.write(" finally ")
+			p.block(stmt.Finally)
+		}
+	case *ast.ForStatement:
+		p.write("for (", stmt.Variable.Value, " in ")
+		p.expression(stmt.Iterable, lowest)
+		p.write(") ")
+		p.block(stmt.Body)
+	case *ast.SelectStatement:
+		p.selectStatement(stmt)
+	case *ast.BlockStatement:
+		p.block(stmt)
+	default:
+		p.write(stmt.String())
+	}
+}
+
+func (p *printer) switchBody(body ast.Node) {
+	if block, ok := body.(*ast.BlockStatement); ok {
+		p.block(block)
+		return
+	}
+	p.expression(body.(ast.Expression), lowest)
+}
+
+func (p *printer) block(block *ast.BlockStatement) {
+	defer p.allowIn()()
+
+	if len(block.Statements) == 0 {
+		p.write("{}")
+		return
+	}
+
+	p.write("{")
+	p.indent++
+	for _, stmt := range block.Statements {
+		p.newline()
+		p.statement(stmt)
+	}
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+func (p *printer) selectStatement(stmt *ast.SelectStatement) {
+	p.write("select {")
+	p.indent++
+	for _, c := range stmt.Cases {
+		p.newline()
+		p.write("case (")
+		if c.Var != nil {
+			p.write(c.Var.Value, " in ")
+		}
+		p.expression(c.Chan, lowest)
+		p.write(") ")
+		p.block(c.Body)
+	}
+	if stmt.Default != nil {
+		p.newline()
+		p.write("default ")
+		p.block(stmt.Default)
+	}
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+// expression writes `exp`, in parentheses if it binds less tightly than `prec`
+func (p *printer) expression(exp ast.Expression, prec parser.Precedence) {
+	infix, isInfix := exp.(*ast.InfixExpression)
+	if precedence(exp) < prec || (p.noIn && isInfix && infix.Operator == "in") {
+		defer p.allowIn()()
+		p.write("(")
+		defer p.write(")")
+	}
+
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.write(exp.Value)
+	case *ast.IntegerLiteral, *ast.Boolean:
+		p.write(exp.TokenLiteral())
+	case *ast.StringLiteral:
+		p.write(`"`, exp.Value, `"`)
+	case *ast.LetInExpression:
+		p.write("let ", exp.Name.Value, " = ")
+		p.letValue(exp.Value)
+		p.write(" in ")
+		p.expression(exp.Body, lowest)
+	case *ast.TryExpression:
+		p.write("try ")
+		p.expression(exp.Body, lowest)
+	case *ast.TernaryExpression:
+		p.expression(exp.Condition, ternary+1)
+		p.write(" ? ")
+		restore := p.allowIn()
+		p.expression(exp.Consequence, lowest)
+		restore()
+		p.write(" : ")
+		p.expression(exp.Alternative, ternary)
+	case *ast.RangeLiteral:
+		operator := "..."
+		if exp.Inclusive {
+			operator = ".."
+		}
+		p.expression(exp.Low, rangePrec)
+		p.write(operator)
+		p.expression(exp.High, rangePrec+1)
+	case *ast.PrefixExpression:
+		if exp.Token.Type == token.NOT {
+			p.write("not ")
+		} else {
+			p.write(exp.Operator)
+		}
+		p.expression(exp.Right, prefix)
+	case *ast.InfixExpression:
+		// Operators are left associative, so a right operand of the same
+		// precedence needs parentheses
+		prec := parser.PrecedenceOf(exp.Token)
+		p.expression(exp.Left, prec)
+		operator := exp.Operator
+		if exp.Token.Is(token.AND, token.OR) {
+			// Keep `and` and `or` as they were written
+			operator = exp.Token.Literal
+		}
+		p.write(" ", operator, " ")
+		p.expression(exp.Right, prec+1)
+	case *ast.IfExpression:
+		defer p.allowIn()()
+		p.write("if (")
+		p.expression(exp.Condition, lowest)
+		p.write(") ")
+		p.block(exp.Consequence)
+		if exp.Alternative != nil {
+			p.write(" else ")
+			p.block(exp.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.function(exp, true)
+	case *ast.CallExpression:
+		p.expression(exp.Function, postfix)
+		p.write("(")
+		defer p.allowIn()()
+		p.expressions(exp.Arguments)
+		p.write(")")
+	case *ast.ArrayLiteral:
+		defer p.allowIn()()
+		p.write("[")
+		p.expressions(exp.Elements)
+		p.write("]")
+	case *ast.IndexExpression:
+		p.expression(exp.Left, postfix)
+		p.write("[")
+		defer p.allowIn()()
+		p.expression(exp.Index, lowest)
+		p.write("]")
+	case *ast.MatchExpression:
+		defer p.allowIn()()
+		p.write("match ")
+		p.expression(exp.Subject, lowest)
+		p.write(" {")
+		p.indent++
+		for _, arm := range exp.Arms {
+			p.newline()
+			p.write("case ")
+			p.expression(arm.Pattern, lowest)
+			if arm.Guard != nil {
+				p.write(" if ")
+				p.expression(arm.Guard, lowest)
+			}
+			p.write(": ")
+			p.switchBody(arm.Body)
+			p.write(",")
+		}
+		if exp.Default != nil {
+			p.newline()
+			p.write("default: ")
+			p.switchBody(exp.Default)
+			p.write(",")
+		}
+		p.indent--
+		p.newline()
+		p.write("}")
+	case *ast.SwitchExpression:
+		defer p.allowIn()()
+		p.write("switch ")
+		p.expression(exp.Subject, lowest)
+		p.write(" {")
+		p.indent++
+		for _, c := range exp.Cases {
+			p.newline()
+			p.write("case ")
+			p.expression(c.Value, lowest)
+			p.write(": ")
+			p.switchBody(c.Body)
+			p.write(",")
+		}
+		if exp.Default != nil {
+			p.newline()
+			p.write("default: ")
+			p.switchBody(exp.Default)
+			p.write(",")
+		}
+		p.indent--
+		p.newline()
+		p.write("}")
+	case *ast.SpreadExpression:
+		p.write("...")
+		p.expression(exp.Value, prefix)
+	case *ast.MemberExpression:
+		p.expression(exp.Object, postfix)
+		p.write(".", exp.Property.Value)
+	case *ast.HashLiteral:
+		defer p.allowIn()()
+		p.write("{")
+		for i, pair := range exp.Pairs {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expression(pair.Key, lowest)
+			p.write(": ")
+			p.expression(pair.Value, lowest)
+		}
+		p.write("}")
+	default:
+		p.write(exp.String())
+	}
+}
+
+// pattern prints a destructuring pattern. Hash keys are written as names
+// where they can be, and `{x: x}` as `{x}`.
+func (p *printer) pattern(pattern ast.Expression) {
+	switch pattern := pattern.(type) {
+	case *ast.ArrayLiteral:
+		p.write("[")
+		for i, el := range pattern.Elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.pattern(el)
+		}
+		p.write("]")
+	case *ast.HashLiteral:
+		p.write("{")
+		for i, pair := range pattern.Pairs {
+			if i > 0 {
+				p.write(", ")
+			}
+			key := pair.Key.(*ast.StringLiteral)
+			if key.Token.Type != token.IDENT {
+				p.write(`"`, key.Value, `": `)
+				p.pattern(pair.Value)
+				continue
+			}
+
+			target := pair.Value
+			if dp, ok := target.(*ast.DefaultPattern); ok {
+				target = dp.Target
+			}
+			if ident, ok := target.(*ast.Identifier); !ok || ident.Value != key.Value {
+				p.write(key.Value, ": ")
+			}
+			p.pattern(pair.Value)
+		}
+		p.write("}")
+	case *ast.DefaultPattern:
+		p.pattern(pattern.Target)
+		p.write(" = ")
+		p.expression(pattern.Default, lowest)
+	default:
+		p.expression(pattern, lowest)
+	}
+}
+
+func (p *printer) function(fn *ast.FunctionLiteral, withName bool) {
+	p.write("fn")
+	if withName && fn.Name != "" {
+		p.write(" ", fn.Name)
+	}
+	p.write("(")
+	for i, param := range fn.Parameters {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(param.Value)
+	}
+	p.write(") ")
+	p.block(fn.Body)
+}
+
+func (p *printer) expressions(exps []ast.Expression) {
+	for i, exp := range exps {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(exp, lowest)
+	}
+}
@@ -0,0 +1,86 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=5", "let x = 5;\n"},
+		{"1 + 2 * 3; (1 + 2) * 3; 1 - (2 - 3); (1 - 2) - 3", "1 + 2 * 3;\n(1 + 2) * 3;\n1 - (2 - 3);\n1 - 2 - 3;\n"},
+		{"-(1 + 2); !true; -a[0]; (-a)[0]", "-(1 + 2);\n!true;\n-a[0];\n(-a)[0];\n"},
+		{"a == (b < c); (a == b) < c", "a == b < c;\n(a == b) < c;\n"},
+		{`puts("hi", [1,2], {"a": 1}, m.x)`, "puts(\"hi\", [1, 2], {\"a\": 1}, m.x);\n"},
+		{"let add = fn(a, b) { return a + b; }", "let add = fn(a, b) {\n    return a + b;\n};\n"},
+		{"fn() {}()", "fn() {}();\n"},
+		{
+			"match x { case [h, ...t] if h>0: h, default: 0 }",
+			"match x {\n    case [h, ...t] if h > 0: h,\n    default: 0,\n}\n",
+		},
+		{"let x = 5 in x*x; (let x = 1 in x) + 1", "let x = 5 in x * x;\n(let x = 1 in x) + 1;\n"},
+		{"let a = (x in xs); let b = f(x in xs); x not in xs; not x", "let a = (x in xs);\nlet b = f(x in xs);\nx not in xs;\nnot x;\n"},
+		{"for (i in 0 .. n+1) { xs[i...(a..b)] }", "for (i in 0..n + 1) {\n    xs[i...(a..b)];\n}\n"},
+		{"a||b&&c; (a or b) and c", "a || b && c;\n(a or b) and c;\n"},
+		{"a ? b : c ? d : e; (a ? b : c) ? d : e; let x = a ? (y in ys) : 1", "a ? b : c ? d : e;\n(a ? b : c) ? d : e;\nlet x = a ? y in ys : 1;\n"},
+		{"fn add(a, b) { a + b }; add(1, 2)", "fn add(a, b) {\n    a + b;\n}\nadd(1, 2);\n"},
+		{
+			"if (x > 1) { if (y) { 1 } } else { 2 }",
+			"if (x > 1) {\n    if (y) {\n        1;\n    }\n} else {\n    2;\n}\n",
+		},
+		{"go f(1); for (x in xs) { puts(x) }", "go f(1);\nfor (x in xs) {\n    puts(x);\n}\n"},
+		{
+			"select { case (x in a) { x } case (b) {} default { 0 } }",
+			"select {\n    case (x in a) {\n        x;\n    }\n    case (b) {}\n    default {\n        0;\n    }\n}\n",
+		},
+		{"##Adds one.\n##\n## Really.\nlet inc = fn(x) { x + 1 }", "## Adds one.\n##\n## Really.\nlet inc = fn(x) {\n    x + 1;\n};\n"},
+		{
+			"let s = switch x { case 1: \"one\", case 2: { puts(2); \"two\" }, default: {} }",
+			"let s = switch x {\n    case 1: \"one\",\n    case 2: {\n        puts(2);\n        \"two\";\n    },\n    default: {},\n};\n",
+		},
+		{"let r = try f(x)+1; (try a) + 1", "let r = try f(x) + 1;\n(try a) + 1;\n"},
+		{"with open(\"f\") as fh { puts(fh) }", "with open(\"f\") as fh {\n    puts(fh);\n}\n"},
+		{
+			"try { f() } catch (err) { puts(err) } finally { done() } try { 1 } finally {}",
+			"try {\n    f();\n} catch (err) {\n    puts(err);\n} finally {\n    done();\n}\ntry {\n    1;\n} finally {}\n",
+		},
+		{"let [x=0,[y,z]]=point", "let [x = 0, [y, z]] = point;\n"},
+		{`let {x:px,y:y,"a b":[c],z=1}=point`, "let {x: px, y, \"a b\": [c], z = 1} = point;\n"},
+		{"type Pair=[Int,Int]", "type Pair = [Int, Int];\n"},
+		{"fn f() {\n## Doubled.\nlet y = 2; y }", "fn f() {\n    ## Doubled.\n    let y = 2;\n    y;\n}\n"},
+	}
+
+	for _, tt := range tests {
+		formatted, err := Source(tt.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", tt.input, err)
+			continue
+		}
+		if formatted != tt.expected {
+			t.Errorf("wrong format for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, formatted)
+		}
+
+		// Formatting is idempotent and keeps the meaning of the program
+		again, err := Source(formatted)
+		if err != nil || again != formatted {
+			t.Errorf("formatting %q again changed it. got=%q, err=%v", formatted, again, err)
+		}
+		original := parser.NewParser(lexer.NewLexer(tt.input)).ParseProgram()
+		reparsed := parser.NewParser(lexer.NewLexer(formatted)).ParseProgram()
+		if original.String() != reparsed.String() {
+			t.Errorf("formatting changed the program. expected=%q, got=%q", original.String(), reparsed.String())
+		}
+	}
+}
+
+func TestSourceParseErrors(t *testing.T) {
+	_, err := Source("let = 1")
+	if err == nil || err.Error() != "expected next token to be 'IDENT', got '=' instead; no prefix parse function for = found" {
+		t.Errorf("expected a parse error. got=%v", err)
+	}
+}
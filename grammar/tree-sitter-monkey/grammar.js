@@ -0,0 +1,379 @@
+/**
+ * Tree-sitter grammar for Monkey.
+ *
+ * This follows the Go parser in parser/parser.go, which is the canonical
+ * grammar. Precedences match the ones in `precedences` there, so a change to
+ * the language needs to be made in both places.
+ */
+
+const PREC = {
+  ternary: 1,     // x ? y : z
+  range: 2,       // .. ...
+  or: 3,          // || or
+  and: 4,         // && and
+  equals: 5,      // == !=
+  lessgreater: 6, // < >
+  membership: 7,  // in, not in
+  sum: 8,         // + -
+  product: 9,     // * /
+  prefix: 10,     // -x !x
+  call: 11,       // f(x)
+  index: 12,      // a[i] a.b
+};
+
+module.exports = grammar({
+  name: 'monkey',
+
+  extras: $ => [/\s/, $.comment, $.doc_comment],
+
+  externals: $ => [],
+
+  word: $ => $.identifier,
+
+  rules: {
+    source_file: $ => repeat($._statement),
+
+    _statement: $ => choice(
+      $.let_statement,
+      $.destructuring_statement,
+      $.function_declaration,
+      $.return_statement,
+      $.go_statement,
+      $.type_alias,
+      $.with_statement,
+      $.try_statement,
+      $.for_statement,
+      $.select_statement,
+      $.expression_statement,
+    ),
+
+    let_statement: $ => seq(
+      'let',
+      field('name', $.identifier),
+      '=',
+      field('value', $._expression),
+      optional(';'),
+    ),
+
+    destructuring_statement: $ => seq(
+      'let',
+      field('pattern', choice($.array_pattern, $.hash_pattern)),
+      '=',
+      field('value', $._expression),
+      optional(';'),
+    ),
+
+    _pattern: $ => choice($.identifier, $.array_pattern, $.hash_pattern),
+
+    array_pattern: $ => seq(
+      '[',
+      commaSep(choice($._pattern, $.default_pattern)),
+      optional(','),
+      ']',
+    ),
+
+    hash_pattern: $ => seq(
+      '{',
+      commaSep(choice($.pair_pattern, $.identifier, $.default_pattern)),
+      optional(','),
+      '}',
+    ),
+
+    pair_pattern: $ => seq(
+      field('key', choice($.identifier, $.string)),
+      ':',
+      field('value', choice($._pattern, $.default_pattern)),
+    ),
+
+    default_pattern: $ => seq(
+      field('target', $._pattern),
+      '=',
+      field('default', $._expression),
+    ),
+
+    function_declaration: $ => prec(1, seq(
+      'fn',
+      field('name', $.identifier),
+      field('parameters', $.parameters),
+      field('body', $.block),
+      optional(';'),
+    )),
+
+    return_statement: $ => seq(
+      'return',
+      field('value', $._expression),
+      optional(';'),
+    ),
+
+    go_statement: $ => seq(
+      'go',
+      field('call', $.call_expression),
+      optional(';'),
+    ),
+
+    type_alias: $ => seq(
+      'type',
+      field('name', $.identifier),
+      '=',
+      field('type', $._expression),
+      optional(';'),
+    ),
+
+    with_statement: $ => seq(
+      'with',
+      field('init', $._expression),
+      'as',
+      field('name', $.identifier),
+      field('body', $.block),
+      optional(';'),
+    ),
+
+    // Takes precedence over a try_expression of a hash, as in the parser
+    try_statement: $ => prec(1, seq(
+      'try',
+      field('body', $.block),
+      choice(
+        seq($.catch_clause, optional($.finally_clause)),
+        $.finally_clause,
+      ),
+      optional(';'),
+    )),
+
+    catch_clause: $ => seq(
+      'catch',
+      '(',
+      field('name', $.identifier),
+      ')',
+      field('body', $.block),
+    ),
+
+    finally_clause: $ => seq('finally', field('body', $.block)),
+
+    for_statement: $ => seq(
+      'for',
+      '(',
+      field('variable', $.identifier),
+      'in',
+      field('iterable', $._expression),
+      ')',
+      field('body', $.block),
+      optional(';'),
+    ),
+
+    select_statement: $ => seq(
+      'select',
+      '{',
+      repeat($.select_case),
+      optional($.select_default),
+      '}',
+      optional(';'),
+    ),
+
+    select_case: $ => seq(
+      'case',
+      '(',
+      optional(seq(field('variable', $.identifier), 'in')),
+      field('channel', $._expression),
+      ')',
+      field('body', $.block),
+    ),
+
+    select_default: $ => seq('default', field('body', $.block)),
+
+    expression_statement: $ => seq($._expression, optional(';')),
+
+    block: $ => seq('{', repeat($._statement), '}'),
+
+    _expression: $ => choice(
+      $.identifier,
+      $.integer,
+      $.string,
+      $.boolean,
+      $.prefix_expression,
+      $.binary_expression,
+      $.range,
+      $.ternary_expression,
+      $.parenthesized_expression,
+      $.if_expression,
+      $.function_literal,
+      $.call_expression,
+      $.array,
+      $.hash,
+      $.index_expression,
+      $.member_expression,
+      $.let_in_expression,
+      $.try_expression,
+      $.match_expression,
+      $.switch_expression,
+      $.spread,
+    ),
+
+    match_expression: $ => seq(
+      'match',
+      field('subject', $._expression),
+      '{',
+      commaSep(choice($.match_arm, $.match_default)),
+      optional(','),
+      '}',
+    ),
+
+    match_arm: $ => seq(
+      'case',
+      field('pattern', $._expression),
+      optional(seq('if', field('guard', $._expression))),
+      ':',
+      field('body', $._expression),
+    ),
+
+    match_default: $ => seq('default', ':', field('body', $._expression)),
+
+    switch_expression: $ => seq(
+      'switch',
+      field('subject', $._expression),
+      '{',
+      commaSep(choice($.switch_case, $.switch_default)),
+      optional(','),
+      '}',
+    ),
+
+    switch_case: $ => seq(
+      'case',
+      field('value', $._expression),
+      ':',
+      field('body', $._switch_body),
+    ),
+
+    switch_default: $ => seq('default', ':', field('body', $._switch_body)),
+
+    // A '{' after the ':' is always a block, as in parseSwitchBody
+    _switch_body: $ => choice(prec(1, $.block), $._expression),
+
+    spread: $ => prec(PREC.prefix, seq('...', field('value', $._expression))),
+
+    // The body extends as far as it can, so it has the lowest precedence
+    let_in_expression: $ => prec.right(seq(
+      'let',
+      field('name', $.identifier),
+      '=',
+      field('value', $._expression),
+      'in',
+      field('body', $._expression),
+    )),
+
+    // Like a let-in, the body extends as far as it can
+    try_expression: $ => prec.right(seq('try', field('body', $._expression))),
+
+    prefix_expression: $ => prec(PREC.prefix, seq(
+      field('operator', choice('!', '-', 'not')),
+      field('operand', $._expression),
+    )),
+
+    binary_expression: $ => {
+      const table = [
+        [PREC.or, choice('||', 'or')],
+        [PREC.and, choice('&&', 'and')],
+        [PREC.equals, choice('==', '!=')],
+        [PREC.lessgreater, choice('<', '>')],
+        [PREC.membership, choice('in', seq('not', 'in'))],
+        [PREC.sum, choice('+', '-')],
+        [PREC.product, choice('*', '/')],
+      ];
+
+      return choice(...table.map(([precedence, operator]) => prec.left(precedence, seq(
+        field('left', $._expression),
+        field('operator', operator),
+        field('right', $._expression),
+      ))));
+    },
+
+    ternary_expression: $ => prec.right(PREC.ternary, seq(
+      field('condition', $._expression),
+      '?',
+      field('consequence', $._expression),
+      ':',
+      field('alternative', $._expression),
+    )),
+
+    range: $ => prec.left(PREC.range, seq(
+      field('low', $._expression),
+      field('operator', choice('..', '...')),
+      field('high', $._expression),
+    )),
+
+    parenthesized_expression: $ => seq('(', $._expression, ')'),
+
+    if_expression: $ => seq(
+      'if',
+      '(',
+      field('condition', $._expression),
+      ')',
+      field('consequence', $.block),
+      optional(seq('else', field('alternative', $.block))),
+    ),
+
+    function_literal: $ => seq(
+      'fn',
+      optional(field('name', $.identifier)),
+      field('parameters', $.parameters),
+      field('body', $.block),
+    ),
+
+    parameters: $ => seq('(', commaSep($.identifier), ')'),
+
+    call_expression: $ => prec(PREC.call, seq(
+      field('function', $._expression),
+      field('arguments', $.arguments),
+    )),
+
+    arguments: $ => seq('(', commaSep($._expression), ')'),
+
+    index_expression: $ => prec(PREC.index, seq(
+      field('left', $._expression),
+      '[',
+      field('index', $._expression),
+      ']',
+    )),
+
+    member_expression: $ => prec(PREC.index, seq(
+      field('object', $._expression),
+      '.',
+      field('property', $.identifier),
+    )),
+
+    array: $ => seq('[', commaSep($._expression), ']'),
+
+    hash: $ => seq('{', commaSep($.pair), '}'),
+
+    pair: $ => seq(
+      field('key', $._expression),
+      ':',
+      field('value', $._expression),
+    ),
+
+    identifier: _ => /[a-zA-Z_]+/,
+
+    integer: _ => /[0-9]+/,
+
+    // Strings have no escape sequences, they run to the next '"'
+    string: _ => seq('"', optional(alias(/[^"]+/, 'string_content')), '"'),
+
+    boolean: _ => choice('true', 'false'),
+
+    // `#` or `//` to the end of the line, but not `##`, or `/* */`. The
+    // interpreter lets block comments nest, which would need an external
+    // scanner here, so a nested one ends at its first `*/`.
+    comment: _ => token(choice(
+      seq('#', optional(/[^#\n].*/)),
+      seq('//', /.*/),
+      seq('/*', /[^*]*\*+([^/*][^*]*\*+)*/, '/'),
+    )),
+
+    // `## text` documents the let after it. It can appear anywhere, but the
+    // interpreter only keeps the ones directly before a let.
+    doc_comment: _ => token(seq('##', /.*/)),
+  },
+});
+
+function commaSep(rule) {
+  return optional(seq(rule, repeat(seq(',', rule))));
+}
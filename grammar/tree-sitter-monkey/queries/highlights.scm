@@ -0,0 +1,137 @@
+; Keywords
+
+[
+  "let"
+  "return"
+  "if"
+  "else"
+  "go"
+  "type"
+  "with"
+  "as"
+] @keyword
+
+[
+  "try"
+  "catch"
+  "finally"
+] @keyword.exception
+
+[
+  "select"
+  "match"
+  "switch"
+  "case"
+  "default"
+] @keyword.conditional
+
+[
+  "for"
+  "in"
+] @keyword.repeat
+
+"fn" @keyword.function
+
+[
+  "not"
+  "and"
+  "or"
+] @keyword.operator
+
+; Functions
+
+(let_statement
+  name: (identifier) @function
+  value: (function_literal))
+
+(function_declaration
+  name: (identifier) @function)
+
+(call_expression
+  function: (identifier) @function.call)
+
+(call_expression
+  function: (member_expression
+    property: (identifier) @function.method.call))
+
+(parameters (identifier) @variable.parameter)
+
+(member_expression
+  property: (identifier) @property)
+
+; Types
+
+(type_alias
+  name: (identifier) @type.definition)
+
+; Builtins, matching the names in eval/builtins.go
+
+((identifier) @function.builtin
+  (#any-of? @function.builtin
+    "puts" "printErr" "read" "import" "debug" "clone" "freeze" "isFrozen" "weakRef" "deref" "stackTrace" "__line__" "__col__" "source" "repeat" "hash" "comparable"
+    "flatten" "chunk" "take" "drop" "takeWhile" "dropWhile" "unique" "uniqueBy"
+    "mapKeys" "mapValues" "mergeHash" "toPairs" "fromPairs"
+    "zip" "unzip" "groupBy" "countBy" "any" "all" "none" "count"
+    "contains" "startsWith" "endsWith" "indexOf"
+    "sleep" "chan" "send" "recv" "close" "wg" "wgAdd" "wgDone" "wgWait"
+    "mutex" "lock" "unlock" "withLock" "spawn" "await" "isReady"
+    "semaphore" "acquire" "release" "withSemaphore"
+    "observable" "subscribe" "unsubscribe" "map" "filter"))
+
+; Bound to the current function by the evaluator
+((identifier) @variable.builtin
+  (#eq? @variable.builtin "self"))
+
+; Literals
+
+(identifier) @variable
+
+(integer) @number
+
+(string) @string
+
+(boolean) @boolean
+
+(pair key: (string) @property)
+
+; Operators and punctuation
+
+[
+  "="
+  "=="
+  "!="
+  "<"
+  ">"
+  "+"
+  "-"
+  "*"
+  "/"
+  "!"
+  "&&"
+  "||"
+  "?"
+  ".."
+  "..."
+] @operator
+
+[
+  "("
+  ")"
+  "["
+  "]"
+  "{"
+  "}"
+] @punctuation.bracket
+
+[
+  ","
+  ";"
+  ":"
+  "."
+] @punctuation.delimiter
+
+; Comments
+
+(comment) @comment
+
+(doc_comment) @comment.documentation
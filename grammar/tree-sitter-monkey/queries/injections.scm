@@ -0,0 +1,3 @@
+; Monkey doesn't embed other languages, and its comments are plain text.
+; The file exists so editors that look for it find the grammar's queries
+; complete, and as the place to add injections as the language grows.
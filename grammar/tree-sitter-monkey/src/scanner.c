@@ -0,0 +1,30 @@
+/*
+ * External scanner for tree-sitter-monkey.
+ *
+ * Monkey has no context-sensitive tokens yet, so `externals` in grammar.js is
+ * empty and this scanner never produces a token. It is kept so tokens the
+ * regular lexer can't express (for example regex literals, which need to know
+ * whether a '/' starts an expression) can be added here without changing how
+ * the grammar is built.
+ */
+
+#include "tree_sitter/parser.h"
+
+#include <stdbool.h>
+
+enum TokenType {
+    // One entry per rule in `externals`, in the same order
+    TOKEN_TYPE_COUNT,
+};
+
+void *tree_sitter_monkey_external_scanner_create(void) { return NULL; }
+
+void tree_sitter_monkey_external_scanner_destroy(void *payload) {}
+
+unsigned tree_sitter_monkey_external_scanner_serialize(void *payload, char *buffer) { return 0; }
+
+void tree_sitter_monkey_external_scanner_deserialize(void *payload, const char *buffer, unsigned length) {}
+
+bool tree_sitter_monkey_external_scanner_scan(void *payload, TSLexer *lexer, const bool *valid_symbols) {
+    return false;
+}
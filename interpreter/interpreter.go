@@ -0,0 +1,216 @@
+// Package interpreter lets Go programs run Monkey code as a scripting engine.
+// Values are passed in with SetVar or RegisterFunc, and read back with GetVar
+// or from the result of Eval. Use object.ToMonkey and object.FromMonkey to
+// convert between Go values and Monkey objects.
+package interpreter
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/eval"
+	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/object"
+	"github.com/vishen/go-monkeylang/optimizer"
+	"github.com/vishen/go-monkeylang/parser"
+)
+
+type Option func(*Interpreter)
+
+// WithConcurrency makes the interpreter safe to use from multiple goroutines.
+// Calls are run one at a time, as they share the same variables.
+func WithConcurrency() Option {
+	return func(i *Interpreter) {
+		i.mu = &sync.Mutex{}
+	}
+}
+
+// WithEvalOptions passes `opts` through to the evaluator, for example to trace
+// evaluation or change where `puts` writes.
+func WithEvalOptions(opts ...eval.Option) Option {
+	return func(i *Interpreter) {
+		i.evalOpts = append(i.evalOpts, opts...)
+	}
+}
+
+// WithOptimizer runs the optimizer over each program before it is evaluated,
+// folding constant expressions and removing if branches that can't be taken
+func WithOptimizer() Option {
+	return func(i *Interpreter) {
+		i.optimize = true
+	}
+}
+
+// Interpreter keeps variables between calls to Eval, like the REPL does
+type Interpreter struct {
+	evaluator *eval.Evaluator
+	env       *object.Environment
+
+	evalOpts []eval.Option
+	optimize bool
+
+	mu sync.Locker
+}
+
+// Monkey is the name programs embedding the language use for an Interpreter:
+// it bundles the lexer, parser, evaluator and environment, so
+//
+//	m := interpreter.New()
+//	result, err := m.Run(`puts("hello")`)
+//
+// is all it takes to run Monkey code
+type Monkey = Interpreter
+
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{
+		env: object.NewEnvironment(),
+		mu:  noLock{},
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	i.evaluator = eval.New(i.evalOpts...)
+	return i
+}
+
+// ParseError is the error returned for source that doesn't parse
+type ParseError struct {
+	// Each parse error, as parser.ParseString or parser.ParseFile give them
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error: " + strings.Join(e.Errors, "; ")
+}
+
+// RuntimeError is the error returned when a program fails while it runs
+type RuntimeError struct {
+	// The Monkey error, which holds the stack trace of where it was raised
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Message
+}
+
+// Evaluator returns the evaluator programs are run with, for example to read
+// the coverage or call profile it has collected
+func (i *Interpreter) Evaluator() *eval.Evaluator {
+	return i.evaluator
+}
+
+// Environment returns the environment holding the interpreter's variables
+func (i *Interpreter) Environment() *object.Environment {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.env
+}
+
+// SetEnvironment makes later calls run in `env`, and read and set variables
+// there
+func (i *Interpreter) SetEnvironment(env *object.Environment) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.env = env
+}
+
+// Eval runs `src` and returns the value of its last statement. Source that
+// doesn't parse gives a *ParseError, and a Monkey runtime error a
+// *RuntimeError.
+func (i *Interpreter) Eval(src string) (object.Object, error) {
+	return i.eval(context.Background(), src)
+}
+
+// Run is Eval, the single entry point for running Monkey source
+func (i *Interpreter) Run(src string) (object.Object, error) {
+	return i.Eval(src)
+}
+
+// EvalWithTimeout is Eval, but stops with an error if `src` is still running
+// after `d`
+func (i *Interpreter) EvalWithTimeout(src string, d time.Duration) (object.Object, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return i.eval(ctx, src)
+}
+
+// RunFile is Run for the program in the file at `path`. A file that can't be
+// read gives the *os.PathError from reading it. The parse errors give their
+// position in the file, "path:line:col: message".
+func (i *Interpreter) RunFile(path string) (object.Object, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(src)))
+	program := p.ParseProgram()
+	if errs := p.ParseErrors(); len(errs) != 0 {
+		parseErr := &ParseError{}
+		for _, e := range errs {
+			parseErr.Errors = append(parseErr.Errors, path+":"+e.Error())
+		}
+		return nil, parseErr
+	}
+	return i.run(context.Background(), program)
+}
+
+// EvalProgram is Eval for a program that has already been parsed
+func (i *Interpreter) EvalProgram(program *ast.Program) (object.Object, error) {
+	return i.run(context.Background(), program)
+}
+
+func (i *Interpreter) eval(ctx context.Context, src string) (object.Object, error) {
+	program, errs := parser.ParseString(src)
+	if len(errs) != 0 {
+		return nil, &ParseError{Errors: errs}
+	}
+	return i.run(ctx, program)
+}
+
+func (i *Interpreter) run(ctx context.Context, program *ast.Program) (object.Object, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.optimize {
+		program = optimizer.Optimize(program)
+	}
+	result := i.evaluator.Eval(ctx, program, i.env)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	return result, nil
+}
+
+// SetVar binds `name` to `val`, as if it had been set with `let`
+func (i *Interpreter) SetVar(name string, val object.Object) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.env.Set(name, val)
+}
+
+func (i *Interpreter) GetVar(name string) (object.Object, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.env.Get(name)
+}
+
+// RegisterFunc makes `fn` callable from Monkey as `name`. It shadows any
+// built-in of the same name for this interpreter only.
+func (i *Interpreter) RegisterFunc(name string, fn object.BuiltinFunction) {
+	i.SetVar(name, &object.Builtin{Name: name, Fn: fn})
+}
+
+type noLock struct{}
+
+func (noLock) Lock()   {}
+func (noLock) Unlock() {}
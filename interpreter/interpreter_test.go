@@ -0,0 +1,224 @@
+package interpreter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vishen/go-monkeylang/object"
+)
+
+func TestEval(t *testing.T) {
+	i := New()
+
+	if _, err := i.Eval("let double = fn(x) { x * 2 };"); err != nil {
+		t.Fatalf("Eval returned error: %v", err)
+	}
+
+	result, err := i.Eval("double(21)")
+	if err != nil {
+		t.Fatalf("Eval returned error: %v", err)
+	}
+	if result.Inspect() != "42" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let = 1;", "parse error: expected next token to be 'IDENT', got '=' instead; no prefix parse function for = found"},
+		{"1 + true", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		_, err := i.Eval(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lib := filepath.Join(dir, "lib.mky")
+	bad := filepath.Join(dir, "bad.mky")
+	if err := ioutil.WriteFile(lib, []byte("let triple = fn(x) { x * 3 };\ntriple(2)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(bad, []byte("let = 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New()
+	result, err := m.RunFile(lib)
+	if err != nil || result.Inspect() != "6" {
+		t.Fatalf("wrong result from RunFile. got=%v, err=%v", result, err)
+	}
+	// The file's bindings are kept, as they are for Run
+	if result, err := m.Run("triple(3)"); err != nil || result.Inspect() != "9" {
+		t.Errorf("wrong result after RunFile. got=%v, err=%v", result, err)
+	}
+
+	_, err = m.RunFile(bad)
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Errors[0] != bad+":1:5: expected next token to be 'IDENT', got '=' instead" {
+		t.Errorf("wrong error for a bad file. got=%v", err)
+	}
+	missing := filepath.Join(dir, "missing.mky")
+	_, err = m.RunFile(missing)
+	if pathErr, ok := err.(*os.PathError); !ok || !os.IsNotExist(err) || pathErr.Path != missing {
+		t.Errorf("expected an *os.PathError for a missing file. got=%T(%v)", err, err)
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	i := New()
+
+	_, err := i.Eval("let f = fn() { 1 + true };\nf()")
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected a *RuntimeError. got=%T(%v)", err, err)
+	}
+	if runtimeErr.Error() != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong message. got=%q", runtimeErr.Error())
+	}
+	if len(runtimeErr.Err.StackTrace) != 1 || runtimeErr.Err.StackTrace[0].String() != "f (2:1)" {
+		t.Errorf("wrong stack trace. got=%v", runtimeErr.Err.StackTrace)
+	}
+
+	if _, err := i.Eval("let = 1;"); err == nil {
+		t.Errorf("expected a parse error")
+	} else if _, ok := err.(*ParseError); !ok {
+		t.Errorf("expected a *ParseError. got=%T(%v)", err, err)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	i := New()
+	if _, err := i.Eval("let a = 1;"); err != nil {
+		t.Fatalf("Eval returned error: %v", err)
+	}
+
+	if a, ok := i.Environment().Get("a"); !ok || a.Inspect() != "1" {
+		t.Errorf("a not set in Environment. got=%v", a)
+	}
+
+	env := object.NewEnclosedEnvironment(i.Environment())
+	i.SetEnvironment(env)
+	if _, err := i.Eval("let b = a + 1;"); err != nil {
+		t.Fatalf("Eval returned error: %v", err)
+	}
+	if _, ok := env.Get("b"); !ok {
+		t.Errorf("b not set in the new environment")
+	}
+	if _, ok := env.Outer().Get("b"); ok {
+		t.Errorf("b should not be set in the old environment")
+	}
+}
+
+func TestVars(t *testing.T) {
+	i := New()
+	i.SetVar("limit", object.NewInteger(10))
+
+	if _, err := i.Eval("let over = limit > 5;"); err != nil {
+		t.Fatalf("Eval returned error: %v", err)
+	}
+
+	over, ok := i.GetVar("over")
+	if !ok {
+		t.Fatalf("over is not set")
+	}
+	if b, ok := over.(*object.Boolean); !ok || !b.Value {
+		t.Errorf("wrong value for over. got=%s", over.Inspect())
+	}
+
+	if _, ok := i.GetVar("missing"); ok {
+		t.Errorf("expected missing not to be set")
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	i := New()
+
+	var got []string
+	i.RegisterFunc("record", func(args ...object.Object) object.Object {
+		for _, arg := range args {
+			got = append(got, arg.Inspect())
+		}
+		return object.NewInteger(int64(len(args)))
+	})
+
+	result, err := i.Eval(`record("a", 1 + 1)`)
+	if err != nil {
+		t.Fatalf("Eval returned error: %v", err)
+	}
+	if result.Inspect() != "2" || fmt.Sprint(got) != "[a 2]" {
+		t.Errorf("wrong result. got=%s, recorded=%v", result.Inspect(), got)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	i := New(WithConcurrency())
+	i.SetVar("n", object.NewInteger(0))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := i.Eval("let n = n + 1;"); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, _ := i.GetVar("n")
+	if n.Inspect() != "400" {
+		t.Errorf("wrong count. expected=400, got=%s", n.Inspect())
+	}
+}
+
+func TestEvalWithTimeout(t *testing.T) {
+	i := New()
+
+	_, err := i.EvalWithTimeout("let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) + f(n - 1) } }; f(40)", 10*time.Millisecond)
+	if err == nil || err.Error() != "execution timed out" {
+		t.Fatalf("expected a timeout error, got=%v", err)
+	}
+
+	// The timeout only applies to that call
+	result, err := i.EvalWithTimeout("f(3)", time.Second)
+	if err != nil || result.Inspect() != "0" {
+		t.Errorf("wrong result after a timeout. got=%v, err=%v", result, err)
+	}
+}
+
+func TestWithOptimizer(t *testing.T) {
+	i := New(WithOptimizer())
+
+	result, err := i.Eval("let f = fn() { if (1 > 2) { 1 } else { 2 * 3 } }; f")
+	if err != nil {
+		t.Fatalf("Eval returned error: %v", err)
+	}
+	if result.Inspect() != "fn f() {\n    6;\n}" {
+		t.Errorf("expected the function body to be optimized. got=%q", result.Inspect())
+	}
+
+	// The result of a block is unchanged, even when a branch is removed
+	result, err = i.Eval("5; if (false) { 10 }")
+	if err != nil || result.Inspect() != "null" {
+		t.Errorf("wrong result. got=%v, err=%v", result, err)
+	}
+}
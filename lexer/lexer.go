@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/vishen/go-monkeylang/token"
 )
 
@@ -9,19 +11,69 @@ type Lexer struct {
 	pos      int
 	read_pos int
 	ch       byte // TODO(): Needs to be a rune to be able to handle UTF-8
+
+	// Position of `ch` in the input
+	line int
+	col  int
+
+	// Where the input starts, so Tokens can lex it from the start again
+	startLine, startCol int
+
+	tokens []token.Token // Set by the first call to Tokens
+
+	sawComment bool // Set once a comment other than a doc comment is skipped
 }
 
 func NewLexer(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewLexerAt(input, 1, 1)
+}
+
+// NewLexerAt lexes `input` as a piece of a larger source that starts at `line`
+// and `col`, so the positions of its tokens are positions in the larger source
+func NewLexerAt(input string, line, col int) *Lexer {
+	l := &Lexer{input: input, line: line, col: col - 1, startLine: line, startCol: col}
 	l.advance()
 	return l
 }
 
+// Tokens returns every token in the input, ending with the EOF token. The
+// input is lexed from the start the first time, whatever NextToken has
+// returned, and later calls return the same slice.
+func (l *Lexer) Tokens() []token.Token {
+	if l.tokens != nil {
+		return l.tokens
+	}
+
+	fresh := NewLexerAt(l.input, l.startLine, l.startCol)
+	tokens := []token.Token{}
+	for {
+		tok := fresh.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	l.tokens = tokens
+	return tokens
+}
+
+// HasComments returns true if `input` has any comments apart from `##` doc
+// comments. Those are tokens, the rest are skipped and lost when a program is
+// parsed.
+func HasComments(input string) bool {
+	l := NewLexer(input)
+	for l.NextToken().Type != token.EOF {
+	}
+	return l.sawComment
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var t token.Token
 
 	l.skipWhitespaces()
 
+	line, col := l.line, l.col
+
 	switch l.ch {
 	case '=':
 		if l.peek() == '=' {
@@ -33,6 +85,23 @@ func (l *Lexer) NextToken() token.Token {
 		}
 	case ';':
 		t = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		t = newToken(token.COLON, l.ch)
+	case '?':
+		t = newToken(token.QUESTION, l.ch)
+	case '.':
+		if l.peek() == '.' && l.peekAhead(2) == '.' {
+			l.advance()
+			l.advance()
+			t.Type = token.DOTDOTDOT
+			t.Literal = l.input[l.pos-2 : l.pos+1]
+		} else if l.peek() == '.' {
+			l.advance()
+			t.Type = token.DOTDOT
+			t.Literal = l.input[l.pos-1 : l.pos+1]
+		} else {
+			t = newToken(token.DOT, l.ch)
+		}
 	case '(':
 		t = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -47,6 +116,13 @@ func (l *Lexer) NextToken() token.Token {
 		t = newToken(token.LBRACE, l.ch)
 	case '}':
 		t = newToken(token.RBRACE, l.ch)
+	case '[':
+		t = newToken(token.LBRACKET, l.ch)
+	case ']':
+		t = newToken(token.RBRACKET, l.ch)
+	case '"':
+		t.Type = token.STRING
+		t.Literal = l.readString()
 	case '!':
 		if l.peek() == '=' {
 			l.advance()
@@ -55,8 +131,37 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			t = newToken(token.BANG, l.ch)
 		}
+	case '&':
+		if l.peek() == '&' {
+			l.advance()
+			t.Type = token.AND
+			t.Literal = l.input[l.pos-1 : l.pos+1]
+		} else {
+			t = newToken(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peek() == '|' {
+			l.advance()
+			t.Type = token.OR
+			t.Literal = l.input[l.pos-1 : l.pos+1]
+		} else {
+			t = newToken(token.ILLEGAL, l.ch)
+		}
+	case '#':
+		// Only `##` is left, single `#` comments are skipped as whitespace
+		t.Type = token.DOC
+		t.Literal = l.readDocComment()
 	case '/':
-		t = newToken(token.SLASH, l.ch)
+		if l.peek() == '*' {
+			// skipWhitespaces leaves only an unterminated block comment, which
+			// is illegal at its start and takes the rest of the input with it
+			t = token.Token{Type: token.ILLEGAL, Literal: "/*"}
+			for l.ch != 0 {
+				l.advance()
+			}
+		} else {
+			t = newToken(token.SLASH, l.ch)
+		}
 	case '*':
 		t = newToken(token.ASTERISK, l.ch)
 	case '<':
@@ -70,20 +175,28 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			t.Literal = l.readIdentifier()
 			t.Type = token.LookupIdent(t.Literal)
+			t.Line, t.Col = line, col
 			return t
 		} else if isDigit(l.ch) {
 			t.Literal = l.readNumber()
 			t.Type = token.INT
+			t.Line, t.Col = line, col
 			return t
 		} else {
 			t = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 	l.advance()
+	t.Line, t.Col = line, col
 	return t
 }
 
 func (l *Lexer) advance() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.col = 0
+	}
+
 	if l.read_pos >= len(l.input) {
 		l.ch = 0 // Ascii code for NUL
 	} else {
@@ -91,6 +204,7 @@ func (l *Lexer) advance() {
 	}
 	l.pos = l.read_pos
 	l.read_pos += 1
+	l.col += 1
 }
 
 func (l *Lexer) peek() byte {
@@ -101,6 +215,14 @@ func (l *Lexer) peek() byte {
 	}
 }
 
+// peekAhead returns the character `n` places after the current one
+func (l *Lexer) peekAhead(n int) byte {
+	if l.read_pos+n-1 >= len(l.input) {
+		return 0
+	}
+	return l.input[l.read_pos+n-1]
+}
+
 func (l *Lexer) readIdentifier() string {
 	pos := l.pos
 
@@ -121,10 +243,82 @@ func (l *Lexer) readNumber() string {
 	return l.input[pos:l.pos]
 }
 
-func (l *Lexer) skipWhitespaces() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+// readString reads until the closing '"' and leaves l.ch on it. An
+// unterminated string runs to the end of the input.
+func (l *Lexer) readString() string {
+	pos := l.pos + 1
+
+	for {
+		l.advance()
+		if l.ch == '"' || l.ch == 0 {
+			break
+		}
+	}
+
+	return l.input[pos:l.pos]
+}
+
+// readDocComment reads a `##` comment to the end of the line, returning the
+// text after the `##` and the space following it
+func (l *Lexer) readDocComment() string {
+	pos := l.pos + 2
+	for l.peek() != '\n' && l.peek() != 0 {
 		l.advance()
 	}
+
+	text := l.input[pos:l.read_pos]
+	text = strings.TrimPrefix(text, " ")
+	return strings.TrimRight(text, " \t\r")
+}
+
+// skipWhitespaces skips whitespace and comments. Comments run from `#` or `//`
+// to the end of the line, which makes a `#!` line at the top of a script a
+// comment too, or from `/*` to its matching `*/`. `##` starts a doc comment,
+// which is a token.
+func (l *Lexer) skipWhitespaces() {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.advance()
+		case l.ch == '#' && l.peek() != '#', l.ch == '/' && l.peek() == '/':
+			l.sawComment = true
+			for l.ch != '\n' && l.ch != 0 {
+				l.advance()
+			}
+		case l.ch == '/' && l.peek() == '*':
+			end := l.blockCommentEnd()
+			if end < 0 {
+				return
+			}
+			l.sawComment = true
+			for l.pos < end {
+				l.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// blockCommentEnd returns the offset just after the `*/` that closes the
+// block comment starting at the current character, or -1 if it isn't closed.
+// Block comments nest, so `/* /* */ */` is one comment.
+func (l *Lexer) blockCommentEnd() int {
+	depth := 0
+	for i := l.pos; i+1 < len(l.input); i++ {
+		switch l.input[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
 }
 
 // Utils
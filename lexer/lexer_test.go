@@ -16,7 +16,7 @@ let add = fn(x, y){
 
 let result = add(five, ten);
 
-!-/*5
+!-/ *5
 
 5 < 10 > 5;
 if (5 < 10) {
@@ -27,7 +27,13 @@ if (5 < 10) {
 
 10 == 10
 10 != 5
-
+"foobar"
+"foo bar"
+[1, 2];
+{"foo": "bar"}
+not x
+a && b || c and d or e
+a ? b : c
 `
 	tests := []struct {
 		expectedType    token.TokenType
@@ -103,6 +109,35 @@ if (5 < 10) {
 		{token.INT, "10"},
 		{token.NOT_EQUALS, "!="},
 		{token.INT, "5"},
+		{token.STRING, "foobar"},
+		{token.STRING, "foo bar"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
+		{token.NOT, "not"},
+		{token.IDENT, "x"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.AND, "and"},
+		{token.IDENT, "d"},
+		{token.OR, "or"},
+		{token.IDENT, "e"},
+		{token.IDENT, "a"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "b"},
+		{token.COLON, ":"},
+		{token.IDENT, "c"},
 		{token.EOF, ""},
 	}
 	l := NewLexer(input)
@@ -149,3 +184,245 @@ func TestNextTokenBasic(t *testing.T) {
 		}
 	}
 }
+
+func TestNextTokenDots(t *testing.T) {
+	input := `a.b [h, ...t] ..`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.DOT, "."},
+		{token.IDENT, "b"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "h"},
+		{token.COMMA, ","},
+		{token.DOTDOTDOT, "..."},
+		{token.IDENT, "t"},
+		{token.RBRACKET, "]"},
+		{token.DOTDOT, ".."},
+		{token.EOF, ""},
+	}
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		token := l.NextToken()
+		if token.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, token.Type)
+		}
+		if token.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, token.Literal)
+		}
+	}
+}
+
+func TestNextTokenComments(t *testing.T) {
+	input := `#!/usr/bin/env monkey
+# A comment
+let x = 1; # After code
+// Another comment, with # and ## in it
+x / 2 // Slashes
+#`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.EOF, ""},
+	}
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		token := l.NextToken()
+		if token.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, token.Type)
+		}
+		if token.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, token.Literal)
+		}
+	}
+}
+
+func TestNextTokenBlockComments(t *testing.T) {
+	input := `a /* one */ b /* two
+/* nested */ still a comment */ c /**/ d / 2
+e /* never
+/* closed */`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{token.IDENT, "a", 1},
+		{token.IDENT, "b", 1},
+		{token.IDENT, "c", 2},
+		{token.IDENT, "d", 2},
+		{token.SLASH, "/", 2},
+		{token.INT, "2", 2},
+		{token.IDENT, "e", 3},
+		{token.ILLEGAL, "/*", 3},
+		{token.EOF, "", 4},
+	}
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		token := l.NextToken()
+		if token.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, token.Type)
+		}
+		if token.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, token.Literal)
+		}
+		if token.Line != tt.expectedLine {
+			t.Fatalf("tests[%d] - line wrong. expected=%d, got=%d",
+				i, tt.expectedLine, token.Line)
+		}
+	}
+}
+
+func TestNextTokenDocComments(t *testing.T) {
+	input := `##  Adds one.  
+##
+let inc = 1; ## trailing
+# x`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.DOC, " Adds one."},
+		{token.DOC, ""},
+		{token.LET, "let"},
+		{token.IDENT, "inc"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.DOC, "trailing"},
+		{token.EOF, ""},
+	}
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		token := l.NextToken()
+		if token.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, token.Type)
+		}
+		if token.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, token.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  add(x,
+"str") == 10`
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedCol     int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"add", 2, 3},
+		{"(", 2, 6},
+		{"x", 2, 7},
+		{",", 2, 8},
+		{"str", 3, 1},
+		{")", 3, 6},
+		{"==", 3, 8},
+		{"10", 3, 11},
+		{"", 3, 13},
+	}
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		token := l.NextToken()
+		if token.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, token.Literal)
+		}
+		if token.Line != tt.expectedLine || token.Col != tt.expectedCol {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedCol, token.Line, token.Col)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	l := NewLexerAt("let x = 5;\nx", 3, 4)
+	l.NextToken() // Tokens starts from the beginning whatever has been read
+
+	tokens := l.Tokens()
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedCol     int
+	}{
+		{token.LET, "let", 3, 4},
+		{token.IDENT, "x", 3, 8},
+		{token.ASSIGN, "=", 3, 10},
+		{token.INT, "5", 3, 12},
+		{token.SEMICOLON, ";", 3, 13},
+		{token.IDENT, "x", 4, 1},
+		{token.EOF, "", 4, 2},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("wrong number of tokens. expected=%d, got=%d", len(expected), len(tokens))
+	}
+	for i, tt := range expected {
+		tok := tokens[i]
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral ||
+			tok.Line != tt.expectedLine || tok.Col != tt.expectedCol {
+			t.Errorf("tokens[%d] wrong. expected=%s %q %d:%d, got=%s %q %d:%d", i,
+				tt.expectedType, tt.expectedLiteral, tt.expectedLine, tt.expectedCol,
+				tok.Type, tok.Literal, tok.Line, tok.Col)
+		}
+	}
+
+	if again := l.Tokens(); &again[0] != &tokens[0] {
+		t.Errorf("expected Tokens to return the same slice")
+	}
+	if tok := l.NextToken(); tok.Literal != "x" {
+		t.Errorf("expected Tokens not to move NextToken on. got=%q", tok.Literal)
+	}
+}
+
+func TestHasComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 5;", false},
+		{"## Doc\nlet x = 5;", false},
+		{`let x = "# not a comment";`, false},
+		{"let x = 5; # note", true},
+		{"let x = 5; // note", true},
+		{"let x = /* note */ 5;", true},
+		{"#!/usr/bin/env monkey\n1", true},
+	}
+
+	for _, tt := range tests {
+		if got := HasComments(tt.input); got != tt.expected {
+			t.Errorf("HasComments(%q) wrong. expected=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func frame(t *testing.T, id int, method string, params interface{}) string {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		msg["id"] = id
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// run sends `requests` to a new server and returns every message it wrote
+func run(t *testing.T, requests ...string) []map[string]interface{} {
+	var in bytes.Buffer
+	for _, r := range requests {
+		in.WriteString(r)
+	}
+	var out bytes.Buffer
+
+	if err := NewServer(&in, &out).Run(); err != nil {
+		t.Fatalf("server returned error: %v", err)
+	}
+
+	replies := []map[string]interface{}{}
+	r := bufio.NewReader(&out)
+	for r.Buffered() > 0 || out.Len() > 0 {
+		msg, err := readRaw(r)
+		if err != nil {
+			t.Fatalf("could not read reply: %v", err)
+		}
+		replies = append(replies, msg)
+	}
+	return replies
+}
+
+func readRaw(r *bufio.Reader) (map[string]interface{}, error) {
+	var length int
+	if _, err := fmt.Fscanf(r, "Content-Length: %d\r\n\r\n", &length); err != nil {
+		return nil, err
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	msg := map[string]interface{}{}
+	return msg, json.Unmarshal(body, &msg)
+}
+
+func open(t *testing.T, text string) string {
+	return frame(t, 0, "textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file:///a.mky", "text": text},
+	})
+}
+
+func at(line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file:///a.mky"},
+		"position":     map[string]int{"line": line, "character": character},
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	replies := run(t,
+		frame(t, 1, "initialize", map[string]interface{}{}),
+		frame(t, 0, "initialized", map[string]interface{}{}),
+		frame(t, 2, "shutdown", nil),
+		frame(t, 0, "exit", nil),
+	)
+
+	if len(replies) != 2 {
+		t.Fatalf("expected 2 replies, got=%d: %v", len(replies), replies)
+	}
+
+	capabilities := replies[0]["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	if capabilities["hoverProvider"] != true {
+		t.Errorf("hoverProvider not enabled: %v", capabilities)
+	}
+
+	if result, ok := replies[1]["result"]; !ok || result != nil {
+		t.Errorf("expected a null result for shutdown, got=%v", replies[1])
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	replies := run(t, open(t, "let x = 5;\nlet = 10;"))
+
+	if len(replies) != 1 || replies[0]["method"] != "textDocument/publishDiagnostics" {
+		t.Fatalf("expected diagnostics to be published, got=%v", replies)
+	}
+
+	diagnostics := replies[0]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	if len(diagnostics) == 0 {
+		t.Fatalf("expected diagnostics, got none")
+	}
+
+	first := diagnostics[0].(map[string]interface{})
+	if first["message"] != "expected next token to be 'IDENT', got '=' instead" {
+		t.Errorf("wrong message. got=%q", first["message"])
+	}
+	start := first["range"].(map[string]interface{})["start"].(map[string]interface{})
+	if start["line"] != 1.0 || start["character"] != 4.0 {
+		t.Errorf("wrong start position. got=%v", start)
+	}
+}
+
+func TestDiagnosticsUnterminatedBlock(t *testing.T) {
+	for _, text := range []string{"let f = fn(x) { x + }", "let f = fn(x) { x"} {
+		replies := run(t, open(t, text))
+
+		if len(replies) != 1 || replies[0]["method"] != "textDocument/publishDiagnostics" {
+			t.Fatalf("expected diagnostics to be published for %q, got=%v", text, replies)
+		}
+		diagnostics := replies[0]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+		found := false
+		for _, d := range diagnostics {
+			if d.(map[string]interface{})["message"] == "unterminated block" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected an unterminated block diagnostic for %q. got=%v", text, diagnostics)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	text := "let add = fn(a, b) {\n  let sum = a + b;\n  sum\n};\nlet x = add(1, 2);\nlet g = fn(c) { c"
+	tests := []struct {
+		line, character int
+		expected        []string
+	}{
+		{4, 0, []string{"add", "g", "x"}},                  // Outside any function
+		{2, 2, []string{"a", "add", "b", "g", "sum", "x"}}, // In add's body
+		{3, 1, []string{"add", "g", "x"}},                  // After add's closing brace
+		{5, 17, []string{"add", "c", "g", "x"}},            // In an unclosed body
+	}
+
+	for _, tt := range tests {
+		replies := run(t,
+			open(t, text),
+			frame(t, 1, "textDocument/completion", at(tt.line, tt.character)),
+		)
+
+		items := replies[1]["result"].([]interface{})
+		labels := []string{}
+		for _, item := range items {
+			labels = append(labels, item.(map[string]interface{})["label"].(string))
+		}
+
+		if fmt.Sprint(labels) != fmt.Sprint(tt.expected) {
+			t.Errorf("wrong completions at %d:%d. expected=%v, got=%v", tt.line, tt.character, tt.expected, labels)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	tests := []struct {
+		line, character int
+		expected        interface{}
+	}{
+		{1, 9, "fn add(a, b) (a + b)"},
+		{1, 14, nil}, // On a literal
+		{1, 4, "add(1, 2)"},
+	}
+
+	for _, tt := range tests {
+		replies := run(t,
+			open(t, "let add = fn(a, b) { a + b };\nlet x = add(1, 2);"),
+			frame(t, 1, "textDocument/hover", at(tt.line, tt.character)),
+		)
+
+		result := replies[1]["result"]
+		if tt.expected == nil {
+			if result != nil {
+				t.Errorf("expected no hover at %d:%d, got=%v", tt.line, tt.character, result)
+			}
+			continue
+		}
+
+		if result == nil {
+			t.Errorf("expected hover at %d:%d, got none", tt.line, tt.character)
+			continue
+		}
+		value := result.(map[string]interface{})["contents"].(map[string]interface{})["value"]
+		if value != tt.expected {
+			t.Errorf("wrong hover at %d:%d. expected=%q, got=%q", tt.line, tt.character, tt.expected, value)
+		}
+	}
+}
+
+func TestHoverWithParseErrors(t *testing.T) {
+	// The value of g is missing the right of its +, which used to panic
+	replies := run(t,
+		open(t, "let g = 1 +;\ng"),
+		frame(t, 1, "textDocument/hover", at(1, 0)),
+	)
+
+	if len(replies) != 2 {
+		t.Fatalf("expected diagnostics and a hover reply, got=%v", replies)
+	}
+	if result := replies[1]["result"]; result != nil {
+		t.Errorf("expected no hover while there are parse errors, got=%v", result)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	replies := run(t, frame(t, 7, "workspace/symbol", map[string]interface{}{}))
+
+	err, ok := replies[0]["error"].(map[string]interface{})
+	if !ok || err["code"] != float64(methodNotFound) {
+		t.Errorf("expected a method not found error, got=%v", replies[0])
+	}
+}
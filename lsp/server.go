@@ -0,0 +1,308 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/parser"
+	"github.com/vishen/go-monkeylang/token"
+)
+
+// Server is a language server for Monkey, talking JSON-RPC over `in` and `out`
+// (normally stdin and stdout). Documents are re-parsed in full on each change.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	documents map[string]string // uri -> text
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: map[string]string{},
+	}
+}
+
+// Run handles messages until the client sends `exit` or closes the input
+func (s *Server) Run() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) error {
+	var (
+		result interface{}
+		err    error
+	)
+
+	switch msg.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   1, // Full document on every change
+				"completionProvider": map[string]interface{}{},
+				"hoverProvider":      true,
+			},
+			"serverInfo": map[string]string{"name": "monkey"},
+		}
+	case "shutdown":
+		result = nil
+	case "textDocument/didOpen":
+		params := DidOpenTextDocumentParams{}
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			return s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		params := DidChangeTextDocumentParams{}
+		if err = json.Unmarshal(msg.Params, &params); err == nil && len(params.ContentChanges) > 0 {
+			text := params.ContentChanges[len(params.ContentChanges)-1].Text
+			return s.update(params.TextDocument.URI, text)
+		}
+	case "textDocument/didClose":
+		params := DidCloseTextDocumentParams{}
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			delete(s.documents, params.TextDocument.URI)
+		}
+	case "textDocument/completion":
+		params := TextDocumentPositionParams{}
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			result = s.completion(params)
+		}
+	case "textDocument/hover":
+		params := TextDocumentPositionParams{}
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			if hover := s.hover(params); hover != nil {
+				result = hover
+			}
+		}
+	default:
+		if msg.ID != nil {
+			return s.replyError(msg, methodNotFound, "method not supported: "+msg.Method)
+		}
+		// Notifications we don't know about are ignored
+		return nil
+	}
+
+	if msg.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return s.replyError(msg, invalidParams, err.Error())
+	}
+	return s.reply(msg, result)
+}
+
+func (s *Server) reply(msg *message, result interface{}) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(body)
+	return writeMessage(s.out, &response{JSONRPC: "2.0", ID: msg.ID, Result: &raw})
+}
+
+func (s *Server) replyError(msg *message, code int, text string) error {
+	return writeMessage(s.out, &response{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+		Error:   &responseError{Code: code, Message: text},
+	})
+}
+
+// update stores the new text for a document and publishes its parse errors
+func (s *Server) update(uri, text string) error {
+	s.documents[uri] = text
+
+	p := parser.NewParser(lexer.NewLexer(text))
+	p.ParseProgram()
+
+	diagnostics := []Diagnostic{}
+	for _, err := range p.ParseErrors() {
+		start := toPosition(err.Line, err.Col)
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    Range{Start: start, End: Position{Line: start.Line, Character: start.Character + 1}},
+			Severity: severityError,
+			Source:   "monkey",
+			Message:  err.Message,
+		})
+	}
+
+	return writeMessage(s.out, &notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics},
+	})
+}
+
+// completion returns the names in scope at the cursor: those bound outside of
+// any function, and in each function whose body the cursor is in. Names bound
+// by a let-in or match arm are offered anywhere in the function they're in.
+func (s *Server) completion(params TextDocumentPositionParams) []CompletionItem {
+	program, _ := parser.ParseString(s.documents[params.TextDocument.URI])
+	closes := closingBraces(s.documents[params.TextDocument.URI])
+
+	names := map[string]bool{}
+	ast.Walk(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			names[node.Name.Value] = true
+		case *ast.LetInExpression:
+			names[node.Name.Value] = true
+		case *ast.DestructuringStatement:
+			for _, name := range ast.PatternNames(node.Pattern) {
+				names[name.Value] = true
+			}
+		case *ast.MatchExpression:
+			for _, arm := range node.Arms {
+				ast.Walk(arm.Pattern, func(node ast.Node) bool {
+					if ident, ok := node.(*ast.Identifier); ok && ident.Value != "_" {
+						names[ident.Value] = true
+					}
+					return true
+				})
+			}
+		case *ast.FunctionLiteral:
+			if node.Body == nil || !inBlock(node.Body.Token, closes, params.Position) {
+				return false
+			}
+			for _, p := range node.Parameters {
+				names[p.Value] = true
+			}
+		case *ast.ForStatement:
+			names[node.Variable.Value] = true
+		case *ast.WithStatement:
+			names[node.Name.Value] = true
+		case *ast.TryCatchStatement:
+			if node.CatchName != nil {
+				names[node.CatchName.Value] = true
+			}
+		case *ast.SelectStatement:
+			for _, c := range node.Cases {
+				if c.Var != nil {
+					names[c.Var.Value] = true
+				}
+			}
+		}
+		return true
+	})
+
+	items := []CompletionItem{}
+	for name := range names {
+		items = append(items, CompletionItem{Label: name, Kind: completionKindVariable})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
+
+// hover shows the expression bound to the identifier under the cursor. There's
+// no hover while the document has parse errors, as the tree can be missing
+// pieces.
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	text := s.documents[params.TextDocument.URI]
+
+	name := identifierAt(text, params.Position)
+	if name == "" {
+		return nil
+	}
+
+	program, errs := parser.ParseString(text)
+	if len(errs) != 0 {
+		return nil
+	}
+
+	var value ast.Expression
+	ast.Walk(program, func(node ast.Node) bool {
+		if let, ok := node.(*ast.LetStatement); ok && value == nil && let.Name.Value == name {
+			value = let.Value
+		}
+		return value == nil
+	})
+	if value == nil {
+		return nil
+	}
+
+	return &Hover{Contents: MarkupContent{Kind: "plaintext", Value: value.String()}}
+}
+
+// closingBraces maps the position of each '{' in `text` to the position of the
+// '}' that closes it. A '{' that is never closed is left out.
+func closingBraces(text string) map[Position]Position {
+	closes := map[Position]Position{}
+	open := []Position{}
+	for _, tok := range lexer.NewLexer(text).Tokens() {
+		switch tok.Type {
+		case token.LBRACE:
+			open = append(open, toPosition(tok.Line, tok.Col))
+		case token.RBRACE:
+			if len(open) > 0 {
+				closes[open[len(open)-1]] = toPosition(tok.Line, tok.Col)
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	return closes
+}
+
+// inBlock reports whether `pos` is between the `brace` opening a block and the
+// '}' closing it. An unclosed block runs to the end of the document.
+func inBlock(brace token.Token, closes map[Position]Position, pos Position) bool {
+	start := toPosition(brace.Line, brace.Col)
+	if !before(start, pos) {
+		return false
+	}
+	end, ok := closes[start]
+	return !ok || !before(end, pos)
+}
+
+// before reports whether `a` comes before `b` in the document
+func before(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// identifierAt returns the identifier covering `pos`, or "" if there isn't one
+func identifierAt(text string, pos Position) string {
+	l := lexer.NewLexer(text)
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			return ""
+		}
+
+		start := toPosition(tok.Line, tok.Col)
+		if tok.Type == token.IDENT && start.Line == pos.Line &&
+			start.Character <= pos.Character && pos.Character <= start.Character+len(tok.Literal) {
+			return tok.Literal
+		}
+	}
+}
+
+// Tokens count lines and columns from 1, LSP counts them from 0
+func toPosition(line, col int) Position {
+	if line > 0 {
+		line--
+	}
+	if col > 0 {
+		col--
+	}
+	return Position{Line: line, Character: col}
+}
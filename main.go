@@ -1,19 +1,349 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/user"
+	"runtime"
+	"runtime/pprof"
+	"strings"
 
+	"github.com/vishen/go-monkeylang/analysis"
+	astjson "github.com/vishen/go-monkeylang/ast/json"
+	"github.com/vishen/go-monkeylang/eval"
+	"github.com/vishen/go-monkeylang/interpreter"
+	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/lsp"
+	"github.com/vishen/go-monkeylang/parser"
 	"github.com/vishen/go-monkeylang/repl"
 )
 
+// Profiles are written in the format `go tool pprof` reads, for example:
+//
+//	monkey --profile cpu.pprof fib.mky
+//	go tool pprof -top monkey cpu.pprof
+//
+// or `go tool pprof -http=:8080 cpu.pprof` to browse them. Heap profiles from
+// --memprofile are read the same way.
+var (
+	trace      = flag.Bool("trace", os.Getenv("MONKEY_TRACE") == "1", "print each evaluated node and its result to stderr")
+	cpuProfile = flag.String("profile", "", "write a CPU profile to `file`")
+	memProfile = flag.String("memprofile", "", "write a heap profile to `file`")
+
+	callProfile  = flag.Bool("call-profile", false, "print how often each function was called when a file finishes running")
+	optimize     = flag.Bool("optimize", false, "fold constant expressions and remove if branches that can't be taken before running")
+	replMode     = flag.String("repl-mode", "text", "write REPL results as `text`, or as json for other programs to read")
+	interactive  = flag.Bool("interactive", false, "start the REPL with the file's bindings after running it")
+	coverageFile = flag.String("coverage", "", "write an LCOV report of the lines run to `file`, and print the percentage covered")
+
+	lspMode = flag.Bool("lsp", false, "run a language server on stdin and stdout")
+	sandbox = flag.Bool("sandbox", false, "run without built-ins that touch the filesystem, network or terminal, and with call depth and time limits")
+	emitAST = flag.Bool("emit-ast", false, "print the AST of the file, or stdin, as JSON and exit")
+	check   = flag.Bool("check", false, "report parse errors and likely mistakes in the file, or stdin, without running it")
+	lint    = flag.Bool("lint", false, "report style issues in the file, or stdin, without running it")
+	lintErr = flag.String("lint-error-on", "", "comma separated lint `codes`, such as ML001, to report as errors rather than warnings")
+)
+
 func main() {
+	flag.Parse()
+
+	if *lspMode {
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *emitAST {
+		os.Exit(emitProgramAST(flag.Arg(0), os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	if *replMode != "text" && *replMode != "json" {
+		fmt.Fprintf(os.Stderr, "unknown --repl-mode %q, want text or json\n", *replMode)
+		os.Exit(2)
+	}
+
+	if *check {
+		os.Exit(checkProgram(flag.Arg(0), os.Stdin, os.Stderr))
+	}
+
+	if *lint {
+		os.Exit(lintProgram(flag.Arg(0), os.Stdin, os.Stderr, *lintErr))
+	}
+
+	opts := []eval.Option{}
+	if *trace {
+		opts = append(opts, eval.WithTrace(os.Stderr))
+	}
+
+	if *callProfile {
+		opts = append(opts, eval.WithCallProfile())
+	}
+
+	if *sandbox {
+		opts = append(opts, eval.WithSandbox())
+	}
+
+	code := withProfiling(*cpuProfile, *memProfile, func() int {
+		if flag.NArg() > 0 {
+			if *coverageFile != "" {
+				opts = append(opts, eval.WithCoverage(flag.Arg(0)))
+			}
+			interp := interpreter.New(interpreterOptions(opts)...)
+			code := runFile(flag.Arg(0), os.Stderr, interp)
+			if *callProfile {
+				interp.Evaluator().WriteCallProfile(os.Stderr)
+			}
+			if *coverageFile != "" {
+				if err := writeCoverage(*coverageFile, interp.Evaluator().Coverage(), os.Stderr); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					return 1
+				}
+			}
+			if *interactive {
+				// Errors have already been reported, the bindings made
+				// before them are still worth a look
+				if *replMode == "json" {
+					repl.StartJSON(os.Stdin, os.Stdout, interp)
+				} else {
+					repl.StartWith(os.Stdin, os.Stdout, interp, flag.Arg(0))
+				}
+				return 0
+			}
+			return code
+		}
+		startRepl(interpreterOptions(opts)...)
+		return 0
+	})
+	os.Exit(code)
+}
+
+func startRepl(opts ...interpreter.Option) {
+	if *replMode == "json" {
+		repl.StartJSON(os.Stdin, os.Stdout, interpreter.New(opts...))
+		return
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
 	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.StartWith(os.Stdin, os.Stdout, interpreter.New(opts...), "")
+}
+
+// interpreterOptions gives the options for an interpreter that evaluates with
+// `opts`, and optimizes programs first if --optimize was given
+func interpreterOptions(opts []eval.Option) []interpreter.Option {
+	result := []interpreter.Option{interpreter.WithEvalOptions(opts...)}
+	if *optimize {
+		result = append(result, interpreter.WithOptimizer())
+	}
+	return result
+}
+
+// runFile evaluates the Monkey program in `path` with `interp`, writing any
+// parse or runtime errors to `errOut`. It returns the process exit code.
+func runFile(path string, errOut io.Writer, interp *interpreter.Monkey) int {
+	_, err := interp.RunFile(path)
+	switch err := err.(type) {
+	case nil:
+		return 0
+	case *interpreter.ParseError:
+		for _, msg := range err.Errors {
+			fmt.Fprintln(errOut, msg)
+		}
+	case *interpreter.RuntimeError:
+		fmt.Fprintf(errOut, "%s: %s\n", path, err.Err.Message)
+		for _, frame := range err.Err.StackTrace {
+			fmt.Fprintf(errOut, "\tat %s\n", frame)
+		}
+	default:
+		fmt.Fprintln(errOut, err)
+	}
+	return 1
+}
+
+// emitProgramAST writes the AST of the program in `path`, or `stdin` if there
+// is no path, as JSON. It returns the process exit code.
+func emitProgramAST(path string, stdin io.Reader, out, errOut io.Writer) int {
+	path, input, err := readInput(path, stdin)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+
+	program, errs := parser.ParseString(string(input))
+	if len(errs) != 0 {
+		for _, msg := range errs {
+			fmt.Fprintf(errOut, "%s: %s\n", path, msg)
+		}
+		return 1
+	}
+
+	body, err := astjson.Marshal(program)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+	out.Write(append(body, '\n'))
+	return 0
+}
+
+// checkProgram parses the program in `path`, or `stdin` if there is no path,
+// and runs the passes in the analysis package over it without evaluating it.
+// Every parse error and issue is written to `errOut` as path:line:col: message.
+// It returns the process exit code, 1 if anything was found.
+func checkProgram(path string, stdin io.Reader, errOut io.Writer) int {
+	path, input, err := readInput(path, stdin)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(input)))
+	program := p.ParseProgram()
+	if len(p.ParseErrors()) != 0 {
+		for _, e := range p.ParseErrors() {
+			fmt.Fprintf(errOut, "%s:%d:%d: %s\n", path, e.Line, e.Col, e.Message)
+		}
+		return 1
+	}
+
+	issues := analysis.Check(program)
+	for _, issue := range issues {
+		fmt.Fprintf(errOut, "%s:%s\n", path, issue)
+	}
+	if len(issues) != 0 {
+		return 1
+	}
+	return 0
+}
+
+// lintProgram parses the program in `path`, or `stdin` if there is no path,
+// and writes the style issues analysis.Lint finds in it to `errOut`. Issues
+// are warnings unless their code is in the comma separated `errorCodes`. It
+// returns the process exit code, 1 if there were parse errors or errors.
+func lintProgram(path string, stdin io.Reader, errOut io.Writer, errorCodes string) int {
+	asErrors := map[string]bool{}
+	for _, code := range strings.Split(errorCodes, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			asErrors[code] = true
+		}
+	}
+	for code := range asErrors {
+		if !contains(analysis.Codes(), code) {
+			fmt.Fprintf(errOut, "unknown lint code %s, want one of %s\n", code, strings.Join(analysis.Codes(), ", "))
+			return 2
+		}
+	}
+
+	path, input, err := readInput(path, stdin)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(input)))
+	program := p.ParseProgram()
+	if len(p.ParseErrors()) != 0 {
+		for _, e := range p.ParseErrors() {
+			fmt.Fprintf(errOut, "%s:%d:%d: %s\n", path, e.Line, e.Col, e.Message)
+		}
+		return 1
+	}
+
+	code := 0
+	for _, issue := range analysis.Lint(program) {
+		severity := "warning"
+		if asErrors[issue.Code] {
+			severity = "error"
+			code = 1
+		}
+		fmt.Fprintf(errOut, "%s:%d:%d: %s: %s %s\n", path, issue.Line, issue.Col, severity, issue.Code, issue.Message)
+	}
+	return code
+}
+
+func contains(list []string, s string) bool {
+	for _, el := range list {
+		if el == s {
+			return true
+		}
+	}
+	return false
+}
+
+// readInput reads the file at `path`, or all of `stdin` if `path` is empty.
+// The path is returned as "<stdin>" for stdin, for use in error messages.
+func readInput(path string, stdin io.Reader) (string, []byte, error) {
+	if path == "" {
+		input, err := ioutil.ReadAll(stdin)
+		return "<stdin>", input, err
+	}
+	input, err := ioutil.ReadFile(path)
+	return path, input, err
+}
+
+// writeCoverage writes `coverage` to `path` as LCOV, and a summary of it to
+// `summary`
+func writeCoverage(path string, coverage eval.CoverageMap, summary io.Writer) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := coverage.WriteLCOV(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(summary, "coverage: %.1f%% of lines\n", coverage.Percent())
+	return nil
+}
+
+// withProfiling runs `f`, writing a CPU profile of it to `cpuFile` and a heap
+// profile taken after it returns to `memFile`. Empty file names disable the
+// matching profile.
+func withProfiling(cpuFile, memFile string, f func() int) int {
+	if cpuFile != "" {
+		out, err := os.Create(cpuFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not create CPU profile: %v\n", err)
+			return 1
+		}
+		defer out.Close()
+
+		if err := pprof.StartCPUProfile(out); err != nil {
+			fmt.Fprintf(os.Stderr, "could not start CPU profile: %v\n", err)
+			return 1
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	code := f()
+
+	if memFile != "" {
+		out, err := os.Create(memFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not create heap profile: %v\n", err)
+			return 1
+		}
+		defer out.Close()
+
+		runtime.GC() // Get up-to-date statistics
+		if err := pprof.WriteHeapProfile(out); err != nil {
+			fmt.Fprintf(os.Stderr, "could not write heap profile: %v\n", err)
+			return 1
+		}
+	}
+
+	return code
 }
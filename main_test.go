@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vishen/go-monkeylang/interpreter"
+)
+
+const fibProgram = `
+let fib = fn(n) {
+    if (n < 2) {
+        return n;
+    }
+    fib(n - 1) + fib(n - 2);
+};
+fib(22);
+`
+
+func TestProfiling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "fib.mky")
+	if err := ioutil.WriteFile(source, []byte(fibProgram), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cpuFile := filepath.Join(dir, "cpu.pprof")
+	memFile := filepath.Join(dir, "mem.pprof")
+
+	var errOut bytes.Buffer
+	code := withProfiling(cpuFile, memFile, func() int {
+		return runFile(source, &errOut, interpreter.New())
+	})
+	if code != 0 {
+		t.Fatalf("program exited with %d: %s", code, errOut.String())
+	}
+
+	for _, name := range []string{cpuFile, memFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("profile not written: %v", err)
+		}
+		if info.Size() == 0 {
+			t.Errorf("profile %s is empty", name)
+		}
+	}
+}
+
+func TestRunFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let = 5;", "error.mky:1:5: expected next token to be 'IDENT', got '=' instead"},
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		source := filepath.Join(dir, "error.mky")
+		if err := ioutil.WriteFile(source, []byte(tt.input), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var errOut bytes.Buffer
+		if code := runFile(source, &errOut, interpreter.New()); code != 1 {
+			t.Errorf("wrong exit code. expected=1, got=%d", code)
+		}
+		if !bytes.Contains(errOut.Bytes(), []byte(tt.expected)) {
+			t.Errorf("expected %q in output. got=%q", tt.expected, errOut.String())
+		}
+	}
+}
+
+func TestEmitAST(t *testing.T) {
+	expected, err := ioutil.ReadFile("ast/json/testdata/program.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := emitProgramAST("ast/json/testdata/program.mky", nil, &out, &errOut); code != 0 {
+		t.Fatalf("exited with %d: %s", code, errOut.String())
+	}
+	if out.String() != string(expected) {
+		t.Errorf("wrong AST. got=\n%s", out.String())
+	}
+
+	out.Reset()
+	errOut.Reset()
+	code := emitProgramAST("", strings.NewReader("let = 1;"), &out, &errOut)
+	if code != 1 {
+		t.Errorf("expected exit code 1 for a parse error, got=%d", code)
+	}
+	if out.Len() != 0 || errOut.String() != "<stdin>: expected next token to be 'IDENT', got '=' instead\n<stdin>: no prefix parse function for = found\n" {
+		t.Errorf("wrong output. out=%q, err=%q", out.String(), errOut.String())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		code     int
+		expected string
+	}{
+		{"let x = 1; puts(x);", 0, ""},
+		{"let x = 5 in x * x;", 0, ""},
+		{"let y = 1 in 2;", 1, "<stdin>:1:5: y is never used\n"},
+		{"let x = 1;\nlet y = 2;\nputs(x);", 1, "<stdin>:2:5: y is never used\n"},
+		{"let = 1;", 1, "<stdin>:1:5: expected next token to be 'IDENT', got '=' instead\n"},
+		{"let f = fn() {\n  return 1;\n  puts(2);\n};\nf();", 1, "<stdin>:3:3: unreachable code\n"},
+		{"let f = fn(x) {\n  x", 1, "<stdin>:1:15: unterminated block\n"},
+		{"let f = fn(x) { x + }", 1, "<stdin>:1:21: no prefix parse function for } found\n<stdin>:1:15: unterminated block\n"},
+	}
+
+	for _, tt := range tests {
+		var errOut bytes.Buffer
+		code := checkProgram("", strings.NewReader(tt.input), &errOut)
+		if code != tt.code {
+			t.Errorf("wrong exit code for %q. expected=%d, got=%d", tt.input, tt.code, code)
+		}
+		if !strings.HasPrefix(errOut.String(), tt.expected) {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, errOut.String())
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	input := "let count = 1;\nlet show = fn() { let count = 2; count };\nlet x = 3;\n"
+
+	tests := []struct {
+		errorCodes string
+		code       int
+		expected   string
+	}{
+		{"", 0, "<stdin>:2:23: warning: ML004 count shadows a variable in an outer scope\n" +
+			"<stdin>:3:5: warning: ML005 x is too short to say what it holds\n"},
+		{"ML004, ML001", 1, "<stdin>:2:23: error: ML004 count shadows a variable in an outer scope\n" +
+			"<stdin>:3:5: warning: ML005 x is too short to say what it holds\n"},
+		{"ML999", 2, "unknown lint code ML999, want one of ML001, ML002, ML003, ML004, ML005\n"},
+	}
+
+	for _, tt := range tests {
+		var errOut bytes.Buffer
+		code := lintProgram("", strings.NewReader(input), &errOut, tt.errorCodes)
+		if code != tt.code {
+			t.Errorf("wrong exit code with %q. expected=%d, got=%d", tt.errorCodes, tt.code, code)
+		}
+		if errOut.String() != tt.expected {
+			t.Errorf("wrong output with %q. expected=%q, got=%q", tt.errorCodes, tt.expected, errOut.String())
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package object
+
+import "reflect"
+
+// DeepEqual reports whether `a` and `b` are the same value. Arrays are equal
+// when their elements are equal in order, hashes when they hold the same keys
+// with equal values. User defined functions are only ever equal to themselves.
+func DeepEqual(a, b Object) bool {
+	if a == b {
+		return true
+	}
+
+	if a == nil || b == nil || a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Null:
+		return true
+	case *Builtin:
+		return a.Name == b.(*Builtin).Name
+	case *GoObject:
+		return reflect.DeepEqual(a.Value, b.(*GoObject).Value)
+	case *Array:
+		b := b.(*Array)
+		if len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !DeepEqual(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Range:
+		return *a == *b.(*Range)
+	case *Hash:
+		b := b.(*Hash)
+		if len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			other, ok := b.Pairs[key]
+			if !ok || !DeepEqual(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
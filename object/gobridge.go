@@ -0,0 +1,395 @@
+package object
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// GoObject wraps a Go value so it can be passed around a Monkey program.
+// Exported fields and methods of the value are reachable with dot notation.
+type GoObject struct {
+	Value interface{}
+}
+
+func (g *GoObject) Type() ObjectType { return GO_OBJECT }
+func (g *GoObject) Inspect() string {
+	return fmt.Sprintf("%v", g.Value)
+}
+
+// Member returns the exported method or field `name` of the wrapped value.
+// Methods are returned as built-ins that convert their arguments and results
+// with FromMonkey and ToMonkey. Wrap a pointer to reach methods with pointer
+// receivers.
+func (g *GoObject) Member(name string) (Object, error) {
+	v := reflect.ValueOf(g.Value)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("cannot access %s on nil", name)
+	}
+
+	if method := v.MethodByName(name); method.IsValid() {
+		return &Builtin{Name: name, Fn: goMethod(method)}, nil
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, fmt.Errorf("cannot access %s on nil %s", name, v.Type())
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Struct {
+		if field, ok := v.Type().FieldByName(name); ok && field.PkgPath == "" {
+			return promotedField(v, field, name)
+		}
+	}
+
+	return nil, fmt.Errorf("%s has no exported field or method %s", v.Type(), name)
+}
+
+// promotedField reads `field` from the struct `v`, following the embedded
+// structs it is promoted through. FieldByIndex would panic on a nil embedded
+// pointer along the way.
+func promotedField(v reflect.Value, field reflect.StructField, name string) (Object, error) {
+	for i, index := range field.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, fmt.Errorf("cannot access %s on nil %s", name, v.Type())
+			}
+			v = v.Elem()
+		}
+		v = v.Field(index)
+	}
+	return ToMonkey(v.Interface())
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func goMethod(method reflect.Value) BuiltinFunction {
+	return func(args ...Object) (result Object) {
+		// A panicking method shouldn't take the host program down with it
+		defer func() {
+			if r := recover(); r != nil {
+				result = &Error{Message: fmt.Sprint(r)}
+			}
+		}()
+
+		t := method.Type()
+
+		want := t.NumIn()
+		if t.IsVariadic() {
+			if len(args) < want-1 {
+				return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want>=%d", len(args), want-1)}
+			}
+		} else if len(args) != want {
+			return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want=%d", len(args), want)}
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var argType reflect.Type
+			if t.IsVariadic() && i >= want-1 {
+				argType = t.In(want - 1).Elem()
+			} else {
+				argType = t.In(i)
+			}
+
+			in[i] = reflect.New(argType).Elem()
+			if err := fromMonkey(arg, in[i]); err != nil {
+				return &Error{Message: fmt.Sprintf("argument %d: %s", i+1, err)}
+			}
+		}
+
+		out := method.Call(in)
+
+		// A trailing error result is turned into a Monkey error
+		if len(out) > 0 && t.Out(len(out)-1) == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return &Error{Message: err.Error()}
+			}
+			out = out[:len(out)-1]
+		}
+
+		switch len(out) {
+		case 0:
+			return NullValue
+		case 1:
+			result, err := ToMonkey(out[0].Interface())
+			if err != nil {
+				return &Error{Message: err.Error()}
+			}
+			return result
+		default:
+			elements := make([]Object, len(out))
+			for i, o := range out {
+				el, err := ToMonkey(o.Interface())
+				if err != nil {
+					return &Error{Message: err.Error()}
+				}
+				elements[i] = el
+			}
+			return &Array{Elements: elements}
+		}
+	}
+}
+
+// ToMonkey converts a Go value to the matching Monkey object. Booleans,
+// integers and strings become their Monkey counterparts, slices and arrays
+// become arrays and maps become hashes. Structs, pointers and anything else
+// without a Monkey equivalent are wrapped in a GoObject. Floats are an error,
+// as Monkey only has integers.
+func ToMonkey(v interface{}) (Object, error) {
+	if v == nil {
+		return NullValue, nil
+	}
+	if obj, ok := v.(Object); ok {
+		return obj, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return TrueValue, nil
+		}
+		return FalseValue, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewInteger(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > 1<<63-1 {
+			return nil, fmt.Errorf("cannot convert %d to a Monkey integer: too large", u)
+		}
+		return NewInteger(int64(u)), nil
+	case reflect.String:
+		return &String{Value: rv.String()}, nil
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return nil, fmt.Errorf("cannot convert %s to a Monkey object", rv.Type())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return NullValue, nil
+		}
+		elements := make([]Object, rv.Len())
+		for i := range elements {
+			el, err := ToMonkey(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = el
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return NullValue, nil
+		}
+		return mapToMonkey(rv)
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return NullValue, nil
+		}
+	}
+
+	return &GoObject{Value: v}, nil
+}
+
+func mapToMonkey(rv reflect.Value) (Object, error) {
+	// Go maps are unordered, sort the keys so the hash always comes out the same
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+
+	hash := NewHash()
+	for _, k := range keys {
+		key, err := ToMonkey(k.Interface())
+		if err != nil {
+			return nil, err
+		}
+		hashable, ok := key.(Hashable)
+		if !ok {
+			return nil, fmt.Errorf("cannot use %s as a hash key", key.Type())
+		}
+
+		value, err := ToMonkey(rv.MapIndex(k).Interface())
+		if err != nil {
+			return nil, err
+		}
+		hash.Set(hashable, value)
+	}
+	return hash, nil
+}
+
+// FromMonkey stores the Go equivalent of `o` in the value `target` points to,
+// the reverse of ToMonkey. Hashes can be stored in maps, or in structs when
+// their keys name exported fields of the struct.
+func FromMonkey(o Object, target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	return fromMonkey(o, rv.Elem())
+}
+
+func fromMonkey(o Object, target reflect.Value) error {
+	if g, ok := o.(*GoObject); ok {
+		v := reflect.ValueOf(g.Value)
+		if v.IsValid() && v.Type().AssignableTo(target.Type()) {
+			target.Set(v)
+			return nil
+		}
+		return cannotConvert(o, target)
+	}
+
+	if _, ok := o.(*Null); ok {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		if target.NumMethod() > 0 {
+			// Only Monkey objects themselves can satisfy an interface
+			if reflect.TypeOf(o).Implements(target.Type()) {
+				target.Set(reflect.ValueOf(o))
+				return nil
+			}
+			return cannotConvert(o, target)
+		}
+		natural := naturalValue(o)
+		if err := fromMonkey(o, natural); err != nil {
+			return err
+		}
+		target.Set(natural)
+		return nil
+	case reflect.Ptr:
+		v := reflect.New(target.Type().Elem())
+		if err := fromMonkey(o, v.Elem()); err != nil {
+			return err
+		}
+		target.Set(v)
+		return nil
+	}
+
+	switch o := o.(type) {
+	case *Integer:
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if target.OverflowInt(o.Value) {
+				return fmt.Errorf("%d overflows %s", o.Value, target.Type())
+			}
+			target.SetInt(o.Value)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if o.Value < 0 || target.OverflowUint(uint64(o.Value)) {
+				return fmt.Errorf("%d overflows %s", o.Value, target.Type())
+			}
+			target.SetUint(uint64(o.Value))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			target.SetFloat(float64(o.Value))
+			return nil
+		}
+	case *Boolean:
+		if target.Kind() == reflect.Bool {
+			target.SetBool(o.Value)
+			return nil
+		}
+	case *String:
+		if target.Kind() == reflect.String {
+			target.SetString(o.Value)
+			return nil
+		}
+	case *Array:
+		switch target.Kind() {
+		case reflect.Slice:
+			slice := reflect.MakeSlice(target.Type(), len(o.Elements), len(o.Elements))
+			for i, el := range o.Elements {
+				if err := fromMonkey(el, slice.Index(i)); err != nil {
+					return err
+				}
+			}
+			target.Set(slice)
+			return nil
+		case reflect.Array:
+			if target.Len() != len(o.Elements) {
+				return fmt.Errorf("cannot convert ARRAY of length %d to %s", len(o.Elements), target.Type())
+			}
+			for i, el := range o.Elements {
+				if err := fromMonkey(el, target.Index(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case *Hash:
+		switch target.Kind() {
+		case reflect.Map:
+			m := reflect.MakeMapWithSize(target.Type(), len(o.Keys))
+			for _, pair := range o.Entries() {
+				key := reflect.New(target.Type().Key()).Elem()
+				if err := fromMonkey(pair.Key, key); err != nil {
+					return err
+				}
+				value := reflect.New(target.Type().Elem()).Elem()
+				if err := fromMonkey(pair.Value, value); err != nil {
+					return err
+				}
+				m.SetMapIndex(key, value)
+			}
+			target.Set(m)
+			return nil
+		case reflect.Struct:
+			return hashToStruct(o, target)
+		}
+	}
+
+	return cannotConvert(o, target)
+}
+
+func hashToStruct(hash *Hash, target reflect.Value) error {
+	for _, pair := range hash.Entries() {
+		key, ok := pair.Key.(*String)
+		if !ok {
+			return fmt.Errorf("cannot set a field of %s from a %s key", target.Type(), pair.Key.Type())
+		}
+
+		field, ok := target.Type().FieldByNameFunc(func(name string) bool {
+			return strings.EqualFold(name, key.Value)
+		})
+		if !ok || field.PkgPath != "" {
+			return fmt.Errorf("%s has no exported field %s", target.Type(), key.Value)
+		}
+
+		if err := fromMonkey(pair.Value, target.FieldByIndex(field.Index)); err != nil {
+			return fmt.Errorf("field %s: %s", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// naturalValue returns a settable value of the Go type `o` converts to when
+// there's no other type to go by
+func naturalValue(o Object) reflect.Value {
+	var v interface{}
+	switch o.(type) {
+	case *Integer:
+		v = int64(0)
+	case *Boolean:
+		v = false
+	case *String:
+		v = ""
+	case *Array:
+		v = []interface{}{}
+	case *Hash:
+		v = map[interface{}]interface{}{}
+	default:
+		// Functions and the like are passed through as they are
+		return reflect.New(reflect.TypeOf((*Object)(nil)).Elem()).Elem()
+	}
+	return reflect.New(reflect.TypeOf(v)).Elem()
+}
+
+func cannotConvert(o Object, target reflect.Value) error {
+	return fmt.Errorf("cannot convert %s to %s", o.Type(), target.Type())
+}
@@ -0,0 +1,32 @@
+package object
+
+import "sync"
+
+// Strings shorter than this many bytes are interned by InternString
+var MaxInternLength = 64
+
+var internTable sync.Map // map[string]*String
+
+// InternString returns the canonical String object for `s`, so equal short
+// strings share one object and can be compared by pointer. Strings that are
+// MaxInternLength bytes or longer always get a new object.
+func InternString(s string) *String {
+	if len(s) >= MaxInternLength {
+		return &String{Value: s}
+	}
+
+	if str, ok := internTable.Load(s); ok {
+		return str.(*String)
+	}
+
+	str, _ := internTable.LoadOrStore(s, &String{Value: s})
+	return str.(*String)
+}
+
+// PurgeInternCache forgets every interned string
+func PurgeInternCache() {
+	internTable.Range(func(key, _ interface{}) bool {
+		internTable.Delete(key)
+		return true
+	})
+}
@@ -3,9 +3,14 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/format"
 )
 
 type ObjectType string
@@ -13,12 +18,35 @@ type ObjectType string
 const (
 	INTEGER      = "INTEGER"
 	BOOLEAN      = "BOOLEAN"
+	STRING       = "STRING"
+	ARRAY        = "ARRAY"
+	HASH         = "HASH"
 	RETURN_VALUE = "RETURN_VALUE"
 	FUNCTION     = "FUNCTION"
+	BUILTIN      = "BUILTIN"
+	WEAK_REF     = "WEAK_REF"
+	GO_OBJECT    = "GO_OBJECT"
+	MODULE       = "MODULE"
+	CHANNEL      = "CHANNEL"
+	WAIT_GROUP   = "WAIT_GROUP"
+	MUTEX        = "MUTEX"
+	FUTURE       = "FUTURE"
+	SEMAPHORE    = "SEMAPHORE"
+	OBSERVABLE   = "OBSERVABLE"
+	SUBSCRIPTION = "SUBSCRIPTION"
+	RANGE        = "RANGE"
 	ERROR        = "ERROR"
+	ERROR_VALUE  = "ERROR_VALUE"
 	NULL         = "NULL"
 )
 
+// The only null and boolean objects, so they can be compared by pointer
+var (
+	NullValue  = &Null{}
+	TrueValue  = &Boolean{Value: true}
+	FalseValue = &Boolean{Value: false}
+)
+
 type Object interface {
 	Type() ObjectType
 	Inspect() string
@@ -28,28 +56,68 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+
+	// The literal the function was created from, used to show its source
+	Literal *ast.FunctionLiteral
 }
 
 func (f Function) Type() ObjectType { return FUNCTION }
+
+// Name returns the name the function was declared or bound with, or "" for an
+// anonymous function
+func (f Function) Name() string {
+	if f.Literal == nil {
+		return ""
+	}
+	return f.Literal.Name
+}
+
+// Inspect returns the function's source, as the formatter writes it
 func (f Function) Inspect() string {
-	var out bytes.Buffer
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
-	out.WriteString("fn")
-	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") {\n")
-	out.WriteString(f.Body.String())
-	out.WriteString("\n}")
-	return out.String()
+	literal := f.Literal
+	if literal == nil {
+		literal = &ast.FunctionLiteral{Parameters: f.Parameters, Body: f.Body}
+	}
+	return format.Node(literal)
+}
+
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
 }
 
+func (b *Builtin) Type() ObjectType { return BUILTIN }
+func (b *Builtin) Inspect() string  { return "builtin function " + b.Name }
+
 type Integer struct {
 	Value int64
 }
 
+// Small integers are allocated once up front and shared, so arithmetic on
+// them doesn't allocate. Integers are never mutated so sharing is safe.
+const (
+	minPooledInteger = -256
+	maxPooledInteger = 1023
+)
+
+var integerPool = func() []*Integer {
+	pool := make([]*Integer, maxPooledInteger-minPooledInteger+1)
+	for i := range pool {
+		pool[i] = &Integer{Value: int64(i + minPooledInteger)}
+	}
+	return pool
+}()
+
+// NewInteger returns an Integer for `v`, using the shared pool when it can
+func NewInteger(v int64) *Integer {
+	if v >= minPooledInteger && v <= maxPooledInteger {
+		return integerPool[v-minPooledInteger]
+	}
+	return &Integer{Value: v}
+}
+
 func (i Integer) Type() ObjectType { return INTEGER }
 func (i Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
@@ -64,6 +132,347 @@ func (b Boolean) Inspect() string {
 	return fmt.Sprintf("%t", b.Value)
 }
 
+type String struct {
+	Value string
+}
+
+func (s String) Type() ObjectType { return STRING }
+func (s String) Inspect() string  { return s.Value }
+
+type Array struct {
+	Elements []Object
+
+	// Anything that mutates the array must return an error when it is frozen
+	IsFrozen bool
+}
+
+func (a *Array) Type() ObjectType { return ARRAY }
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range a.Elements {
+		elements = append(elements, el.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// Objects that can be used as a key in a Hash
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+func (i Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash remembers the order keys were first inserted so that it always
+// inspects the same way. Use Set to add pairs so the order is kept.
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey
+
+	// Anything that mutates the hash must return an error when it is frozen
+	IsFrozen bool
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+func (h *Hash) Type() ObjectType { return HASH }
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Entries() {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+	if _, ok := h.Pairs[hashKey]; !ok {
+		h.Keys = append(h.Keys, hashKey)
+	}
+	h.Pairs[hashKey] = HashPair{Key: key, Value: value}
+}
+
+// Entries returns the pairs in insertion order
+func (h *Hash) Entries() []HashPair {
+	entries := make([]HashPair, 0, len(h.Keys))
+	for _, key := range h.Keys {
+		entries = append(entries, h.Pairs[key])
+	}
+	return entries
+}
+
+// WeakRef refers to an object without keeping it alive. The tree-walking
+// evaluator never collects objects, so for now it holds a strong reference and
+// the referenced object is always alive.
+type WeakRef struct {
+	Value Object
+}
+
+func (w *WeakRef) Type() ObjectType { return WEAK_REF }
+func (w *WeakRef) Inspect() string  { return "weakRef(" + w.Value.Inspect() + ")" }
+
+// Deref returns the referenced object, or false if it has been collected
+func (w *WeakRef) Deref() (Object, bool) {
+	return w.Value, w.Value != nil
+}
+
+// Module is a named set of bindings, reached with dot notation
+type Module struct {
+	Name     string
+	Bindings map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE }
+func (m *Module) Inspect() string  { return "module " + m.Name }
+
+// Channel is a Go channel of objects, shared between goroutines started with
+// `go`
+type Channel struct {
+	Ch chan Object
+}
+
+func (c *Channel) Type() ObjectType { return CHANNEL }
+func (c *Channel) Inspect() string {
+	return fmt.Sprintf("channel(%d)", cap(c.Ch))
+}
+
+type WaitGroup struct {
+	WG sync.WaitGroup
+}
+
+func (w *WaitGroup) Type() ObjectType { return WAIT_GROUP }
+func (w *WaitGroup) Inspect() string  { return "waitGroup" }
+
+// Mutex is a lock that remembers who holds it, so that locking it twice from
+// the same goroutine can be reported instead of deadlocking. It is held while
+// its channel has a value in it, so waiting for it can be cancelled.
+type Mutex struct {
+	held chan struct{}
+
+	ownerMu sync.Mutex // Guards owner
+	owner   interface{}
+}
+
+func NewMutex() *Mutex {
+	return &Mutex{held: make(chan struct{}, 1)}
+}
+
+func (m *Mutex) Type() ObjectType { return MUTEX }
+func (m *Mutex) Inspect() string  { return "mutex" }
+
+// HeldBy returns true if `owner` holds the mutex
+func (m *Mutex) HeldBy(owner interface{}) bool {
+	m.ownerMu.Lock()
+	defer m.ownerMu.Unlock()
+	return m.owner == owner
+}
+
+// Lock blocks until the mutex is held by `owner`, returning false if `done`
+// is closed first
+func (m *Mutex) Lock(owner interface{}, done <-chan struct{}) bool {
+	select {
+	case m.held <- struct{}{}:
+	case <-done:
+		return false
+	}
+
+	m.ownerMu.Lock()
+	m.owner = owner
+	m.ownerMu.Unlock()
+	return true
+}
+
+// Unlock releases the mutex, returning false if it wasn't locked
+func (m *Mutex) Unlock() bool {
+	m.ownerMu.Lock()
+	defer m.ownerMu.Unlock()
+
+	if m.owner == nil {
+		return false
+	}
+	m.owner = nil
+	<-m.held
+	return true
+}
+
+// Semaphore limits how many goroutines can hold it at once. Each holder has
+// a value in the buffered channel, so acquiring blocks while it is full.
+type Semaphore struct {
+	Slots chan struct{}
+}
+
+func (s *Semaphore) Type() ObjectType { return SEMAPHORE }
+func (s *Semaphore) Inspect() string {
+	return fmt.Sprintf("semaphore(%d)", cap(s.Slots))
+}
+
+// Observable is a stream of values, produced each time it is subscribed to.
+// Run produces the values, calling emit with each one until emit returns an
+// error, which Run returns.
+type Observable struct {
+	Run func(emit func(Object) Object) Object
+}
+
+func (o *Observable) Type() ObjectType { return OBSERVABLE }
+func (o *Observable) Inspect() string  { return "observable" }
+
+// Subscription is a running subscription to an observable
+type Subscription struct {
+	stop chan struct{}
+	once sync.Once
+}
+
+func NewSubscription() *Subscription {
+	return &Subscription{stop: make(chan struct{})}
+}
+
+func (s *Subscription) Type() ObjectType { return SUBSCRIPTION }
+func (s *Subscription) Inspect() string  { return "subscription" }
+
+// Unsubscribe stops the subscription. It can be called more than once.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// Stopped is closed once Unsubscribe has been called
+func (s *Subscription) Stopped() <-chan struct{} {
+	return s.stop
+}
+
+// Future is the result of a function running in another goroutine. The
+// result is kept once it arrives, so every caller waiting on it gets the same
+// one.
+type Future struct {
+	done   chan struct{} // Closed once result is set
+	result Object
+}
+
+func NewFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) Type() ObjectType { return FUTURE }
+func (f *Future) Inspect() string {
+	if f.Ready() {
+		return "future(ready)"
+	}
+	return "future(pending)"
+}
+
+// Resolve sets the result of the future. It must only be called once.
+func (f *Future) Resolve(result Object) {
+	f.result = result
+	close(f.done)
+}
+
+// Done is closed once the result is available from Result
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Ready reports whether the result is available, without waiting for it
+func (f *Future) Ready() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Result returns the result, which is only set once Done is closed
+func (f *Future) Result() Object {
+	return f.result
+}
+
+// Range is the integers from Low up to High, including High if Inclusive.
+// The integers aren't stored, so a large range takes no more memory than a
+// small one.
+type Range struct {
+	Low       int64
+	High      int64
+	Inclusive bool
+}
+
+func (r *Range) Type() ObjectType { return RANGE }
+func (r *Range) Inspect() string {
+	operator := "..."
+	if r.Inclusive {
+		operator = ".."
+	}
+	return fmt.Sprintf("%d%s%d", r.Low, operator, r.High)
+}
+
+// Last returns the last integer in the range, and false if the range is
+// empty. It is worked out from High without adding to it, so ranges reaching
+// the largest integer don't overflow.
+func (r *Range) Last() (int64, bool) {
+	if r.Inclusive {
+		return r.High, r.High >= r.Low
+	}
+	return r.High - 1, r.High > r.Low
+}
+
+// Len returns the number of integers in the range, 0 if it is empty. Ranges
+// holding more integers than an int64 can count return the largest int64.
+func (r *Range) Len() int64 {
+	last, ok := r.Last()
+	if !ok {
+		return 0
+	}
+	if n := uint64(last-r.Low) + 1; n <= math.MaxInt64 && n != 0 {
+		return int64(n)
+	}
+	return math.MaxInt64
+}
+
 type Null struct{}
 
 func (n Null) Type() ObjectType { return NULL }
@@ -71,21 +480,66 @@ func (n Null) Inspect() string {
 	return "null"
 }
 
+// Signal is an object that stops the evaluation of the statements it's in
+// early, as `return` and errors do. Unwrap returns the value it carries out.
+type Signal interface {
+	Object
+	Unwrap() Object
+}
+
+// Unwrap returns the value a Signal carries, or `obj` itself if it isn't one
+func Unwrap(obj Object) Object {
+	if signal, ok := obj.(Signal); ok {
+		return signal.Unwrap()
+	}
+	return obj
+}
+
 type ReturnValue struct {
 	Value Object
 }
 
 func (rv ReturnValue) Type() ObjectType { return RETURN_VALUE }
 func (rv ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+func (rv ReturnValue) Unwrap() Object   { return rv.Value }
 
 type Error struct {
 	Message string
+
+	// The calls that were being evaluated when the error occurred, innermost
+	// call first. Empty when the error happened outside of any function.
+	StackTrace []StackFrame
 }
 
 func (e *Error) Type() ObjectType { return ERROR }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
-// Environment for storing variables...
+// Unwrap returns the error itself, as an error carries on up to the caller
+func (e *Error) Unwrap() Object { return e }
+
+// ErrorValue is an error caught by `try`. Unlike an Error, which stops the
+// program, it is an ordinary value that can be bound and passed around.
+type ErrorValue struct {
+	Error *Error
+}
+
+func (ev *ErrorValue) Type() ObjectType { return ERROR_VALUE }
+func (ev *ErrorValue) Inspect() string  { return ev.Error.Inspect() }
+
+type StackFrame struct {
+	FunctionName string
+	Line         int
+	Col          int
+}
+
+func (sf StackFrame) String() string {
+	return fmt.Sprintf("%s (%d:%d)", sf.FunctionName, sf.Line, sf.Col)
+}
+
+// Environment for storing variables. An enclosed environment points at its
+// outer environment rather than copying it, and names are looked up when they
+// are used, so a closure sees bindings made in an outer scope after the
+// closure was created.
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
 	return &Environment{store: s, outer: nil}
@@ -94,23 +548,71 @@ func NewEnvironment() *Environment {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.depth = outer.depth + 1
 
 	return env
 }
 
+// The store is locked as goroutines started with `go` share environments. The
+// outer environment and depth are only set when the environment is created,
+// so need no lock.
 type Environment struct {
+	mu    sync.RWMutex
 	store map[string]Object
 	outer *Environment
+	depth int // The number of environments enclosing this one
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
 	obj, ok := e.store[name]
+	e.mu.RUnlock()
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
 	return obj, ok
 }
 func (e *Environment) Set(name string, val Object) Object {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	e.store[name] = val
 	return val
 }
+
+// Keys returns the names bound in this environment, sorted. Names bound in the
+// environments it is enclosed by aren't included, see Outer.
+func (e *Environment) Keys() []string {
+	e.mu.RLock()
+	keys := make([]string, 0, len(e.store))
+	for name := range e.store {
+		keys = append(keys, name)
+	}
+	e.mu.RUnlock()
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Delete removes the binding of `name` from this environment, which uncovers
+// any binding of the same name in an outer environment. It returns false if
+// `name` isn't bound in this environment.
+func (e *Environment) Delete(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, ok := e.store[name]
+	delete(e.store, name)
+	return ok
+}
+
+// Depth returns 0 for an environment from NewEnvironment, and one more than
+// the environment it encloses for one from NewEnclosedEnvironment
+func (e *Environment) Depth() int {
+	return e.depth
+}
+
+// Outer returns the environment this one is enclosed by, or nil
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
@@ -0,0 +1,453 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestStringHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff1 := &String{Value: "My name is johnny"}
+	diff2 := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if diff1.HashKey() != diff2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestPrettyInspect(t *testing.T) {
+	inner := NewHash()
+	inner.Set(&String{Value: "a"}, &Integer{Value: 1})
+	inner.Set(&String{Value: "b"}, &Array{Elements: []Object{&Integer{Value: 2}, &Integer{Value: 3}}})
+
+	tests := []struct {
+		obj      Object
+		indent   int
+		expected string
+	}{
+		{&Integer{Value: 5}, 0, "5"},
+		{&String{Value: "monkey"}, 2, "monkey"},
+		{&Array{}, 0, "[]"},
+		{NewHash(), 0, "{}"},
+		{&Array{Elements: []Object{&Integer{Value: 1}, &Boolean{Value: true}}}, 0, "[\n  1,\n  true\n]"},
+		{
+			&Array{Elements: []Object{inner, &Array{}}},
+			0,
+			"[\n  {\n    a: 1,\n    b: [\n      2,\n      3\n    ]\n  },\n  []\n]",
+		},
+		{inner, 1, "{\n    a: 1,\n    b: [\n      2,\n      3\n    ]\n  }"},
+	}
+
+	for _, tt := range tests {
+		if actual := PrettyInspect(tt.obj, tt.indent); actual != tt.expected {
+			t.Errorf("wrong output. expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestDeepEqual(t *testing.T) {
+	hash := func(pairs ...Object) *Hash {
+		h := NewHash()
+		for i := 0; i < len(pairs); i += 2 {
+			h.Set(pairs[i].(Hashable), pairs[i+1])
+		}
+		return h
+	}
+	fn := &Function{}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &Integer{Value: 2}, false},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&Boolean{Value: true}, &Boolean{Value: false}, false},
+		{&Null{}, &Null{}, true},
+		{&Integer{Value: 1}, &String{Value: "1"}, false},
+		{
+			&Array{Elements: []Object{&Integer{Value: 1}, &Array{Elements: []Object{&Integer{Value: 2}}}}},
+			&Array{Elements: []Object{&Integer{Value: 1}, &Array{Elements: []Object{&Integer{Value: 2}}}}},
+			true,
+		},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &Array{}, false},
+		{
+			hash(&String{Value: "a"}, &Integer{Value: 1}, &String{Value: "b"}, &Integer{Value: 2}),
+			hash(&String{Value: "b"}, &Integer{Value: 2}, &String{Value: "a"}, &Integer{Value: 1}),
+			true,
+		},
+		{hash(&String{Value: "a"}, &Integer{Value: 1}), hash(&String{Value: "a"}, &Integer{Value: 3}), false},
+		{fn, fn, true},
+		{fn, &Function{}, false},
+	}
+
+	for i, tt := range tests {
+		if actual := DeepEqual(tt.a, tt.b); actual != tt.expected {
+			t.Errorf("tests[%d] - expected=%t, got=%t", i, tt.expected, actual)
+		}
+	}
+}
+
+func TestNewInteger(t *testing.T) {
+	for _, v := range []int64{-256, -1, 0, 1, 1023} {
+		a, b := NewInteger(v), NewInteger(v)
+		if a != b {
+			t.Errorf("NewInteger(%d) not pooled", v)
+		}
+		if a.Value != v {
+			t.Errorf("NewInteger(%d) has wrong value. got=%d", v, a.Value)
+		}
+	}
+
+	for _, v := range []int64{-257, 1024, 1 << 40} {
+		a, b := NewInteger(v), NewInteger(v)
+		if a == b {
+			t.Errorf("NewInteger(%d) should not be pooled", v)
+		}
+		if a.Value != v {
+			t.Errorf("NewInteger(%d) has wrong value. got=%d", v, a.Value)
+		}
+	}
+}
+
+func TestInternString(t *testing.T) {
+	defer PurgeInternCache()
+
+	a, b := InternString("monkey"), InternString("monkey")
+	if a != b {
+		t.Errorf("equal short strings were not interned")
+	}
+	if a.Value != "monkey" {
+		t.Errorf("interned string has wrong value. got=%q", a.Value)
+	}
+
+	long := string(make([]byte, MaxInternLength))
+	if InternString(long) == InternString(long) {
+		t.Errorf("strings of MaxInternLength bytes should not be interned")
+	}
+
+	PurgeInternCache()
+	if InternString("monkey") == a {
+		t.Errorf("PurgeInternCache did not clear the table")
+	}
+}
+
+type account struct {
+	Name    string
+	Balance int
+	Tags    []string
+	secret  string
+}
+
+func (a *account) Deposit(amount int) int {
+	a.Balance += amount
+	return a.Balance
+}
+
+func (a *account) Withdraw(amount int) (int, error) {
+	if amount > a.Balance {
+		return 0, errors.New("insufficient funds")
+	}
+	a.Balance -= amount
+	return a.Balance, nil
+}
+
+func (a *account) Split(ways int) int {
+	return a.Balance / ways
+}
+
+func (a account) Summary(prefix string, extra ...string) string {
+	return prefix + a.Name
+}
+
+func TestToMonkey(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{int8(-3), "-3"},
+		{uint(7), "7"},
+		{"hello", "hello"},
+		{[]int{1, 2}, "[1, 2]"},
+		{[2]bool{true, false}, "[true, false]"},
+		{map[string]int{"b": 2, "a": 1}, "{a: 1, b: 2}"},
+		{[]string(nil), "null"},
+		{(*account)(nil), "null"},
+		{NewInteger(5), "5"},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToMonkey(tt.input)
+		if err != nil {
+			t.Errorf("ToMonkey(%#v) returned error: %v", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("ToMonkey(%#v) wrong. expected=%q, got=%q", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	if obj, _ := ToMonkey(false); obj != FalseValue {
+		t.Errorf("booleans should convert to the shared objects")
+	}
+
+	if obj, _ := ToMonkey(&account{}); obj.Type() != GO_OBJECT {
+		t.Errorf("structs should be wrapped. got=%s", obj.Type())
+	}
+
+	if _, err := ToMonkey(1.5); err == nil || err.Error() != "cannot convert float64 to a Monkey object" {
+		t.Errorf("expected an error converting a float, got=%v", err)
+	}
+}
+
+func TestFromMonkey(t *testing.T) {
+	var i int8
+	if err := FromMonkey(NewInteger(-5), &i); err != nil || i != -5 {
+		t.Errorf("wrong int8. got=%d, err=%v", i, err)
+	}
+	if err := FromMonkey(NewInteger(300), &i); err == nil || err.Error() != "300 overflows int8" {
+		t.Errorf("expected an overflow error, got=%v", err)
+	}
+
+	var u uint
+	if err := FromMonkey(NewInteger(-1), &u); err == nil {
+		t.Errorf("expected an error storing a negative integer in a uint")
+	}
+
+	var names []string
+	arr := &Array{Elements: []Object{&String{Value: "a"}, &String{Value: "b"}}}
+	if err := FromMonkey(arr, &names); err != nil || !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("wrong slice. got=%v, err=%v", names, err)
+	}
+
+	hash := NewHash()
+	hash.Set(&String{Value: "name"}, &String{Value: "savings"})
+	hash.Set(&String{Value: "balance"}, NewInteger(10))
+	hash.Set(&String{Value: "tags"}, arr)
+
+	var acc account
+	if err := FromMonkey(hash, &acc); err != nil {
+		t.Fatalf("FromMonkey returned error: %v", err)
+	}
+	expected := account{Name: "savings", Balance: 10, Tags: []string{"a", "b"}}
+	if !reflect.DeepEqual(acc, expected) {
+		t.Errorf("wrong struct. expected=%+v, got=%+v", expected, acc)
+	}
+
+	var m map[string]int
+	counts := NewHash()
+	counts.Set(&String{Value: "x"}, NewInteger(1))
+	if err := FromMonkey(counts, &m); err != nil || m["x"] != 1 {
+		t.Errorf("wrong map. got=%v, err=%v", m, err)
+	}
+
+	var any interface{}
+	if err := FromMonkey(arr, &any); err != nil || !reflect.DeepEqual(any, []interface{}{"a", "b"}) {
+		t.Errorf("wrong interface value. got=%#v, err=%v", any, err)
+	}
+
+	var s string
+	if err := FromMonkey(TrueValue, &s); err == nil || err.Error() != "cannot convert BOOLEAN to string" {
+		t.Errorf("expected a conversion error, got=%v", err)
+	}
+
+	if err := FromMonkey(TrueValue, s); err == nil {
+		t.Errorf("expected an error for a non-pointer target")
+	}
+
+	ptr := &account{Name: "wrapped"}
+	var got *account
+	if err := FromMonkey(&GoObject{Value: ptr}, &got); err != nil || got != ptr {
+		t.Errorf("wrapped values should be unwrapped. got=%v, err=%v", got, err)
+	}
+}
+
+func TestGoObjectMember(t *testing.T) {
+	acc := &GoObject{Value: &account{Name: "savings", Balance: 10, secret: "x"}}
+
+	tests := []struct {
+		member   string
+		args     []Object
+		expected string
+	}{
+		{"Name", nil, "savings"},
+		{"Deposit", []Object{NewInteger(5)}, "15"},
+		{"Balance", nil, "15"},
+		{"Withdraw", []Object{NewInteger(100)}, "ERROR: insufficient funds"},
+		{"Withdraw", []Object{NewInteger(5)}, "10"},
+		{"Summary", []Object{&String{Value: "account: "}}, "account: savings"},
+		{"Summary", []Object{&String{Value: "> "}, &String{Value: "a"}}, "> savings"},
+		{"Split", []Object{NewInteger(2)}, "5"},
+		{"Split", []Object{NewInteger(0)}, "ERROR: runtime error: integer divide by zero"},
+		{"Deposit", nil, "ERROR: wrong number of arguments. got=0, want=1"},
+		{"Deposit", []Object{TrueValue}, "ERROR: argument 1: cannot convert BOOLEAN to int"},
+	}
+
+	for _, tt := range tests {
+		member, err := acc.Member(tt.member)
+		if err != nil {
+			t.Fatalf("Member(%q) returned error: %v", tt.member, err)
+		}
+
+		result := member
+		if builtin, ok := member.(*Builtin); ok {
+			result = builtin.Fn(tt.args...)
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s(%v) wrong. expected=%q, got=%q", tt.member, tt.args, tt.expected, result.Inspect())
+		}
+	}
+
+	for _, name := range []string{"secret", "Missing"} {
+		if _, err := acc.Member(name); err == nil || err.Error() != "object.account has no exported field or method "+name {
+			t.Errorf("expected an error for %s, got=%v", name, err)
+		}
+	}
+
+	// Fields promoted from an embedded pointer that is nil
+	type Owner struct{ Name string }
+	type Joint struct {
+		*Owner
+		Limit int
+	}
+	joint := &GoObject{Value: Joint{Limit: 5}}
+	if limit, err := joint.Member("Limit"); err != nil || limit.Inspect() != "5" {
+		t.Errorf("wrong Limit. got=%v, err=%v", limit, err)
+	}
+	if _, err := joint.Member("Name"); err == nil || err.Error() != "cannot access Name on nil *object.Owner" {
+		t.Errorf("expected an error for a nil embedded pointer, got=%v", err)
+	}
+	joint = &GoObject{Value: Joint{Owner: &Owner{Name: "ann"}}}
+	if owner, err := joint.Member("Name"); err != nil || owner.Inspect() != "ann" {
+		t.Errorf("wrong Name. got=%v, err=%v", owner, err)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	five := NewInteger(5)
+	err := &Error{Message: "boom"}
+
+	if got := Unwrap(&ReturnValue{Value: five}); got != five {
+		t.Errorf("expected the return value's value. got=%v", got)
+	}
+	if got := Unwrap(err); got != err {
+		t.Errorf("expected the error itself. got=%v", got)
+	}
+	if got := Unwrap(five); got != five {
+		t.Errorf("expected a non-signal to be returned as it is. got=%v", got)
+	}
+	if _, ok := Object(&ErrorValue{Error: err}).(Signal); ok {
+		t.Errorf("expected a caught error not to be a signal")
+	}
+}
+
+// Run with -race to check for data races on a shared environment
+func TestEnvironmentConcurrentAccess(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("shared", NewInteger(0))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("g%d", i)
+			global.Set(name, NewInteger(int64(i)))
+
+			local := NewEnclosedEnvironment(global)
+			local.Set("shared", NewInteger(int64(i)))
+			if _, ok := local.Get(name); !ok {
+				t.Errorf("%s not bound", name)
+			}
+			global.Get("shared")
+			global.Keys()
+			global.Delete(name + "-missing")
+		}(i)
+	}
+	wg.Wait()
+
+	if keys := global.Keys(); len(keys) != 101 {
+		t.Errorf("expected 101 names. got=%d", len(keys))
+	}
+	for i := 0; i < 100; i++ {
+		if val, ok := global.Get(fmt.Sprintf("g%d", i)); !ok || val.Inspect() != fmt.Sprint(i) {
+			t.Errorf("wrong value for g%d. got=%v", i, val)
+		}
+	}
+}
+
+func TestEnvironmentKeysAndDelete(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("b", NewInteger(1))
+	outer.Set("a", NewInteger(2))
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("c", NewInteger(3))
+	inner.Set("a", NewInteger(4))
+
+	// Only the names in the environment itself
+	if keys := inner.Keys(); fmt.Sprint(keys) != "[a c]" {
+		t.Errorf("wrong keys. got=%v", keys)
+	}
+	if keys := inner.Outer().Keys(); fmt.Sprint(keys) != "[a b]" {
+		t.Errorf("wrong outer keys. got=%v", keys)
+	}
+	if outer.Outer() != nil {
+		t.Errorf("expected no outer environment. got=%v", outer.Outer())
+	}
+	if outer.Depth() != 0 || inner.Depth() != 1 || NewEnclosedEnvironment(inner).Depth() != 2 {
+		t.Errorf("wrong depths. got=%d, %d", outer.Depth(), inner.Depth())
+	}
+
+	// Deleting the inner binding uncovers the outer one, which is left alone
+	if !inner.Delete("a") {
+		t.Fatalf("expected a to be deleted")
+	}
+	if a, _ := inner.Get("a"); a.Inspect() != "2" {
+		t.Errorf("expected the outer a after delete. got=%v", a)
+	}
+	if inner.Delete("a") || inner.Delete("b") {
+		t.Errorf("expected outer bindings not to be deleted")
+	}
+	if keys := inner.Keys(); fmt.Sprint(keys) != "[c]" {
+		t.Errorf("wrong keys after delete. got=%v", keys)
+	}
+	if keys := outer.Keys(); fmt.Sprint(keys) != "[a b]" {
+		t.Errorf("wrong outer keys after delete. got=%v", keys)
+	}
+}
+
+func TestRangeLen(t *testing.T) {
+	tests := []struct {
+		r        Range
+		expected int64
+	}{
+		{Range{Low: 1, High: 5, Inclusive: true}, 5},
+		{Range{Low: 1, High: 5}, 4},
+		{Range{Low: 5, High: 1, Inclusive: true}, 0},
+		{Range{Low: 1, High: 1}, 0},
+		{Range{Low: math.MaxInt64 - 1, High: math.MaxInt64, Inclusive: true}, 2},
+		{Range{Low: math.MinInt64, High: math.MinInt64}, 0},
+		{Range{Low: math.MinInt64, High: math.MaxInt64, Inclusive: true}, math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		if got := tt.r.Len(); got != tt.expected {
+			t.Errorf("wrong length of %s. expected=%d, got=%d", tt.r.Inspect(), tt.expected, got)
+		}
+	}
+}
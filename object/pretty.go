@@ -0,0 +1,61 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// PrettyInspect is like Inspect but puts each array element and hash pair on
+// its own line, indented two spaces per level of nesting. `indent` is the
+// nesting level `obj` is printed at. Anything that isn't an array or hash is
+// printed using Inspect.
+func PrettyInspect(obj Object, indent int) string {
+	var out bytes.Buffer
+	writePretty(&out, obj, indent)
+	return out.String()
+}
+
+func writePretty(out *bytes.Buffer, obj Object, indent int) {
+	padding := strings.Repeat("  ", indent+1)
+
+	switch obj := obj.(type) {
+	case *Array:
+		if len(obj.Elements) == 0 {
+			out.WriteString("[]")
+			return
+		}
+
+		out.WriteString("[\n")
+		for i, el := range obj.Elements {
+			out.WriteString(padding)
+			writePretty(out, el, indent+1)
+			if i < len(obj.Elements)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(strings.Repeat("  ", indent))
+		out.WriteString("]")
+	case *Hash:
+		if len(obj.Keys) == 0 {
+			out.WriteString("{}")
+			return
+		}
+
+		out.WriteString("{\n")
+		for i, pair := range obj.Entries() {
+			out.WriteString(padding)
+			out.WriteString(pair.Key.Inspect())
+			out.WriteString(": ")
+			writePretty(out, pair.Value, indent+1)
+			if i < len(obj.Keys)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(strings.Repeat("  ", indent))
+		out.WriteString("}")
+	default:
+		out.WriteString(obj.Inspect())
+	}
+}
@@ -0,0 +1,242 @@
+package optimizer
+
+import (
+	"strconv"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/token"
+)
+
+// Optimize runs the AST level optimisation passes over the program. Constant
+// folding runs first so that conditions like `2 > 1` are reduced to a boolean
+// literal before the dead code elimination pass looks at them.
+func Optimize(program *ast.Program) *ast.Program {
+	for i, stmt := range program.Statements {
+		program.Statements[i] = foldStatement(stmt)
+	}
+	program.Statements = eliminateDeadCode(program.Statements)
+	return program
+}
+
+// Constant folding
+
+func foldStatement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		stmt.Value = foldExpression(stmt.Value)
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = foldExpression(stmt.ReturnValue)
+	case *ast.ExpressionStatement:
+		stmt.Expression = foldExpression(stmt.Expression)
+	case *ast.BlockStatement:
+		foldBlock(stmt)
+	}
+	return stmt
+}
+
+func foldBlock(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+	for i, stmt := range block.Statements {
+		block.Statements[i] = foldStatement(stmt)
+	}
+}
+
+func foldExpression(exp ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.PrefixExpression:
+		exp.Right = foldExpression(exp.Right)
+		return foldPrefixExpression(exp)
+	case *ast.InfixExpression:
+		exp.Left = foldExpression(exp.Left)
+		exp.Right = foldExpression(exp.Right)
+		return foldInfixExpression(exp)
+	case *ast.IfExpression:
+		exp.Condition = foldExpression(exp.Condition)
+		foldBlock(exp.Consequence)
+		foldBlock(exp.Alternative)
+	case *ast.FunctionLiteral:
+		foldBlock(exp.Body)
+	case *ast.CallExpression:
+		exp.Function = foldExpression(exp.Function)
+		for i, arg := range exp.Arguments {
+			exp.Arguments[i] = foldExpression(arg)
+		}
+	}
+	return exp
+}
+
+func foldPrefixExpression(exp *ast.PrefixExpression) ast.Expression {
+	switch right := exp.Right.(type) {
+	case *ast.IntegerLiteral:
+		if exp.Operator == "-" {
+			return newIntegerLiteral(-right.Value)
+		}
+	case *ast.Boolean:
+		if exp.Operator == "!" {
+			return newBoolean(!right.Value)
+		}
+	}
+	return exp
+}
+
+func foldInfixExpression(exp *ast.InfixExpression) ast.Expression {
+	switch left := exp.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := exp.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return exp
+		}
+		switch exp.Operator {
+		case "+":
+			return newIntegerLiteral(left.Value + right.Value)
+		case "-":
+			return newIntegerLiteral(left.Value - right.Value)
+		case "*":
+			return newIntegerLiteral(left.Value * right.Value)
+		case "/":
+			// Leave division by zero for the evaluator to report
+			if right.Value != 0 {
+				return newIntegerLiteral(left.Value / right.Value)
+			}
+		case "<":
+			return newBoolean(left.Value < right.Value)
+		case ">":
+			return newBoolean(left.Value > right.Value)
+		case "==":
+			return newBoolean(left.Value == right.Value)
+		case "!=":
+			return newBoolean(left.Value != right.Value)
+		}
+	case *ast.Boolean:
+		right, ok := exp.Right.(*ast.Boolean)
+		if !ok {
+			return exp
+		}
+		switch exp.Operator {
+		case "==":
+			return newBoolean(left.Value == right.Value)
+		case "!=":
+			return newBoolean(left.Value != right.Value)
+		}
+	}
+	return exp
+}
+
+// Dead code elimination
+
+// eliminateDeadCode removes the untaken branch of any `if` whose condition is
+// a boolean literal. When the `if` is a statement on its own, it is replaced by
+// the taken branch as a single block, so the enclosing block still evaluates
+// to what the `if` did; blocks don't introduce a new scope so this doesn't
+// change what is visible. With no branch to take, the `if` is kept with an
+// empty body, as it evaluates to null rather than nothing.
+func eliminateDeadCode(statements []ast.Statement) []ast.Statement {
+	result := []ast.Statement{}
+
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.ExpressionStatement:
+			if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+				if cond, ok := ie.Condition.(*ast.Boolean); ok {
+					if taken := takenBranch(ie, cond); taken != nil {
+						taken.Statements = eliminateDeadCode(taken.Statements)
+						result = append(result, unwrapBlock(taken))
+					} else {
+						ie.Consequence = &ast.BlockStatement{Token: ie.Consequence.Token}
+						result = append(result, stmt)
+					}
+					continue
+				}
+			}
+			stmt.Expression = eliminateDeadCodeExpression(stmt.Expression)
+		case *ast.LetStatement:
+			stmt.Value = eliminateDeadCodeExpression(stmt.Value)
+		case *ast.ReturnStatement:
+			stmt.ReturnValue = eliminateDeadCodeExpression(stmt.ReturnValue)
+		case *ast.BlockStatement:
+			stmt.Statements = eliminateDeadCode(stmt.Statements)
+		}
+		result = append(result, stmt)
+	}
+
+	return result
+}
+
+// eliminateDeadCodeExpression handles `if` expressions used as a value, for
+// example `let x = if (true) { 1 } else { 2 }`. A block can't be used as an
+// expression, so the `if` is only replaced when the taken branch is a single
+// expression statement.
+func eliminateDeadCodeExpression(exp ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.IfExpression:
+		if exp.Consequence != nil {
+			exp.Consequence.Statements = eliminateDeadCode(exp.Consequence.Statements)
+		}
+		if exp.Alternative != nil {
+			exp.Alternative.Statements = eliminateDeadCode(exp.Alternative.Statements)
+		}
+
+		cond, ok := exp.Condition.(*ast.Boolean)
+		if !ok {
+			return exp
+		}
+		taken := takenBranch(exp, cond)
+		if taken == nil || len(taken.Statements) != 1 {
+			return exp
+		}
+		if es, ok := taken.Statements[0].(*ast.ExpressionStatement); ok {
+			return es.Expression
+		}
+	case *ast.PrefixExpression:
+		exp.Right = eliminateDeadCodeExpression(exp.Right)
+	case *ast.InfixExpression:
+		exp.Left = eliminateDeadCodeExpression(exp.Left)
+		exp.Right = eliminateDeadCodeExpression(exp.Right)
+	case *ast.FunctionLiteral:
+		if exp.Body != nil {
+			exp.Body.Statements = eliminateDeadCode(exp.Body.Statements)
+		}
+	case *ast.CallExpression:
+		exp.Function = eliminateDeadCodeExpression(exp.Function)
+		for i, arg := range exp.Arguments {
+			exp.Arguments[i] = eliminateDeadCodeExpression(arg)
+		}
+	}
+	return exp
+}
+
+// unwrapBlock returns the statement in `block` when it is a single expression,
+// which evaluates to the same value as the block
+func unwrapBlock(block *ast.BlockStatement) ast.Statement {
+	if len(block.Statements) == 1 {
+		if es, ok := block.Statements[0].(*ast.ExpressionStatement); ok {
+			return es
+		}
+	}
+	return block
+}
+
+func takenBranch(ie *ast.IfExpression, cond *ast.Boolean) *ast.BlockStatement {
+	if cond.Value {
+		return ie.Consequence
+	}
+	return ie.Alternative
+}
+
+// Utils
+func newIntegerLiteral(value int64) *ast.IntegerLiteral {
+	literal := strconv.FormatInt(value, 10)
+	return &ast.IntegerLiteral{
+		Token: token.Token{Type: token.INT, Literal: literal},
+		Value: value,
+	}
+}
+
+func newBoolean(value bool) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}
+	}
+	return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}
+}
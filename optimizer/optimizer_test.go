@@ -0,0 +1,101 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/vishen/go-monkeylang/eval"
+	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/object"
+	"github.com/vishen/go-monkeylang/parser"
+)
+
+func TestConstantFolding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2", "3"},
+		{"2 * (3 + 4) - 1", "13"},
+		{"-(5 + 5)", "-10"},
+		{"10 / 0", "(10 / 0)"},
+		{"2 > 1", "true"},
+		{"1 == 2", "false"},
+		{"!(1 < 2)", "false"},
+		{"true != false", "true"},
+		{"x + 1 * 2", "(x + 2)"},
+		{"let a = 4 * 4;", "let a = 16;"},
+		{"add(1 + 1, 2)", "add(2, 2)"},
+	}
+
+	for _, tt := range tests {
+		if actual := testOptimize(t, tt.input); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestDeadCodeElimination(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"if (true) { 10 }", "10"},
+		{"if (false) { 10 }", "iffalse "},
+		{"if (false) { 10 } else { 20 }", "20"},
+		{"if (2 > 1) { 10; 11 } else { 20 }", "1011"},
+		{"if (x) { 10 } else { 20 }", "ifx 10else 20"},
+		{"let a = if (1 > 2) { 1 } else { 2 };", "let a = 2;"},
+		{"let a = if (true) { let b = 1; b };", "let a = iftrue let b = 1;b;"},
+		{"fn() { if (true) { return 1; } 2 }", "fn() return 1;2"},
+		{"if (true) { if (false) { 1 } else { 2 } }", "2"},
+	}
+
+	for _, tt := range tests {
+		if actual := testOptimize(t, tt.input); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestBlockResultUnchanged(t *testing.T) {
+	tests := []string{
+		"5; if (false) { 10 }",
+		"let f = fn() { 1; if (false) { 2 } }; f()",
+		"let f = fn() { if (true) { 1; 2 } }; f()",
+		"let f = fn() { if (true) { let a = 1; } }; f()",
+		"let x = 1; if (true) { x + 1 }",
+		"let f = fn() { if (true) { return 1; } 2 }; f()",
+		"if (false) { 1 } else { }",
+	}
+
+	for _, input := range tests {
+		program, _ := parser.ParseString(input)
+		expected := inspect(eval.Eval(program, object.NewEnvironment()))
+
+		program, _ = parser.ParseString(input)
+		actual := inspect(eval.Eval(Optimize(program), object.NewEnvironment()))
+		if actual != expected {
+			t.Errorf("wrong result after optimising %q. expected=%s, got=%s", input, expected, actual)
+		}
+	}
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "<nil>"
+	}
+	return obj.Inspect()
+}
+
+func testOptimize(t *testing.T, input string) string {
+	t.Helper()
+
+	l := lexer.NewLexer(input)
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	return Optimize(program).String()
+}
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/vishen/go-monkeylang/ast"
+	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/token"
+)
+
+// IncrementalParser parses a source as it is edited, such as a document open
+// in an editor. Each Update only re-parses the top level statements touched by
+// the edit, the rest are reused from the last program.
+type IncrementalParser struct {
+	src    string
+	chunks []chunk
+
+	// Unset until the first Update, and after an Update with parse errors,
+	// as the chunks can't be trusted then
+	valid bool
+}
+
+// chunk is a top level statement and where its source is. Its source runs up
+// to the start of the next statement, so includes the whitespace after it.
+type chunk struct {
+	start, end int // Byte offsets in the source
+	stmt       ast.Statement
+
+	// Whether the last token of the source is a ';'. Otherwise the statement
+	// after it could carry on the expression, `x` followed by `-1` is
+	// `x - 1`, so it can't be parsed on its own.
+	terminated bool
+}
+
+func NewIncrementalParser() *IncrementalParser {
+	return &IncrementalParser{}
+}
+
+// Update parses `src`, the whole of the new source, returning its program.
+// The statements before and after the edited lines are the same nodes as in
+// the last program returned. The error is an ErrorList when `src` doesn't
+// parse, in which case the whole source was parsed again.
+func (ip *IncrementalParser) Update(src string) (*ast.Program, error) {
+	if !ip.valid {
+		return ip.parseAll(src)
+	}
+
+	old := ip.src
+	prefix := commonPrefix(old, src)
+	suffix := commonSuffix(old[prefix:], src[prefix:])
+	changeEnd := len(old) - suffix // In the old source
+	delta := len(src) - len(old)
+
+	// Statements entirely before the edit are kept, as long as the last of
+	// them ends with a ';' so the edit can't be a continuation of it
+	k := 0
+	for k < len(ip.chunks) && ip.chunks[k].end <= prefix {
+		k++
+	}
+	for k > 0 && !ip.chunks[k-1].terminated {
+		k--
+	}
+	regionStart := 0
+	if k > 0 {
+		regionStart = ip.chunks[k-1].end
+	}
+
+	// Statements after the edit are kept when they start on a later line and
+	// the edit didn't add or remove lines, so the positions in their tokens
+	// are still right
+	m := len(ip.chunks)
+	if strings.Count(old[prefix:changeEnd], "\n") == strings.Count(src[prefix:len(src)-suffix], "\n") {
+		for m > k && ip.chunks[m-1].start >= changeEnd && strings.Contains(old[changeEnd:ip.chunks[m-1].start], "\n") {
+			m--
+		}
+	}
+
+	lines := lineStarts(src)
+	for {
+		regionEnd := len(src)
+		if m < len(ip.chunks) {
+			regionEnd = ip.chunks[m].start + delta
+		}
+
+		region, errs := parseChunks(src, lines, regionStart, regionEnd)
+		if len(errs) != 0 {
+			return ip.parseAll(src)
+		}
+
+		// The first kept statement after the edit mustn't be able to carry
+		// on the last re-parsed one
+		if m < len(ip.chunks) && len(region) > 0 && !region[len(region)-1].terminated {
+			m++
+			continue
+		}
+
+		chunks := append([]chunk{}, ip.chunks[:k]...)
+		chunks = append(chunks, region...)
+		for _, c := range ip.chunks[m:] {
+			c.start += delta
+			c.end += delta
+			chunks = append(chunks, c)
+		}
+
+		ip.src, ip.chunks = src, chunks
+		return ip.program(), nil
+	}
+}
+
+func (ip *IncrementalParser) parseAll(src string) (*ast.Program, error) {
+	chunks, errs := parseChunks(src, lineStarts(src), 0, len(src))
+
+	ip.src, ip.chunks, ip.valid = src, chunks, len(errs) == 0
+	if len(errs) != 0 {
+		return ip.program(), ErrorList(errs)
+	}
+	return ip.program(), nil
+}
+
+func (ip *IncrementalParser) program() *ast.Program {
+	program := &ast.Program{Statements: []ast.Statement{}}
+	for _, c := range ip.chunks {
+		program.Statements = append(program.Statements, c.stmt)
+	}
+	return program
+}
+
+// parseChunks parses the statements in src[from:to]. `lines` holds the offset
+// of the start of each line of `src`.
+func parseChunks(src string, lines []int, from, to int) ([]chunk, []ParseError) {
+	line, col := position(lines, from)
+	p := NewParser(lexer.NewLexerAt(src[from:to], line, col))
+
+	chunks := []chunk{}
+	for !p.curTokenIs(token.EOF) {
+		// A statement's documentation is part of it
+		first := p.curToken
+		if p.curDoc.text != "" {
+			first = p.curDoc.first
+		}
+		start := lines[first.Line-1] + first.Col - 1
+		if stmt := p.parseStatement(); stmt != nil {
+			if len(chunks) > 0 {
+				chunks[len(chunks)-1].end = start
+			}
+			chunks = append(chunks, chunk{start: start, stmt: stmt})
+		}
+		p.nextToken()
+	}
+
+	for i := range chunks {
+		if i == len(chunks)-1 {
+			chunks[i].end = to
+		}
+		chunks[i].terminated = endsWithSemicolon(src[chunks[i].start:chunks[i].end])
+	}
+	return chunks, p.ParseErrors()
+}
+
+func endsWithSemicolon(src string) bool {
+	l := lexer.NewLexer(src)
+	last := token.Token{}
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type != token.DOC {
+			last = tok
+		}
+	}
+	return last.Type == token.SEMICOLON
+}
+
+func lineStarts(src string) []int {
+	lines := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
+
+// position returns the line and column of `offset`, both starting at 1 as
+// they do in tokens
+func position(lines []int, offset int) (int, int) {
+	line := 0
+	for line+1 < len(lines) && lines[line+1] <= offset {
+		line++
+	}
+	return line + 1, offset - lines[line] + 1
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func commonSuffix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
@@ -1,59 +1,129 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"strconv"
+	"strings"
 
 	"github.com/vishen/go-monkeylang/ast"
 	"github.com/vishen/go-monkeylang/lexer"
 	"github.com/vishen/go-monkeylang/token"
 )
 
+// Precedence is how tightly an operator binds its operands. An operator of a
+// higher precedence binds before one of a lower precedence.
+type Precedence int
+
 // Operator precedence for prefix and infix operators
 const (
-	_ int = iota
+	_ Precedence = iota
 	LOWEST
+	TERNARY // x ? y : z
+	RANGE   // 1..5 or 1...5
 
 	// Infix Operators
+	LOGICAL_OR  // || or `or`
+	LOGICAL_AND // && or `and`
 	EQUALS      // ==
 	LESSGREATER // > or <
+	MEMBERSHIP  // in or not in
 	SUM         // +
 	PRODUCT     // *
 
 	// Prefix operators
 	PREFIX // -X or !X
 	CALL   // myFunction(X)
+	INDEX  // array[index]
 )
 
-var precedences = map[token.TokenType]int{
+var precedences = map[token.TokenType]Precedence{
 	token.EQUALS:     EQUALS,
 	token.NOT_EQUALS: EQUALS,
 	token.LT:         LESSGREATER,
 	token.GT:         LESSGREATER,
+	token.IN:         MEMBERSHIP,
+	token.DOTDOT:     RANGE,
+	token.DOTDOTDOT:  RANGE,
+	token.NOT:        MEMBERSHIP,
+	token.QUESTION:   TERNARY,
+	token.OR:         LOGICAL_OR,
+	token.AND:        LOGICAL_AND,
 	token.PLUS:       SUM,
 	token.MINUS:      SUM,
 	token.SLASH:      PRODUCT,
 	token.ASTERISK:   PRODUCT,
 	token.LPAREN:     CALL,
+	token.LBRACKET:   INDEX,
+	token.DOT:        INDEX,
+}
+
+// PrecedenceOf returns the precedence `tok` has as an infix or postfix
+// operator, or LOWEST if it isn't one. Prefix operators bind at PREFIX.
+func PrecedenceOf(tok token.Token) Precedence {
+	if prec, ok := precedences[tok.Type]; ok {
+		return prec
+	}
+	return LOWEST
 }
 
 type prefixParseFunc func() ast.Expression
 type infixParseFunc func(ast.Expression) ast.Expression
 
+// ParseError is a parser error along with where in the source it happened
+type ParseError struct {
+	Message string
+	Line    int
+	Col     int
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Message)
+}
+
+// ErrorList is the error returned by the Parse functions when the source has
+// parse errors
+type ErrorList []ParseError
+
+func (l ErrorList) Error() string {
+	messages := []string{}
+	for _, e := range l {
+		messages = append(messages, e.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
 type Parser struct {
-	l         *lexer.Lexer
+	tokens    *token.TokenStream
 	curToken  token.Token
 	peekToken token.Token
 
-	errors []string
+	// The `##` comments on the lines just before each token, see nextToken
+	curDoc  docComment
+	peekDoc docComment
+
+	errors      []string
+	parseErrors []ParseError
 
 	// Pratt Parser; associating token.Type with parsing functions...?
 	prefixParseFuncs map[token.TokenType]prefixParseFunc
 	infixParseFuncs  map[token.TokenType]infixParseFunc
+
+	// Set while parsing the value of a let binding, where `in` starts the
+	// body of a let-in expression rather than being the membership operator.
+	// Inside brackets `in` is an operator again.
+	noIn bool
 }
 
 func NewParser(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	return NewTokenParser(token.NewTokenStream(l.Tokens()))
+}
+
+// NewTokenParser parses tokens that have already been lexed, such as those
+// from lexer.Lexer.Tokens
+func NewTokenParser(tokens *token.TokenStream) *Parser {
+	p := &Parser{tokens: tokens, errors: []string{}}
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -63,13 +133,22 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.prefixParseFuncs = make(map[token.TokenType]prefixParseFunc)
 	p.registerPrefixFunc(token.IDENT, p.parseIdentifier)
 	p.registerPrefixFunc(token.INT, p.parseIntegerLiteral)
+	p.registerPrefixFunc(token.STRING, p.parseStringLiteral)
 	p.registerPrefixFunc(token.BANG, p.parsePrefixExpression)
+	p.registerPrefixFunc(token.NOT, p.parseNotExpression)
 	p.registerPrefixFunc(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefixFunc(token.TRUE, p.parseBoolean)
 	p.registerPrefixFunc(token.FALSE, p.parseBoolean)
 	p.registerPrefixFunc(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefixFunc(token.IF, p.parseIfExpression)
 	p.registerPrefixFunc(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefixFunc(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefixFunc(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefixFunc(token.LET, p.parseLetInExpression)
+	p.registerPrefixFunc(token.MATCH, p.parseMatchExpression)
+	p.registerPrefixFunc(token.SWITCH, p.parseSwitchExpression)
+	p.registerPrefixFunc(token.TRY, p.parseTryExpression)
+	p.registerPrefixFunc(token.DOTDOTDOT, p.parseSpreadExpression)
 
 	// Register the infix functions
 	p.infixParseFuncs = make(map[token.TokenType]infixParseFunc)
@@ -82,6 +161,15 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.registerInfixFunc(token.LT, p.parseInfixExpression)
 	p.registerInfixFunc(token.GT, p.parseInfixExpression)
 	p.registerInfixFunc(token.LPAREN, p.parseCallExpression)
+	p.registerInfixFunc(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfixFunc(token.DOT, p.parseMemberExpression)
+	p.registerInfixFunc(token.IN, p.parseInfixExpression)
+	p.registerInfixFunc(token.QUESTION, p.parseTernaryExpression)
+	p.registerInfixFunc(token.AND, p.parseLogicalExpression)
+	p.registerInfixFunc(token.OR, p.parseLogicalExpression)
+	p.registerInfixFunc(token.DOTDOT, p.parseRangeLiteral)
+	p.registerInfixFunc(token.DOTDOTDOT, p.parseRangeLiteral)
+	p.registerInfixFunc(token.NOT, p.parseNotInExpression)
 
 	return p
 }
@@ -98,15 +186,26 @@ func (p Parser) Errors() []string {
 	return p.errors
 }
 
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be '%s', got '%s' instead", t, p.peekToken.Type)
+// ParseErrors returns the same errors as Errors, with their positions
+func (p Parser) ParseErrors() []ParseError {
+	return p.parseErrors
+}
+
+// addError records an error found while parsing `tok`
+func (p *Parser) addError(tok token.Token, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
 	p.errors = append(p.errors, msg)
+	p.parseErrors = append(p.parseErrors, ParseError{Message: msg, Line: tok.Line, Col: tok.Col})
+}
+
+func (p *Parser) peekError(t token.TokenType) {
+	p.addError(p.peekToken, "expected next token to be '%s', got '%s' instead", t, p.peekToken.Type)
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
-	for p.curToken.Type != token.EOF {
+	for !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
@@ -116,12 +215,118 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// ParseString parses the program in `src`, returning it along with any parse
+// errors, as Errors does
+func ParseString(src string) (*ast.Program, []string) {
+	p := NewParser(lexer.NewLexer(src))
+	program := p.ParseProgram()
+	return program, p.Errors()
+}
+
+// ParseFile reads and parses the program in the file at `path`. Each error
+// gives its position in the file, "path:line:col: message". The program is
+// nil if the file can't be read.
+func ParseFile(path string) (*ast.Program, []string) {
+	input, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	p := NewParser(lexer.NewLexer(string(input)))
+	program := p.ParseProgram()
+	errs := []string{}
+	for _, e := range p.ParseErrors() {
+		errs = append(errs, path+":"+e.Error())
+	}
+	return program, errs
+}
+
+// ParseExpression parses `src`, which must be a single expression, such as
+// `1 + 2` or `fn(x) { x }`. The error is an ErrorList when `src` doesn't parse.
+func ParseExpression(src string) (ast.Expression, error) {
+	p := NewParser(lexer.NewLexer(src))
+	program := p.ParseProgram()
+	if len(p.ParseErrors()) != 0 {
+		return nil, ErrorList(p.ParseErrors())
+	}
+
+	if len(program.Statements) != 1 {
+		return nil, fmt.Errorf("expected a single expression, got %d statements", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, errors.New("expected an expression, got a " + program.Statements[0].TokenLiteral() + " statement")
+	}
+	return stmt.Expression, nil
+}
+
 func (p *Parser) parseStatement() ast.Statement {
+	doc := p.curDoc.text
+
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		if p.peekToken.Is(token.LBRACKET, token.LBRACE) {
+			if stmt := p.parseDestructuringStatement(); stmt != nil {
+				return stmt
+			}
+			return nil
+		}
+		stmt := p.parseLetStatement()
+		if stmt == nil {
+			// Don't return a nil *ast.LetStatement as a non-nil ast.Statement
+			return nil
+		}
+		document(stmt, doc)
+		// `let x = 5 in x * x` is an expression rather than a statement
+		if p.peekTokenIs(token.IN) {
+			return p.parseLetInStatement(stmt)
+		}
+		return stmt
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.FUNCTION:
+		if !p.peekTokenIs(token.IDENT) {
+			return p.parseExpressionStatement()
+		}
+		if stmt := p.parseFunctionDeclaration(); stmt != nil {
+			document(stmt, doc)
+			return stmt
+		}
+		return nil
+	case token.GO:
+		if stmt := p.parseGoStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.TYPE:
+		if stmt := p.parseTypeAliasStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.TRY:
+		// `try {` starts a try statement rather than a try of a hash literal
+		if !p.peekTokenIs(token.LBRACE) {
+			return p.parseExpressionStatement()
+		}
+		if stmt := p.parseTryCatchStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.WITH:
+		if stmt := p.parseWithStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.FOR:
+		if stmt := p.parseForStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.SELECT:
+		if stmt := p.parseSelectStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -142,12 +347,17 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) noPrefixParseFuncError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	// The lexer turns an unclosed `/*` and the rest of the input into one
+	// illegal token
+	if t == token.ILLEGAL && p.curToken.Literal == "/*" {
+		p.addError(p.curToken, "unterminated block comment")
+		return
+	}
+	p.addError(p.curToken, "no prefix parse function for %s found", t)
 }
 
 // `prec` is for precedence
-func (p *Parser) parseExpression(prec int) ast.Expression {
+func (p *Parser) parseExpression(prec Precedence) ast.Expression {
 	prefix := p.prefixParseFuncs[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFuncError(p.curToken.Type)
@@ -159,6 +369,9 @@ func (p *Parser) parseExpression(prec int) ast.Expression {
 	// Loop until we find a semicolon, or an operator with a higher precedence
 	// If the precendence is the same or lower, add it to the current `leftExp`
 	for !p.peekTokenIs(token.SEMICOLON) && prec < p.peekPrec() {
+		if p.noIn && p.peekTokenIs(token.IN) {
+			break
+		}
 		infix := p.infixParseFuncs[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -184,6 +397,64 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseTernaryExpression parses `condition ? consequence : alternative`. It is
+// right associative, so `a ? b : c ? d : e` is `a ? b : (c ? d : e)`.
+func (p *Parser) parseTernaryExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.TernaryExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	restore := p.allowIn()
+	expression.Consequence = p.parseExpression(LOWEST)
+	restore()
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Alternative = p.parseExpression(TERNARY - 1)
+
+	return expression
+}
+
+// parseLogicalExpression parses `&&` and `||`, and their keyword forms `and`
+// and `or`. The operator is always stored as `&&` or `||`.
+func (p *Parser) parseLogicalExpression(left ast.Expression) ast.Expression {
+	expression := p.parseInfixExpression(left).(*ast.InfixExpression)
+	expression.Operator = string(expression.Token.Type)
+	return expression
+}
+
+func (p *Parser) parseRangeLiteral(low ast.Expression) ast.Expression {
+	lit := &ast.RangeLiteral{Token: p.curToken, Low: low, Inclusive: p.curTokenIs(token.DOTDOT)}
+
+	p.nextToken()
+	lit.High = p.parseExpression(RANGE)
+
+	return lit
+}
+
+// parseNotInExpression parses `not in`, the negated membership operator
+func (p *Parser) parseNotInExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{Token: p.curToken, Operator: "not in", Left: left}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(MEMBERSHIP)
+
+	return expression
+}
+
+// allowIn lets `in` be used as an operator inside brackets, returning a func
+// that restores the previous setting
+func (p *Parser) allowIn() func() {
+	noIn := p.noIn
+	p.noIn = false
+	return func() { p.noIn = noIn }
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -197,13 +468,19 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return expression
 }
 
+// parseNotExpression parses `not x`, which is another way to write `!x`
+func (p *Parser) parseNotExpression() ast.Expression {
+	expression := p.parsePrefixExpression().(*ast.PrefixExpression)
+	expression.Operator = "!"
+	return expression
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 
@@ -212,9 +489,33 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+// parseFunctionDeclaration parses `fn name(params) { body }` as
+// `let name = fn name(params) { body }`
+func (p *Parser) parseFunctionDeclaration() *ast.LetStatement {
+	stmt := &ast.LetStatement{Token: p.curToken}
+	stmt.Name = &ast.Identifier{Token: p.peekToken, Value: p.peekToken.Literal}
+
+	lit := p.parseFunctionLiteral()
+	if lit == nil {
+		return nil
+	}
+	stmt.Value = lit
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		lit.Name = p.curToken.Literal
+	}
+
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
@@ -259,35 +560,111 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
 	return exp
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+// parseExpressionList parses comma separated expressions up to and including
+// the `end` token.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	defer p.allowIn()()
 
-	if p.peekTokenIs(token.RPAREN) {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
 		p.nextToken()
-		return args
+		return list
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	return array
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	defer p.allowIn()()
+
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: left}
+
+	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	return args
+	return exp
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	defer p.allowIn()()
+
+	hash := &ast.HashLiteral{Token: p.curToken}
+	hash.Pairs = []ast.HashPair{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if p.unterminated(hash.Token, p.peekToken) {
+			return nil
+		}
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
+	defer p.allowIn()()
+
 	expression := &ast.IfExpression{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) {
 		return nil
@@ -320,12 +697,17 @@ func (p *Parser) parseIfExpression() ast.Expression {
 }
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	defer p.allowIn()()
+
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) {
+		if p.unterminated(block.Token, p.curToken) {
+			return block
+		}
 		stmt := p.parseStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
@@ -337,6 +719,8 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	defer p.allowIn()()
+
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
@@ -356,7 +740,144 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// document sets the documentation of a let statement, and of the function it
+// binds so `source` can show it
+func document(stmt *ast.LetStatement, doc string) {
+	stmt.Doc = doc
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && fn.Name == stmt.Name.Value {
+		fn.Doc = doc
+	}
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
+	stmt := p.parseLetBinding()
+	if stmt == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseDestructuringStatement parses `let pattern = value`, such as
+// `let [x, y = 0] = point`
+func (p *Parser) parseDestructuringStatement() *ast.DestructuringStatement {
+	stmt := &ast.DestructuringStatement{Token: p.curToken}
+
+	p.nextToken()
+	if stmt.Pattern = p.parsePattern(); stmt.Pattern == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+
+	if stmt.Value = p.parseExpression(LOWEST); stmt.Value == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parsePattern parses a name, an array of patterns or a hash of patterns, to
+// destructure a value into. Elements of arrays and hashes can have a default,
+// `[x = 0]`.
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.LBRACKET:
+		array := &ast.ArrayLiteral{Token: p.curToken, Elements: []ast.Expression{}}
+		for !p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+			el := p.parsePatternElement()
+			if el == nil {
+				return nil
+			}
+			array.Elements = append(array.Elements, el)
+
+			if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+		p.nextToken()
+		return array
+	case token.LBRACE:
+		hash := &ast.HashLiteral{Token: p.curToken, Pairs: []ast.HashPair{}}
+		for !p.peekTokenIs(token.RBRACE) {
+			if p.unterminated(hash.Token, p.peekToken) {
+				return nil
+			}
+			p.nextToken()
+			pair, ok := p.parseHashPatternPair()
+			if !ok {
+				return nil
+			}
+			hash.Pairs = append(hash.Pairs, pair)
+
+			if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+		p.nextToken()
+		return hash
+	default:
+		p.addError(p.curToken, "expected a name or pattern to bind, got '%s'", p.curToken.Type)
+		return nil
+	}
+}
+
+// parseHashPatternPair parses `key: pattern` in a hash pattern. The key is a
+// name or a string, and is the pattern too if it's a name on its own, so
+// `{x}` is `{x: x}`.
+func (p *Parser) parseHashPatternPair() (ast.HashPair, bool) {
+	if !p.curToken.Is(token.IDENT, token.STRING) {
+		p.addError(p.curToken, "expected a key to destructure, got '%s'", p.curToken.Type)
+		return ast.HashPair{}, false
+	}
+	pair := ast.HashPair{Key: &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}}
+
+	if p.curTokenIs(token.IDENT) && !p.peekTokenIs(token.COLON) {
+		pair.Value = p.parsePatternElement()
+		return pair, pair.Value != nil
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return ast.HashPair{}, false
+	}
+	p.nextToken()
+	pair.Value = p.parsePatternElement()
+	return pair, pair.Value != nil
+}
+
+// parsePatternElement parses a pattern with an optional `= default`
+func (p *Parser) parsePatternElement() ast.Expression {
+	target := p.parsePattern()
+	if target == nil || !p.peekTokenIs(token.ASSIGN) {
+		return target
+	}
+	p.nextToken()
+
+	dp := &ast.DefaultPattern{Token: p.curToken, Target: target}
+	p.nextToken()
+	if dp.Default = p.parseExpression(LOWEST); dp.Default == nil {
+		return nil
+	}
+	return dp
+}
+
+// parseLetBinding parses the `let x = value` shared by let statements and
+// let-in expressions
+func (p *Parser) parseLetBinding() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -369,7 +890,32 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	p.nextToken()
 
+	noIn := p.noIn
+	p.noIn = true
 	stmt.Value = p.parseExpression(LOWEST)
+	p.noIn = noIn
+
+	// `let add = fn() {}` names the function add, as `fn add() {}` does
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && fn.Name == "" {
+		fn.Name = stmt.Name.Value
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLetInExpression() ast.Expression {
+	binding := p.parseLetBinding()
+	if binding == nil {
+		return nil
+	}
+	return p.parseLetInBody(binding)
+}
+
+// parseLetInStatement finishes a let statement that turned out to be the
+// start of a let-in expression
+func (p *Parser) parseLetInStatement(binding *ast.LetStatement) *ast.ExpressionStatement {
+	stmt := &ast.ExpressionStatement{Token: binding.Token}
+	stmt.Expression = p.parseLetInBody(binding)
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -378,6 +924,21 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parseLetInBody parses `in body` after a binding. The body extends as far
+// as it can, so `let x = 1 in x + 1` is `let x = 1 in (x + 1)`.
+func (p *Parser) parseLetInBody(binding *ast.LetStatement) ast.Expression {
+	exp := &ast.LetInExpression{Token: binding.Token, Name: binding.Name, Value: binding.Value}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+
+	exp.Body = p.parseExpression(LOWEST)
+
+	return exp
+}
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
@@ -392,17 +953,429 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseGoStatement() *ast.GoStatement {
+	stmt := &ast.GoStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	call, ok := p.parseExpression(LOWEST).(*ast.CallExpression)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if !ok {
+		p.addError(stmt.Token, "expected a function call after go")
+		return nil
+	}
+	stmt.Call = call
+
+	return stmt
+}
+
+func (p *Parser) parseTypeAliasStatement() *ast.TypeAliasStatement {
+	stmt := &ast.TypeAliasStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+
+	stmt.Type = p.parseExpression(LOWEST)
+	if stmt.Type == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseTryCatchStatement() *ast.TryCatchStatement {
+	stmt := &ast.TryCatchStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if !p.expectPeek(token.LPAREN) || !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.CatchName = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.addError(p.peekToken, "expected catch or finally after try block, got '%s'", p.peekToken.Type)
+		return nil
+	}
+
+	// Allow an optional semicolon, as parseExpressionStatement does
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseWithStatement() *ast.WithStatement {
+	stmt := &ast.WithStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Init = p.parseExpression(LOWEST)
+	if stmt.Init == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	// Allow an optional semicolon, as parseExpressionStatement does
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	// Allow an optional semicolon, as parseExpressionStatement does
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseSelectStatement() *ast.SelectStatement {
+	stmt := &ast.SelectStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	open := p.curToken
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) {
+		if p.unterminated(open, p.curToken) {
+			return nil
+		}
+		switch p.curToken.Type {
+		case token.CASE:
+			c, ok := p.parseSelectCase()
+			if !ok {
+				return nil
+			}
+			stmt.Cases = append(stmt.Cases, c)
+		case token.DEFAULT:
+			if stmt.Default != nil {
+				p.addError(p.curToken, "select has more than one default")
+				return nil
+			}
+			if !p.expectPeek(token.LBRACE) {
+				return nil
+			}
+			stmt.Default = p.parseBlockStatement()
+		default:
+			p.addError(p.curToken, "expected case or default in select, got '%s'", p.curToken.Type)
+			return nil
+		}
+		p.nextToken()
+	}
+
+	// Allow an optional semicolon, as parseExpressionStatement does
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseSelectCase parses `case (x in ch) { body }`, where `x in` is optional
+func (p *Parser) parseSelectCase() (ast.SelectCase, bool) {
+	c := ast.SelectCase{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return c, false
+	}
+	p.nextToken()
+
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.IN) {
+		c.Var = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.nextToken()
+		p.nextToken()
+	}
+	c.Chan = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return c, false
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return c, false
+	}
+	c.Body = p.parseBlockStatement()
+
+	return c, true
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	defer p.allowIn()()
+
+	exp := &ast.MatchExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	open := p.curToken
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) {
+		if p.unterminated(open, p.curToken) {
+			return nil
+		}
+		switch p.curToken.Type {
+		case token.CASE:
+			arm := ast.MatchArm{Token: p.curToken}
+			p.nextToken()
+			arm.Pattern = p.parseExpression(LOWEST)
+			if p.peekTokenIs(token.IF) {
+				p.nextToken()
+				p.nextToken()
+				arm.Guard = p.parseExpression(LOWEST)
+			}
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			if arm.Body = p.parseSwitchBody(); arm.Body == nil {
+				return nil
+			}
+			exp.Arms = append(exp.Arms, arm)
+		case token.DEFAULT:
+			if exp.Default != nil {
+				p.addError(p.curToken, "match has more than one default")
+				return nil
+			}
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			if exp.Default = p.parseSwitchBody(); exp.Default == nil {
+				return nil
+			}
+		default:
+			p.addError(p.curToken, "expected case or default in match, got '%s'", p.curToken.Type)
+			return nil
+		}
+
+		// Arms are separated by commas, with an optional trailing comma
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		p.nextToken()
+	}
+
+	return exp
+}
+
+func (p *Parser) parseSwitchExpression() ast.Expression {
+	defer p.allowIn()()
+
+	exp := &ast.SwitchExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	open := p.curToken
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) {
+		if p.unterminated(open, p.curToken) {
+			return nil
+		}
+		switch p.curToken.Type {
+		case token.CASE:
+			c := ast.SwitchCase{Token: p.curToken}
+			p.nextToken()
+			c.Value = p.parseExpression(LOWEST)
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			if c.Body = p.parseSwitchBody(); c.Body == nil {
+				return nil
+			}
+			exp.Cases = append(exp.Cases, c)
+		case token.DEFAULT:
+			if exp.Default != nil {
+				p.addError(p.curToken, "switch has more than one default")
+				return nil
+			}
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			if exp.Default = p.parseSwitchBody(); exp.Default == nil {
+				return nil
+			}
+		default:
+			p.addError(p.curToken, "expected case or default in switch, got '%s'", p.curToken.Type)
+			return nil
+		}
+
+		// Cases are separated by commas, with an optional trailing comma
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		p.nextToken()
+	}
+
+	return exp
+}
+
+// parseSwitchBody parses the body after a switch case's or match arm's ':'. A
+// '{' always starts a block, so a hash literal body has to be in parentheses.
+func (p *Parser) parseSwitchBody() ast.Node {
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		return p.parseBlockStatement()
+	}
+	// Don't return a nil ast.Expression as a non-nil ast.Node
+	if exp := p.parseExpression(LOWEST); exp != nil {
+		return exp
+	}
+	return nil
+}
+
+// parseTryExpression parses `try body`. Like a let-in, the body extends as far
+// as it can, so `try x / y` is `try (x / y)`.
+func (p *Parser) parseTryExpression() ast.Expression {
+	exp := &ast.TryExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp.Body = p.parseExpression(LOWEST)
+	if exp.Body == nil {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseSpreadExpression() ast.Expression {
+	exp := &ast.SpreadExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(PREFIX)
+
+	return exp
+}
+
+// docComment is the text of a run of `##` comments, and the first of them
+type docComment struct {
+	text  string
+	first token.Token
+}
+
+// nextToken moves on to the next token, skipping `##` comments. The comments
+// are kept with the token after them, as long as there is no blank line in
+// between, so a let statement can take its documentation.
 func (p *Parser) nextToken() {
-	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.curToken, p.curDoc = p.peekToken, p.peekDoc
+	p.peekToken, p.peekDoc = p.tokens.Consume(), docComment{}
+
+	lines := []string{}
+	for p.peekToken.Type == token.DOC {
+		if len(lines) == 0 || p.peekToken.Line != p.peekDoc.first.Line+len(lines) {
+			// Not a continuation of the comments before it
+			lines, p.peekDoc.first = []string{}, p.peekToken
+		}
+		lines = append(lines, p.peekToken.Literal)
+		p.peekToken = p.tokens.Consume()
+	}
+	if len(lines) > 0 && p.peekToken.Line == p.peekDoc.first.Line+len(lines) {
+		p.peekDoc.text = strings.Join(lines, "\n")
+	} else {
+		p.peekDoc = docComment{}
+	}
+}
+
+// unterminated reports whether `tok` is the end of the input, reached before
+// the '}' closing the `open` brace, adding an error at the brace if it is
+func (p *Parser) unterminated(open, tok token.Token) bool {
+	if !tok.Is(token.EOF) {
+		return false
+	}
+	p.addError(open, "unterminated block")
+	return true
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
-	return p.curToken.Type == t
+	return p.curToken.Is(t)
 }
 
 func (p *Parser) peekTokenIs(t token.TokenType) bool {
-	return p.peekToken.Type == t
+	return p.peekToken.Is(t)
 }
 
 func (p *Parser) expectPeek(t token.TokenType) bool {
@@ -415,18 +1388,10 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
-func (p *Parser) peekPrec() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
-	}
-
-	return LOWEST
+func (p *Parser) peekPrec() Precedence {
+	return PrecedenceOf(p.peekToken)
 }
 
-func (p *Parser) curPrec() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
-		return p
-	}
-
-	return LOWEST
+func (p *Parser) curPrec() Precedence {
+	return PrecedenceOf(p.curToken)
 }
@@ -2,11 +2,15 @@ package parser
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/vishen/go-monkeylang/ast"
 	"github.com/vishen/go-monkeylang/lexer"
-	_ "github.com/vishen/go-monkeylang/token"
+	"github.com/vishen/go-monkeylang/token"
 )
 
 func TestFunctionLiteralParsing(t *testing.T) {
@@ -178,6 +182,126 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello world";`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "hello world" {
+		t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	testInfixExpression(t, indexExp.Index, 1, "+", 1)
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]func(ast.Expression)
+	}{
+		{`{}`, map[string]func(ast.Expression){}},
+		{
+			`{"one": 1, "two": 2, "three": 3}`,
+			map[string]func(ast.Expression){
+				"one":   func(e ast.Expression) { testIntegerLiteral(t, e, 1) },
+				"two":   func(e ast.Expression) { testIntegerLiteral(t, e, 2) },
+				"three": func(e ast.Expression) { testIntegerLiteral(t, e, 3) },
+			},
+		},
+		{
+			`{"one": 0 + 1, "two": 10 - 8}`,
+			map[string]func(ast.Expression){
+				"one": func(e ast.Expression) { testInfixExpression(t, e, 0, "+", 1) },
+				"two": func(e ast.Expression) { testInfixExpression(t, e, 10, "-", 8) },
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+		}
+
+		if len(hash.Pairs) != len(tt.expected) {
+			t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+		}
+
+		for _, pair := range hash.Pairs {
+			literal, ok := pair.Key.(*ast.StringLiteral)
+			if !ok {
+				t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
+				continue
+			}
+
+			testFunc, ok := tt.expected[literal.Value]
+			if !ok {
+				t.Errorf("No test function for key %q found", literal.Value)
+				continue
+			}
+
+			testFunc(pair.Value)
+		}
+	}
+}
+
 func TestIfExpression(t *testing.T) {
 	input := `if (x < y) { x }`
 
@@ -296,6 +420,38 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"-a * b",
 			"((-a) * b)",
 		},
+		{
+			"a + 1 in b == c < d",
+			"(((a + 1) in b) == (c < d))",
+		},
+		{
+			"x not in y * 2",
+			"(x not in (y * 2))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a || b ? x + 1 : y",
+			"((a || b) ? (x + 1) : y)",
+		},
+		{
+			"a || b && c == d",
+			"(a || (b && (c == d)))",
+		},
+		{
+			"a or b and not c",
+			"(a || (b && (!c)))",
+		},
+		{
+			"1..n + 1",
+			"(1..(n + 1))",
+		},
+		{
+			"a[i...len - 1] == b",
+			"((a[(i...(len - 1))]) == b)",
+		},
 		{
 			"!-a",
 			"(!(-a))",
@@ -400,6 +556,18 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"-a.b.c * d.e(f)[0]",
+			"((-((a.b).c)) * ((d.e)(f)[0]))",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.NewLexer(tt.input)
@@ -475,6 +643,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15;", "-", 15},
+		{"not 5;", "!", 5},
 	}
 
 	for _, tt := range prefixTests {
@@ -609,7 +778,7 @@ func TestBooleanExpression(t *testing.T) {
 		t.Fatalf("exp not *ast.Boolean. got=%T", stmt.Expression)
 	}
 	if b.Value != true {
-		t.Errorf("b.Value not %s. got=%s", "true", b.Value)
+		t.Errorf("b.Value not %t. got=%t", true, b.Value)
 	}
 }
 
@@ -644,6 +813,92 @@ let foobar = 838383;
 		}
 	}
 }
+func TestDocComments(t *testing.T) {
+	input := `## Adds one
+## to x.
+let inc = fn(x) { x + 1 };
+
+## Not attached, there is a blank line.
+
+let y = 1;
+## Declarations too.
+fn double(x) { x * 2 }
+## Nor to expressions.
+inc(1);
+let z = 2;
+`
+	p := NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	tests := []struct {
+		index       int
+		expectedDoc string
+	}{
+		{0, "Adds one\nto x."},
+		{1, ""},
+		{2, "Declarations too."},
+		{4, ""},
+	}
+	for _, tt := range tests {
+		stmt, ok := program.Statements[tt.index].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not *ast.LetStatement. got=%T", tt.index, program.Statements[tt.index])
+		}
+		if stmt.Doc != tt.expectedDoc {
+			t.Errorf("wrong doc for %s. expected=%q, got=%q", stmt.Name.Value, tt.expectedDoc, stmt.Doc)
+		}
+		if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && fn.Doc != tt.expectedDoc {
+			t.Errorf("wrong function doc for %s. expected=%q, got=%q", stmt.Name.Value, tt.expectedDoc, fn.Doc)
+		}
+	}
+}
+
+func TestDestructuringStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = xs", "let [a, b] = xs;"},
+		{"let [x = 0, [y, z] = [1, 2],] = point;", "let [x = 0, [y, z] = [1, 2]] = point;"},
+		{"let [] = xs", "let [] = xs;"},
+		{`let {x: a, y, "z": [b], w = 1,} = h`, "let {x:a, y:y, z:[b], w:w = 1} = h;"},
+		{"let {a: {b: c}} = h", "let {a:{b:c}} = h;"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if _, ok := program.Statements[0].(*ast.DestructuringStatement); !ok {
+			t.Fatalf("stmt not *ast.DestructuringStatement. got=%T", program.Statements[0])
+		}
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, 1] = xs", "expected a name or pattern to bind, got 'INT'"},
+		{"let [a b] = xs", "expected next token to be ',', got 'IDENT' instead"},
+		{"let [a] xs", "expected next token to be '=', got 'IDENT' instead"},
+		{"let {1: a} = h", "expected a key to destructure, got 'INT'"},
+		{`let {"x"} = h`, "expected next token to be ':', got '}' instead"},
+		{"let {x: 1} = h", "expected a name or pattern to bind, got 'INT'"},
+	}
+	for _, tt := range errors {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	input := `
 return 5;
@@ -670,6 +925,445 @@ return 993322;
 	}
 }
 
+func TestGoStatement(t *testing.T) {
+	l := lexer.NewLexer(`go worker(ch, 1); go fn() { x }();`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []string{"go worker(ch, 1);", "go fn() x();"}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", len(expected), len(program.Statements))
+	}
+	for i, stmt := range program.Statements {
+		goStmt, ok := stmt.(*ast.GoStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.GoStatement. got=%T", stmt)
+		}
+		if goStmt.String() != expected[i] {
+			t.Errorf("wrong statement. expected=%q, got=%q", expected[i], goStmt.String())
+		}
+	}
+
+	l = lexer.NewLexer(`go x;`)
+	p = NewParser(l)
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0] != "expected a function call after go" {
+		t.Errorf("expected an error for go without a call. got=%q", p.Errors())
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	l := lexer.NewLexer(`for (x in [1, 2]) { puts(x); }`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ForStatement. got=%T", program.Statements[0])
+	}
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+	if stmt.Iterable.String() != "[1, 2]" {
+		t.Errorf("wrong iterable. got=%q", stmt.Iterable.String())
+	}
+	if len(stmt.Body.Statements) != 1 || stmt.Body.String() != "puts(x)" {
+		t.Errorf("wrong body. got=%q", stmt.Body.String())
+	}
+
+	l = lexer.NewLexer(`for (x of y) {}`)
+	p = NewParser(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be 'IN', got 'IDENT' instead" {
+		t.Errorf("expected an error for a missing in. got=%q", p.Errors())
+	}
+}
+
+func TestBlockStatementsTrailingSemicolon(t *testing.T) {
+	tests := []string{
+		"for (n in [1]) { n }; 1",
+		"with f() as x { x }; 1",
+		"try { 1 } catch (err) { 2 }; 1",
+		"try { 1 } finally { 2 }; 1",
+		"select { default { 1 } }; 1",
+	}
+
+	for _, input := range tests {
+		p := NewParser(lexer.NewLexer(input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 2 {
+			t.Errorf("program.Statements does not contain 2 statements for %q. got=%d", input, len(program.Statements))
+		}
+	}
+}
+
+func TestTryCatchStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() } catch (err) { g(err) } finally { h() }", "try f() catch (err) g(err) finally h()"},
+		{"try { f() } catch (err) { g(err) }", "try f() catch (err) g(err)"},
+		{"try { f() } finally { h() }", "try f() finally h()"},
+		{"try {}", ""},
+		{"try { f() } catch { g() }", ""},
+	}
+	errors := map[string]string{
+		"try {}":                    "expected catch or finally after try block, got 'EOF'",
+		"try { f() } catch { g() }": "expected next token to be '(', got '{' instead",
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+
+		if expected, ok := errors[tt.input]; ok {
+			if len(p.Errors()) == 0 || p.Errors()[0] != expected {
+				t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, expected, p.Errors())
+			}
+			continue
+		}
+		checkParserErrors(t, p)
+
+		if _, ok := program.Statements[0].(*ast.TryCatchStatement); !ok {
+			t.Fatalf("stmt not *ast.TryCatchStatement. got=%T", program.Statements[0])
+		}
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestWithStatement(t *testing.T) {
+	l := lexer.NewLexer(`with open("f") as fh { puts(fh); }`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WithStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.WithStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Init.String() != "open(f)" {
+		t.Errorf("wrong init. got=%q", stmt.Init.String())
+	}
+	if !testIdentifier(t, stmt.Name, "fh") {
+		return
+	}
+	if stmt.Body.String() != "puts(fh)" {
+		t.Errorf("wrong body. got=%q", stmt.Body.String())
+	}
+
+	l = lexer.NewLexer(`with open("f") { }`)
+	p = NewParser(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be 'AS', got '{' instead" {
+		t.Errorf("expected an error for a missing as. got=%q", p.Errors())
+	}
+}
+
+func TestTypeAliasStatement(t *testing.T) {
+	l := lexer.NewLexer(`type Vector = [Int];`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.TypeAliasStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.TypeAliasStatement. got=%T", program.Statements[0])
+	}
+	if !testIdentifier(t, stmt.Name, "Vector") {
+		return
+	}
+	if stmt.Type.String() != "[Int]" {
+		t.Errorf("wrong type. got=%q", stmt.Type.String())
+	}
+
+	l = lexer.NewLexer(`type Vector [Int]`)
+	p = NewParser(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be '=', got '[' instead" {
+		t.Errorf("expected an error for a missing =. got=%q", p.Errors())
+	}
+}
+
+func TestFunctionDeclaration(t *testing.T) {
+	l := lexer.NewLexer(`fn add(x, y) { x + y }; fn(x) { x }(1);`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+	if !testIdentifier(t, stmt.Name, "add") {
+		return
+	}
+	fn, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value not *ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+	if fn.Name != "add" || len(fn.Parameters) != 2 {
+		t.Errorf("wrong function. got name=%q, parameters=%v", fn.Name, fn.Parameters)
+	}
+	if stmt.String() != "fn add(x, y) (x + y)" {
+		t.Errorf("wrong string. got=%q", stmt.String())
+	}
+
+	// An anonymous function at the start of a statement is still an expression
+	if _, ok := program.Statements[1].(*ast.ExpressionStatement); !ok {
+		t.Errorf("stmt not *ast.ExpressionStatement. got=%T", program.Statements[1])
+	}
+
+	program = NewParser(lexer.NewLexer(`let add = fn(x, y) { x + y };`)).ParseProgram()
+	let := program.Statements[0].(*ast.LetStatement)
+	if let.Value.(*ast.FunctionLiteral).Name != "add" {
+		t.Errorf("expected let to name the function add. got=%q", let.Value.(*ast.FunctionLiteral).Name)
+	}
+	if let.String() != "let add = fn(x, y) (x + y);" {
+		t.Errorf("wrong string. got=%q", let.String())
+	}
+}
+
+func TestLetInExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5 in x * x", "let x = 5 in (x * x)"},
+		{"let x = 5 in x * x;", "let x = 5 in (x * x)"},
+		{"f(let x = 1 in x, 2)", "f(let x = 1 in x, 2)"},
+		{"let y = let x = 1 in x + 1;", "let y = let x = 1 in (x + 1);"},
+		// A `let` starting a statement is the same let-in as one in a value
+		{"let x = 1 in x + 1", "let x = 1 in (x + 1)"},
+		{"let x = 1 in fn(y) { y }", "let x = 1 in fn(y) y"},
+		{"let a = 1 in let b = 2 in a + b", "let a = 1 in let b = 2 in (a + b)"},
+		// `in` in a let value starts the body, unless it is in brackets
+		{"let found = (x in xs);", "let found = (x in xs);"},
+		{"let found = f(x in xs);", "let found = f((x in xs));"},
+		{"let x = 1 in x in xs", "let x = 1 in (x in xs)"},
+		{"let x = a == b in x", "let x = (a == b) in x"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement for %q. got=%d", tt.input, len(program.Statements))
+		}
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("f(let x = 1)"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be 'IN', got ')' instead" {
+		t.Errorf("expected an error for a let without in. got=%q", p.Errors())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match x { case 1: "one", case [h, ...t]: h, case {"a": a}: a, default: 0, }`
+	p := NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression not *ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, exp.Subject, "x") {
+		return
+	}
+	if len(exp.Arms) != 3 {
+		t.Fatalf("match does not contain 3 arms. got=%d", len(exp.Arms))
+	}
+	if _, ok := exp.Arms[1].Pattern.(*ast.ArrayLiteral).Elements[1].(*ast.SpreadExpression); !ok {
+		t.Errorf("expected a spread in the array pattern. got=%s", exp.Arms[1].Pattern)
+	}
+	if exp.Default == nil {
+		t.Errorf("expected a default")
+	}
+
+	expected := `match x { case 1: one, case [h, ...t]: h, case {a:a}: a, default: 0 }`
+	if exp.String() != expected {
+		t.Errorf("wrong string. expected=%q, got=%q", expected, exp.String())
+	}
+
+	p = NewParser(lexer.NewLexer(`match x { case n if n > 0: n }`))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+	arm := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression).Arms[0]
+	if arm.Guard == nil || arm.Guard.String() != "(n > 0)" {
+		t.Errorf("wrong guard. got=%v", arm.Guard)
+	}
+
+	// As in a switch, braces after the ':' are a block, not a hash literal
+	p = NewParser(lexer.NewLexer(`match x { case 1: {}, default: { x } }`))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+	exp = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	if _, ok := exp.Arms[0].Body.(*ast.BlockStatement); !ok {
+		t.Errorf("expected a block for the arm body. got=%T", exp.Arms[0].Body)
+	}
+	if _, ok := exp.Default.(*ast.BlockStatement); !ok {
+		t.Errorf("expected a block for the default. got=%T", exp.Default)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`match x { case n if : 1 }`, "no prefix parse function for : found"},
+		{`match x { default: 1, default: 2 }`, "match has more than one default"},
+		{`match x { 1: 2 }`, "expected case or default in match, got 'INT'"},
+		{`match x { case 1: 2 case 2: 3 }`, "expected next token to be ',', got 'CASE' instead"},
+	}
+	for _, tt := range errorTests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("expected error %q for %q. got=%q", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try f()", "try f()"},
+		{"try x / y", "try (x / y)"},
+		{"(try x) / y", "(try x / y)"},
+		{"let r = try [1][2];", "let r = try ([1][2]);"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestSwitchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`switch x { case 1: "one", case 2: "two", default: "other" }`, `switch x { case 1: one, case 2: two, default: other }`},
+		{`switch x { case 1 + 1: { puts(x); x }, }`, `switch x { case (1 + 1): { puts(x)x } }`},
+		{`switch x {}`, `switch x {  }`},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.SwitchExpression); !ok {
+			t.Fatalf("exp not *ast.SwitchExpression. got=%T", stmt.Expression)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong switch for %q. expected=%q, got=%q", tt.input, tt.expected, stmt.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`switch x { case 1 "one" }`, "expected next token to be ':', got 'STRING' instead"},
+		{`switch x { default: 1, default: 2 }`, "switch has more than one default"},
+		{`switch x { 1: 2 }`, "expected case or default in switch, got 'INT'"},
+	}
+	for _, tt := range errors {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestSelectStatement(t *testing.T) {
+	l := lexer.NewLexer(`select { case (x in a) { puts(x); } case (b) { 1 } default { 2 } }`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.SelectStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.SelectStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Cases) != 2 {
+		t.Fatalf("select does not contain 2 cases. got=%d", len(stmt.Cases))
+	}
+	if !testIdentifier(t, stmt.Cases[0].Var, "x") || !testIdentifier(t, stmt.Cases[0].Chan, "a") {
+		return
+	}
+	if stmt.Cases[1].Var != nil {
+		t.Errorf("expected no variable for the second case. got=%s", stmt.Cases[1].Var)
+	}
+	if stmt.Default == nil || stmt.Default.String() != "2" {
+		t.Errorf("wrong default. got=%v", stmt.Default)
+	}
+
+	expected := "select { case (x in a) puts(x) case (b) 1 default 2 }"
+	if stmt.String() != expected {
+		t.Errorf("wrong string. expected=%q, got=%q", expected, stmt.String())
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`select { default { 1 } default { 2 } }`, "select has more than one default"},
+		{`select { 1 }`, "expected case or default in select, got 'INT'"},
+	}
+	for _, tt := range errorTests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("expected error %q for %q. got=%q", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
@@ -795,3 +1489,243 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	t.FailNow()
 
 }
+
+func TestParseErrorPositions(t *testing.T) {
+	input := `let x 5;
+let = 10;`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	p.ParseProgram()
+
+	expected := []ParseError{
+		{"expected next token to be '=', got 'INT' instead", 1, 7},
+		{"expected next token to be 'IDENT', got '=' instead", 2, 5},
+		{"no prefix parse function for = found", 2, 5},
+	}
+
+	errors := p.ParseErrors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%+v)", len(expected), len(errors), errors)
+	}
+	for i, err := range expected {
+		if errors[i] != err {
+			t.Errorf("errors[%d] wrong. expected=%+v, got=%+v", i, err, errors[i])
+		}
+		if p.Errors()[i] != err.Message {
+			t.Errorf("Errors()[%d] wrong. expected=%q, got=%q", i, err.Message, p.Errors()[i])
+		}
+	}
+}
+
+// Each of these used to loop forever at the end of the input
+func TestUnterminatedBlocks(t *testing.T) {
+	tests := []struct {
+		input string
+		line  int
+		col   int
+	}{
+		{"let f = fn(x) { x", 1, 15},
+		{"let f = fn(x) { x + }", 1, 15},
+		{"if (x) {\n  1", 1, 8},
+		{"select {", 1, 8},
+		{"match x {", 1, 9},
+		{"switch x { case 1: 2,", 1, 10},
+		{"{1: 2,", 1, 1},
+		{"let {a, ", 1, 5},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.ParseProgram()
+
+		found := false
+		for _, err := range p.ParseErrors() {
+			if err.Message == "unterminated block" && err.Line == tt.line && err.Col == tt.col {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected an unterminated block error at %d:%d for %q. got=%v", tt.line, tt.col, tt.input, p.ParseErrors())
+		}
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []ParseError
+	}{
+		{"let x = 1;\n/* never closed", []ParseError{{"unterminated block comment", 2, 1}}},
+		{"let x = 1 /* never closed", []ParseError{{"unterminated block comment", 1, 11}}},
+		{"puts(1); /* a /* nested */ comment", []ParseError{{"unterminated block comment", 1, 10}}},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong errors for %q. expected=%+v, got=%+v", tt.input, tt.expected, errors)
+			continue
+		}
+		for i, err := range tt.expected {
+			if errors[i] != err {
+				t.Errorf("errors[%d] wrong for %q. expected=%+v, got=%+v", i, tt.input, err, errors[i])
+			}
+		}
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{"1 + 2 * 3", "(1 + (2 * 3))", ""},
+		{"fn(x) { x };", "fn(x) x", ""},
+		{"let x = 1;", "", "expected an expression, got a let statement"},
+		{"1; 2", "", "expected a single expression, got 2 statements"},
+		{"", "", "expected a single expression, got 0 statements"},
+		{"1 +", "", "1:4: no prefix parse function for EOF found"},
+	}
+
+	for _, tt := range tests {
+		exp, err := ParseExpression(tt.input)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("wrong error for %q. expected=%q, got=%v", tt.input, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", tt.input, err)
+			continue
+		}
+		if exp.String() != tt.expected {
+			t.Errorf("wrong expression for %q. expected=%q, got=%q", tt.input, tt.expected, exp.String())
+		}
+	}
+
+	_, err := ParseExpression("let = 1")
+	if list, ok := err.(ErrorList); !ok || len(list) != 2 || list[0].Line != 1 || list[0].Col != 5 {
+		t.Errorf("expected an ErrorList with positions, got=%#v", err)
+	}
+}
+
+func TestPrecedenceOf(t *testing.T) {
+	tests := []struct {
+		tok      token.Token
+		expected Precedence
+	}{
+		{token.Token{Type: token.EQUALS, Literal: "=="}, EQUALS},
+		{token.Token{Type: token.LT, Literal: "<"}, LESSGREATER},
+		{token.Token{Type: token.PLUS, Literal: "+"}, SUM},
+		{token.Token{Type: token.ASTERISK, Literal: "*"}, PRODUCT},
+		{token.Token{Type: token.LPAREN, Literal: "("}, CALL},
+		{token.Token{Type: token.AND, Literal: "and"}, LOGICAL_AND},
+		{token.Token{Type: token.IDENT, Literal: "x"}, LOWEST},
+		{token.Token{Type: token.SEMICOLON, Literal: ";"}, LOWEST},
+	}
+
+	for _, tt := range tests {
+		if got := PrecedenceOf(tt.tok); got != tt.expected {
+			t.Errorf("wrong precedence for %q. expected=%d, got=%d", tt.tok.Literal, tt.expected, got)
+		}
+	}
+	if !(LOWEST < EQUALS && EQUALS < LESSGREATER && LESSGREATER < SUM && SUM < PRODUCT && PRODUCT < PREFIX && PREFIX < CALL) {
+		t.Errorf("precedences out of order")
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "good.mky")
+	bad := filepath.Join(dir, "bad.mky")
+	if err := ioutil.WriteFile(good, []byte("let x = 1 + 2;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(bad, []byte("let = 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	program, errs := ParseFile(good)
+	if len(errs) != 0 || program.String() != "let x = (1 + 2);" {
+		t.Errorf("wrong result for good file. got=%q, errors=%q", program, errs)
+	}
+
+	_, errs = ParseFile(bad)
+	if len(errs) == 0 || errs[0] != bad+":1:5: expected next token to be 'IDENT', got '=' instead" {
+		t.Errorf("wrong errors for bad file. got=%q", errs)
+	}
+
+	program, errs = ParseFile(filepath.Join(dir, "missing.mky"))
+	if program != nil || len(errs) != 1 || !strings.Contains(errs[0], "missing.mky") {
+		t.Errorf("wrong result for missing file. got=%v, errors=%q", program, errs)
+	}
+
+	program, errs = ParseString("1 +")
+	if len(errs) != 1 || errs[0] != "no prefix parse function for EOF found" || program == nil {
+		t.Errorf("wrong errors for ParseString. got=%q", errs)
+	}
+}
+
+func TestIncrementalParser(t *testing.T) {
+	ip := NewIncrementalParser()
+
+	update := func(src string) *ast.Program {
+		program, err := ip.Update(src)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", src, err)
+		}
+
+		p := NewParser(lexer.NewLexer(src))
+		expected := p.ParseProgram()
+		if program.String() != expected.String() {
+			t.Errorf("wrong program for %q. expected=%q, got=%q", src, expected.String(), program.String())
+		}
+		return program
+	}
+
+	first := update("let a = 1;\nlet b = 2;\nlet c = 3;\n")
+
+	// Only the edited statement is parsed again
+	second := update("let a = 1;\nlet b = 20;\nlet c = 3;\n")
+	if second.Statements[0] != first.Statements[0] || second.Statements[2] != first.Statements[2] {
+		t.Errorf("statements around the edit should be reused")
+	}
+	if second.Statements[1] == first.Statements[1] {
+		t.Errorf("edited statement should be parsed again")
+	}
+
+	// Adding a line moves the statements after it, so they are parsed again
+	// to get their positions right
+	third := update("let a = 1;\nlet b = 20;\nlet x = 9;\nlet c = 3;\n")
+	if third.Statements[1] != second.Statements[1] {
+		t.Errorf("statement before the edit should be reused")
+	}
+	if third.Statements[3] == second.Statements[2] {
+		t.Errorf("statement after an added line should be parsed again")
+	}
+	if c := third.Statements[3].(*ast.LetStatement); c.Token.Line != 4 {
+		t.Errorf("wrong line for moved statement. expected=4, got=%d", c.Token.Line)
+	}
+
+	// Without a ';' the next statement carries on the edited one
+	update("x;\n-1;\n")
+	if program := update("y\n-1;\n"); len(program.Statements) != 1 {
+		t.Errorf("expected the statements to be joined, got=%q", program.String())
+	}
+
+	if _, err := ip.Update("let = 1;"); err == nil {
+		t.Errorf("expected an error for a parse error")
+	}
+	update("let a = 1;")
+}
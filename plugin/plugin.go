@@ -0,0 +1,62 @@
+// Package plugin lets other Go packages add built-ins and modules to Monkey
+// without changing the interpreter. The usual pattern is to register them
+// from an init function in the plugin's own package:
+//
+//	package strings
+//
+//	func init() {
+//		plugin.Register("upper", func(args ...object.Object) object.Object {
+//			...
+//		})
+//	}
+//
+// and to import that package for its side effects from the program that runs
+// the interpreter. Registrations are visible to every evaluator, including
+// ones that were created before the plugin registered.
+package plugin
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/vishen/go-monkeylang/eval"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+var (
+	mu    sync.Mutex
+	names = map[string]bool{}
+)
+
+// Register adds `fn` to the built-ins as `name`
+func Register(name string, fn object.BuiltinFunction) {
+	eval.RegisterBuiltin(name, fn)
+	record(name)
+}
+
+// Module registers `bindings` as a module that Monkey programs can load with
+// `import(name)` and use as `module.binding`
+func Module(name string, bindings map[string]object.Object) {
+	eval.RegisterModule(name, bindings)
+	record(name)
+}
+
+// List returns the names of every built-in and module registered by plugins
+func List() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func record(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names[name] = true
+}
@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/vishen/go-monkeylang/interpreter"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+func init() {
+	Register("triple", func(args ...object.Object) object.Object {
+		return object.NewInteger(args[0].(*object.Integer).Value * 3)
+	})
+
+	Module("geometry", map[string]object.Object{
+		"sides": object.NewInteger(4),
+		"area": &object.Builtin{Name: "area", Fn: func(args ...object.Object) object.Object {
+			return object.NewInteger(args[0].(*object.Integer).Value * args[1].(*object.Integer).Value)
+		}},
+	})
+}
+
+func TestPlugins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"triple(4)", "12"},
+		{`let g = import("geometry"); g.area(g.sides, 5)`, "20"},
+		{`import("geometry")`, "module geometry"},
+		{`import("missing")`, "module not found: missing"},
+		{`import("geometry").volume`, "module geometry has no member volume"},
+	}
+
+	for _, tt := range tests {
+		result, err := interpreter.New().Eval(tt.input)
+		got := fmt.Sprint(err)
+		if err == nil {
+			got = result.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	if fmt.Sprint(List()) != "[geometry triple]" {
+		t.Errorf("wrong plugin list. got=%v", List())
+	}
+}
+
+func TestConcurrentRegistration(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := "plugin" + string(rune('a'+i))
+			Register(name, func(args ...object.Object) object.Object { return object.NewInteger(int64(i)) })
+			if _, err := interpreter.New().Eval(name + "()"); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,133 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/vishen/go-monkeylang/interpreter"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// session is the state of a running REPL
+type session struct {
+	out    io.Writer
+	interp *interpreter.Monkey
+
+	// The file given to the last :load, and the environment it was loaded
+	// on top of
+	loaded     string
+	beforeLoad *object.Environment
+}
+
+// Commands the REPL understands, typed with a leading ':'
+var commands = map[string]func(s *session, arg string){
+	"help":   (*session).help,
+	"load":   (*session).load,
+	"reload": (*session).reload,
+	"env":    (*session).showEnv,
+	"reset":  (*session).reset,
+}
+
+// command runs a line starting with ':'
+func (s *session) command(line string) {
+	name, arg := strings.TrimPrefix(line, ":"), ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, arg = name[:i], strings.TrimSpace(name[i:])
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		names := []string{}
+		for n := range commands {
+			names = append(names, n)
+		}
+		fmt.Fprintf(s.out, "unknown command :%s%s\n", name, suggest(name, names, ":"))
+		return
+	}
+	cmd(s, arg)
+}
+
+// load evaluates the file at `path`, binding its top level names in the
+// session. The names are bound in a new environment enclosing the current one,
+// so :reload can start again from the environment the file was loaded into.
+func (s *session) load(path string) {
+	if path == "" {
+		io.WriteString(s.out, "usage: :load <path>\n")
+		return
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+
+	s.loaded, s.beforeLoad = path, s.interp.Environment()
+	s.interp.SetEnvironment(object.NewEnclosedEnvironment(s.beforeLoad))
+	Run(s.out, s.interp, string(src), false)
+}
+
+// reload evaluates the last loaded file again. Anything bound since it was
+// loaded is forgotten, including names the file no longer defines.
+func (s *session) reload(string) {
+	if s.loaded == "" {
+		io.WriteString(s.out, "no file has been loaded, use :load <path>\n")
+		return
+	}
+
+	src, err := ioutil.ReadFile(s.loaded)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+
+	s.interp.SetEnvironment(object.NewEnclosedEnvironment(s.beforeLoad))
+	Run(s.out, s.interp, string(src), false)
+}
+
+// showEnv lists the names bound in the session and the types of their values,
+// or with a name, shows that name's value in full
+func (s *session) showEnv(name string) {
+	env := s.interp.Environment()
+	if name != "" {
+		value, ok := env.Get(name)
+		if !ok {
+			fmt.Fprintf(s.out, "%s is not bound\n", name)
+			return
+		}
+		io.WriteString(s.out, object.PrettyInspect(value, 0)+"\n")
+		return
+	}
+
+	// A loaded file's names are in an environment enclosing the one from
+	// before it was loaded, so list the names from every scope
+	seen := map[string]bool{}
+	for scope := env; scope != nil; scope = scope.Outer() {
+		for _, key := range scope.Keys() {
+			seen[key] = true
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, _ := env.Get(key)
+		fmt.Fprintf(s.out, "%s: %s\n", key, value.Type())
+	}
+}
+
+// reset forgets every name bound in the session, leaving only the built-ins
+func (s *session) reset(string) {
+	for env := s.interp.Environment(); env != nil; env = env.Outer() {
+		for _, key := range env.Keys() {
+			env.Delete(key)
+		}
+	}
+	s.loaded, s.beforeLoad = "", nil
+}
@@ -0,0 +1,202 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// BuiltinDoc describes a built-in for `:help`
+type BuiltinDoc struct {
+	Signature   string
+	Description string
+}
+
+// BuiltinDocs documents every built-in in eval/builtins.go. Add new built-ins
+// here and to a category in helpCategories.
+var BuiltinDocs = map[string]BuiltinDoc{
+	"puts":     {"puts(args...)", "Writes each argument to stdout on its own line."},
+	"printErr": {"printErr(args...)", "Writes each argument to stderr on its own line."},
+	"read":     {"read(prompt)", "Writes the optional prompt, then returns the next line of stdin without surrounding whitespace, or null at the end of the input."},
+	"debug":    {"debug(fn, args...)", "Calls fn with args, pausing before each statement to take debugger commands from stdin."},
+
+	"contains":   {"contains(s, sub)", "Reports whether sub is in the string s."},
+	"startsWith": {"startsWith(s, prefix)", "Reports whether the string s begins with prefix."},
+	"endsWith":   {"endsWith(s, suffix)", "Reports whether the string s ends with suffix."},
+	"indexOf":    {"indexOf(s, sub)", "Returns the byte offset of the first sub in s, or -1."},
+	"repeat":     {"repeat(val, n)", "Returns a new string or array holding val repeated n times."},
+
+	"flatten":   {"flatten(arr, depth)", "Replaces nested arrays with their elements, at most depth levels deep if a depth is given."},
+	"chunk":     {"chunk(arr, size)", "Splits arr into arrays of size elements, the last holding what's left over."},
+	"take":      {"take(arr, n)", "Returns the first n elements of arr."},
+	"drop":      {"drop(arr, n)", "Returns the elements of arr after the first n."},
+	"takeWhile": {"takeWhile(arr, pred)", "Returns the elements at the front of arr that pred is truthy for."},
+	"dropWhile": {"dropWhile(arr, pred)", "Returns the elements of arr from the first that pred is falsy for."},
+	"unique":    {"unique(arr)", "Returns arr without the elements equal to an earlier one."},
+	"uniqueBy":  {"uniqueBy(arr, fn)", "Returns arr without the elements fn gives the same key as an earlier one."},
+	"zip":       {"zip(a, b, ...)", "Pairs up the elements of its arguments, stopping at the end of the shortest."},
+	"unzip":     {"unzip(arrays)", "The reverse of zip."},
+	"groupBy":   {"groupBy(arr, fn)", "Returns a hash from each result of fn to the elements that gave it."},
+	"countBy":   {"countBy(arr, fn)", "Returns a hash from each result of fn to the number of elements that gave it."},
+	"any":       {"any(arr, pred)", "Reports whether pred is truthy for some element of arr."},
+	"all":       {"all(arr, pred)", "Reports whether pred is truthy for every element of arr."},
+	"none":      {"none(arr, pred)", "Reports whether pred is falsy for every element of arr."},
+	"count":     {"count(arr, pred)", "Returns the number of elements pred is truthy for, or every element without a pred."},
+
+	"mapKeys":   {"mapKeys(hash, fn)", "Returns a new hash with each key replaced by fn(key)."},
+	"mapValues": {"mapValues(hash, fn)", "Returns a new hash with each value replaced by fn(value)."},
+	"mergeHash": {"mergeHash(h1, h2, ...)", "Returns a new hash with the pairs of every argument, later ones winning."},
+	"toPairs":   {"toPairs(hash)", "Returns the [key, value] pairs of hash."},
+	"fromPairs": {"fromPairs(arr)", "Returns a hash from an array of [key, value] pairs."},
+
+	"clone":      {"clone(val)", "Returns a deep copy of arrays and hashes."},
+	"freeze":     {"freeze(obj)", "Makes an array or hash, and everything nested in it, immutable."},
+	"isFrozen":   {"isFrozen(obj)", "Returns false for arrays and hashes that can still be changed."},
+	"weakRef":    {"weakRef(obj)", "Returns a reference to obj that doesn't keep it alive."},
+	"deref":      {"deref(wref)", "Returns the referenced object, or null if it has been collected."},
+	"hash":       {"hash(val)", "Returns an integer hash of val. Equal values hash the same."},
+	"comparable": {"comparable(a, b)", "Returns -1, 0 or 1 when a is less than, equal to or greater than b."},
+	"source":     {"source(fn)", "Returns the formatted source of fn, including its ## doc comment."},
+
+	"stackTrace": {"stackTrace(err)", "Returns the calls that led to err, an error caught by try, as an array of hashes."},
+	"__line__":   {"__line__()", "Returns the line it was called on."},
+	"__col__":    {"__col__()", "Returns the column it was called at."},
+
+	"sleep":    {"sleep(ms)", "Pauses for ms milliseconds."},
+	"chan":     {"chan(size)", "Returns a new channel, unbuffered unless a size is given."},
+	"send":     {"send(ch, val)", "Blocks until val has been sent on ch."},
+	"recv":     {"recv(ch)", "Blocks until a value is received from ch, returning null once it's closed."},
+	"close":    {"close(ch)", "Closes ch, ending any for loops reading from it."},
+	"wg":       {"wg()", "Returns a new wait group."},
+	"wgAdd":    {"wgAdd(wg, n)", "Adds n to the number of goroutines wg waits for."},
+	"wgDone":   {"wgDone(wg)", "Marks one goroutine as finished."},
+	"wgWait":   {"wgWait(wg)", "Blocks until every goroutine added to wg is done."},
+	"mutex":    {"mutex()", "Returns a new, unlocked mutex."},
+	"lock":     {"lock(m)", "Blocks until m is locked by the calling goroutine."},
+	"unlock":   {"unlock(m)", "Unlocks m."},
+	"withLock": {"withLock(m, fn)", "Calls fn while holding m."},
+	"spawn":    {"spawn(fn)", "Calls fn in a new goroutine, returning a future for its result."},
+	"await":    {"await(future)", "Blocks until the future's function returns, then returns its result. Errors are returned as errors of await, and every await of a future gets the same result."},
+	"isReady":  {"isReady(future)", "Reports whether the future's function has returned, without waiting for it."},
+
+	"semaphore":     {"semaphore(n)", "Returns a new semaphore that at most n goroutines can hold at once."},
+	"acquire":       {"acquire(sem)", "Blocks until sem has a free slot, then takes it."},
+	"release":       {"release(sem)", "Frees a slot taken by acquire."},
+	"withSemaphore": {"withSemaphore(sem, fn)", "Calls fn while holding a slot of sem."},
+
+	"observable":  {"observable(producer)", "Returns an observable that calls producer with a next function for each subscription. Each call to next emits a value, and returns an error once the subscription has stopped."},
+	"subscribe":   {"subscribe(obs, handler)", "Runs obs in a new goroutine, calling handler with each value it emits. Returns the subscription."},
+	"unsubscribe": {"unsubscribe(sub)", "Stops the subscription, so its handler gets no more values."},
+	"map":         {"map(obs, fn)", "Returns an observable of fn(value) for each value of obs."},
+	"filter":      {"filter(obs, pred)", "Returns an observable of the values of obs that pred is truthy for."},
+
+	"import": {"import(name)", "Returns the module registered as name."},
+}
+
+var helpCategories = []struct {
+	name     string
+	builtins []string
+}{
+	{"Input and output", []string{"puts", "printErr", "read", "debug"}},
+	{"Strings", []string{"contains", "startsWith", "endsWith", "indexOf", "repeat"}},
+	{"Arrays", []string{
+		"flatten", "chunk", "take", "drop", "takeWhile", "dropWhile", "unique", "uniqueBy",
+		"zip", "unzip", "groupBy", "countBy", "any", "all", "none", "count",
+	}},
+	{"Hashes", []string{"mapKeys", "mapValues", "mergeHash", "toPairs", "fromPairs"}},
+	{"Values", []string{"clone", "freeze", "isFrozen", "weakRef", "deref", "hash", "comparable", "source"}},
+	{"Errors", []string{"stackTrace", "__line__", "__col__"}},
+	{"Concurrency", []string{
+		"sleep", "chan", "send", "recv", "close", "wg", "wgAdd", "wgDone", "wgWait",
+		"mutex", "lock", "unlock", "withLock", "spawn", "await", "isReady",
+		"semaphore", "acquire", "release", "withSemaphore",
+		"observable", "subscribe", "unsubscribe", "map", "filter",
+	}},
+	{"Modules", []string{"import"}},
+}
+
+// help lists the built-ins, or describes the one named `arg`
+func (s *session) help(arg string) {
+	out := s.out
+
+	if arg != "" {
+		doc, ok := BuiltinDocs[arg]
+		if !ok {
+			names := []string{}
+			for n := range BuiltinDocs {
+				names = append(names, n)
+			}
+			fmt.Fprintf(out, "no built-in called %s%s\n", arg, suggest(arg, names, ""))
+			return
+		}
+		fmt.Fprintf(out, "%s\n    %s\n", doc.Signature, doc.Description)
+		return
+	}
+
+	for _, category := range helpCategories {
+		fmt.Fprintf(out, "%s:\n", category.name)
+		for _, name := range category.builtins {
+			fmt.Fprintf(out, "  %-26s %s\n", BuiltinDocs[name].Signature, firstSentence(BuiltinDocs[name].Description))
+		}
+	}
+	io.WriteString(out, "Type :help <name> for more about a built-in.\n")
+}
+
+func firstSentence(s string) string {
+	if i := strings.Index(s, ". "); i >= 0 {
+		return s[:i+1]
+	}
+	return s
+}
+
+// suggest returns ", did you mean <prefix><name>?" for the closest of `names`
+// to `s`, or "" if none of them are close
+func suggest(s string, names []string, prefix string) string {
+	sort.Strings(names) // So ties always pick the same name
+
+	best, bestDistance := "", len(s)/2+1
+	for _, name := range names {
+		if d := levenshtein(s, name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %s%s?", prefix, best)
+}
+
+// levenshtein returns the number of single character insertions, deletions
+// and substitutions needed to turn `a` into `b`
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,156 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	astjson "github.com/vishen/go-monkeylang/ast/json"
+	"github.com/vishen/go-monkeylang/interpreter"
+	"github.com/vishen/go-monkeylang/object"
+)
+
+// StartJSON runs the REPL for other programs to drive. There is no prompt, and
+// the result of each line is written as a single line of JSON, see RunJSON.
+// Commands such as :help are written as they are in the REPL.
+func StartJSON(in io.Reader, out io.Writer, interp *interpreter.Monkey) {
+	scanner := bufio.NewScanner(in)
+	s := &session{out: out, interp: interp}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, ":") {
+			s.command(line)
+			continue
+		}
+
+		RunJSON(out, interp, line)
+	}
+}
+
+// RunJSON evaluates `src` with `interp`, writing `{"ok": true, "value": ...}` with
+// the result, or `{"ok": false, "error": "..."}` for parse and runtime errors,
+// to `out` on one line. Statements without a value, such as `let`, give null.
+//
+// Integers, strings, booleans and null are written as themselves, arrays as
+// arrays and hashes as objects, keyed by the Inspect() of keys that aren't
+// strings. Functions are written as {"function": ...}, holding their literal
+// in the format of the ast/json package. Anything else is written as its
+// Inspect() string.
+func RunJSON(out io.Writer, interp *interpreter.Monkey, src string) {
+	evaluated, err := interp.Run(src)
+	switch err := err.(type) {
+	case nil:
+	case *interpreter.ParseError:
+		writeJSONResult(out, nil, strings.Join(err.Errors, "\n"))
+		return
+	default:
+		writeJSONResult(out, nil, err.Error())
+		return
+	}
+	if evaluated == nil {
+		evaluated = object.NullValue
+	}
+
+	value, err := jsonValue(evaluated, map[object.Object]bool{})
+	if err != nil {
+		writeJSONResult(out, nil, err.Error())
+		return
+	}
+	writeJSONResult(out, value, "")
+}
+
+// The results are structs rather than maps so "ok" is always written first
+type jsonValueResult struct {
+	OK    bool        `json:"ok"`
+	Value interface{} `json:"value"`
+}
+
+type jsonErrorResult struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+func writeJSONResult(out io.Writer, value interface{}, errMsg string) {
+	var result interface{} = jsonValueResult{OK: true, Value: value}
+	if errMsg != "" {
+		result = jsonErrorResult{Error: errMsg}
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		// Only reachable if a value can't be encoded, report that instead
+		body, _ = json.Marshal(jsonErrorResult{Error: err.Error()})
+	}
+	out.Write(append(body, '\n'))
+}
+
+// jsonValue converts `obj` to a value encoding/json can write. `seen` holds
+// the arrays and hashes being converted, to catch ones that contain themselves.
+func jsonValue(obj object.Object, seen map[object.Object]bool) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Null:
+		return nil, nil
+	case *object.Array:
+		if seen[obj] {
+			return nil, errors.New("cannot encode an array that contains itself")
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		elements := []interface{}{}
+		for _, el := range obj.Elements {
+			v, err := jsonValue(el, seen)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, v)
+		}
+		return elements, nil
+	case *object.Hash:
+		if seen[obj] {
+			return nil, errors.New("cannot encode a hash that contains itself")
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		pairs := map[string]interface{}{}
+		for _, pair := range obj.Entries() {
+			key := pair.Key.Inspect()
+			if s, ok := pair.Key.(*object.String); ok {
+				key = s.Value
+			}
+			v, err := jsonValue(pair.Value, seen)
+			if err != nil {
+				return nil, err
+			}
+			pairs[key] = v
+		}
+		return pairs, nil
+	case *object.Function:
+		if obj.Literal == nil {
+			return obj.Inspect(), nil
+		}
+		body, err := astjson.Marshal(obj.Literal)
+		if err != nil {
+			return nil, err
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, body); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"function": json.RawMessage(compact.Bytes())}, nil
+	default:
+		return obj.Inspect(), nil
+	}
+}
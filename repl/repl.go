@@ -4,47 +4,84 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/vishen/go-monkeylang/eval"
-	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/interpreter"
 	"github.com/vishen/go-monkeylang/object"
 	"github.com/vishen/go-monkeylang/parser"
 )
 
 const PROMPT = ">> "
 
-func Start(in io.Reader, out io.Writer) {
+func Start(in io.Reader, out io.Writer, opts ...eval.Option) {
+	StartWith(in, out, interpreter.New(interpreter.WithEvalOptions(opts...)), "")
+}
+
+// StartWith runs the REPL with `interp`, such as the one a file was just run
+// with, so its bindings can be inspected. When `name` isn't empty it is shown
+// in the prompt.
+func StartWith(in io.Reader, out io.Writer, interp *interpreter.Monkey, name string) {
 	scanner := bufio.NewScanner(in)
 
-	// Keep the environment around so we can use variables
-	env := object.NewEnvironment()
+	prompt := PROMPT
+	if name != "" {
+		prompt = name + " " + PROMPT
+	}
+
+	// Keep the interpreter around so we can use variables
+	s := &session{out: out, interp: interp}
 
 	for {
-		fmt.Printf(PROMPT)
+		fmt.Fprint(out, prompt)
 		scanned := scanner.Scan()
 		if !scanned {
 			return
 		}
 		line := scanner.Text()
 
-		l := lexer.NewLexer(line)
-		p := parser.NewParser(l)
-
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		if strings.HasPrefix(line, ":") {
+			s.command(line)
 			continue
 		}
 
+		Run(out, s.interp, line, true)
+	}
+}
+
+// Run evaluates `src` with `interp`, writing the result or any errors to `out`
+// the same way the REPL does. With `debug` set the parsed program is written
+// first.
+func Run(out io.Writer, interp *interpreter.Monkey, src string, debug bool) {
+	program, errs := parser.ParseString(src)
+	if len(errs) != 0 {
+		printParserErrors(out, errs)
+		return
+	}
+
+	if debug {
 		io.WriteString(out, "[DEBUG] ")
 		io.WriteString(out, program.String())
 		io.WriteString(out, "\n")
+	}
 
-		evaluated := eval.Eval(program, env)
-		if evaluated != nil {
+	evaluated, err := interp.EvalProgram(program)
+	if runtimeErr, ok := err.(*interpreter.RuntimeError); ok {
+		io.WriteString(out, runtimeErr.Err.Inspect())
+		for _, frame := range runtimeErr.Err.StackTrace {
+			io.WriteString(out, "\n\tat "+frame.String())
+		}
+		io.WriteString(out, "\n")
+		return
+	}
+	if evaluated != nil {
+		switch evaluated := evaluated.(type) {
+		case *object.Array, *object.Hash:
+			io.WriteString(out, object.PrettyInspect(evaluated, 0))
+		default:
 			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
 		}
+		io.WriteString(out, "\n")
 	}
 }
 
@@ -0,0 +1,219 @@
+package repl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vishen/go-monkeylang/eval"
+	"github.com/vishen/go-monkeylang/interpreter"
+	"github.com/vishen/go-monkeylang/lexer"
+	"github.com/vishen/go-monkeylang/object"
+	"github.com/vishen/go-monkeylang/parser"
+)
+
+func TestBuiltinDocs(t *testing.T) {
+	categorised := map[string]bool{}
+	for _, category := range helpCategories {
+		for _, name := range category.builtins {
+			if _, ok := BuiltinDocs[name]; !ok {
+				t.Errorf("%s is in category %s but isn't documented", name, category.name)
+			}
+			categorised[name] = true
+		}
+	}
+
+	for name, doc := range BuiltinDocs {
+		if !categorised[name] {
+			t.Errorf("%s isn't in a category", name)
+		}
+		if !strings.HasPrefix(doc.Signature, name+"(") {
+			t.Errorf("signature for %s doesn't start with its name: %s", name, doc.Signature)
+		}
+
+		program := parser.NewParser(lexer.NewLexer(name)).ParseProgram()
+		if _, ok := eval.Eval(program, object.NewEnvironment()).(*object.Builtin); !ok {
+			t.Errorf("%s is documented but isn't a built-in", name)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":help repeat", "repeat(val, n)\n    Returns a new string or array holding val repeated n times.\n"},
+		{":help  zip ", "zip(a, b, ...)\n    Pairs up the elements of its arguments, stopping at the end of the shortest.\n"},
+		{":help repaet", "no built-in called repaet, did you mean repeat?\n"},
+		{":help xyzzy", "no built-in called xyzzy\n"},
+		{":hepl", "unknown command :hepl, did you mean :help?\n"},
+		{":quit", "unknown command :quit\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		(&session{out: &out}).command(tt.input)
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+
+	var out bytes.Buffer
+	(&session{out: &out}).command(":help")
+	for _, want := range []string{"Arrays:\n", "  takeWhile(arr, pred)", "Type :help <name>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf(":help output doesn't contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestLoadAndReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "lib.mky")
+
+	write := func(src string) {
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	s := &session{out: &out, interp: interpreter.New()}
+	run := func(line string) string {
+		out.Reset()
+		if strings.HasPrefix(line, ":") {
+			s.command(line)
+		} else {
+			Run(&out, s.interp, line, false)
+		}
+		return out.String()
+	}
+
+	if got := run(":reload"); got != "no file has been loaded, use :load <path>\n" {
+		t.Errorf("wrong output for :reload before :load. got=%q", got)
+	}
+
+	run("let base = 1;")
+	write("let double = fn(x) { x * 2 }; let old = 1;")
+	run(":load " + path)
+	if got := run("double(base)"); got != "2\n" {
+		t.Errorf("loaded function not bound. got=%q", got)
+	}
+
+	run("let scratch = 5;")
+	write("let double = fn(x) { x * 20 };")
+	run(":reload")
+	if got := run("double(base)"); got != "20\n" {
+		t.Errorf("reloaded function not bound. got=%q", got)
+	}
+	for _, name := range []string{"old", "scratch"} {
+		if got := run(name); got != "ERROR: identifier not found: "+name+"\n" {
+			t.Errorf("%s should be gone after :reload. got=%q", name, got)
+		}
+	}
+
+	if got := run(":load " + filepath.Join(dir, "missing.mky")); !strings.Contains(got, "no such file") {
+		t.Errorf("expected an error loading a missing file. got=%q", got)
+	}
+}
+
+func TestEnvAndReset(t *testing.T) {
+	var out bytes.Buffer
+	s := &session{out: &out, interp: interpreter.New()}
+	Run(&out, s.interp, `let b = [1, 2]; let a = "x"; let f = fn() { a };`, false)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":env", "a: STRING\nb: ARRAY\nf: FUNCTION\n"},
+		{":env b", "[\n  1,\n  2\n]\n"},
+		{":env puts", "puts is not bound\n"},
+		{":reset", ""},
+		{":env", ""},
+	}
+
+	for _, tt := range tests {
+		out.Reset()
+		s.command(tt.input)
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+}
+
+func TestStartWith(t *testing.T) {
+	interp := interpreter.New()
+	interp.SetVar("x", object.NewInteger(5))
+
+	var out bytes.Buffer
+	StartWith(strings.NewReader("x * 2\n"), &out, interp, "script.mky")
+
+	expected := "script.mky >> [DEBUG] (x * 2)\n10\nscript.mky >> "
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2", `{"ok":true,"value":3}`},
+		{`"monkey"`, `{"ok":true,"value":"monkey"}`},
+		{"let x = 1;", `{"ok":true,"value":null}`},
+		{`[1, true, "a", [2]]`, `{"ok":true,"value":[1,true,"a",[2]]}`},
+		{`{"a": 1, 2: 3}`, `{"ok":true,"value":{"2":3,"a":1}}`},
+		{"1..3", `{"ok":true,"value":"1..3"}`},
+		{"fn(x) { x }", `{"ok":true,"value":{"function":{"type":"FunctionLiteral","line":1,"col":1,"parameters":[{"type":"Identifier","line":1,"col":4,"value":"x"}],"body":{"type":"BlockStatement","line":1,"col":7,"statements":[{"type":"ExpressionStatement","line":1,"col":9,"expression":{"type":"Identifier","line":1,"col":9,"value":"x"}}]}}}}`},
+		{"1 + true", `{"ok":false,"error":"type mismatch: INTEGER + BOOLEAN"}`},
+		{"let = 1", `{"ok":false,"error":"expected next token to be 'IDENT', got '=' instead\nno prefix parse function for = found"}`},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		RunJSON(&out, interpreter.New(), tt.input)
+		if out.String() != tt.expected+"\n" {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+
+	circular := &object.Array{}
+	circular.Elements = []object.Object{circular}
+	interp := interpreter.New()
+	interp.SetVar("a", circular)
+
+	var out bytes.Buffer
+	RunJSON(&out, interp, "a")
+	if expected := `{"ok":false,"error":"cannot encode an array that contains itself"}` + "\n"; out.String() != expected {
+		t.Errorf("wrong output for a circular array. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"help", "help", 0},
+		{"hepl", "help", 2},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if d := levenshtein(tt.a, tt.b); d != tt.expected {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, d, tt.expected)
+		}
+	}
+}
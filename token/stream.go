@@ -0,0 +1,57 @@
+package token
+
+// TokenStream reads a list of tokens in order, with as much lookahead as
+// needed. Mark and Reset go back to an earlier token, to try another way of
+// parsing the same tokens.
+type TokenStream struct {
+	tokens []Token
+	pos    int // Index of the token Consume returns next
+}
+
+// NewTokenStream reads `tokens`, which should end with an EOF token as
+// lexer.Lexer.Tokens does
+func NewTokenStream(tokens []Token) *TokenStream {
+	return &TokenStream{tokens: tokens}
+}
+
+// Peek returns the token `n` after the one Consume would return next, so
+// Peek(0) is that token. Before the start of the tokens it returns the first
+// token, and past the end the last one, the EOF.
+func (s *TokenStream) Peek(n int) Token {
+	if len(s.tokens) == 0 {
+		return Token{Type: EOF}
+	}
+	i := s.pos + n
+	if i < 0 {
+		i = 0
+	} else if i >= len(s.tokens) {
+		i = len(s.tokens) - 1
+	}
+	return s.tokens[i]
+}
+
+// Consume returns the next token and moves past it. Once the tokens are used
+// up it keeps returning the last one.
+func (s *TokenStream) Consume() Token {
+	tok := s.Peek(0)
+	if s.pos < len(s.tokens) {
+		s.pos++
+	}
+	return tok
+}
+
+// Mark returns the position in the stream, for Reset to go back to
+func (s *TokenStream) Mark() int {
+	return s.pos
+}
+
+// Reset goes back to a position returned by Mark, so the tokens after it are
+// read again. A mark outside the stream goes to its start or end.
+func (s *TokenStream) Reset(mark int) {
+	if mark < 0 {
+		mark = 0
+	} else if mark > len(s.tokens) {
+		mark = len(s.tokens)
+	}
+	s.pos = mark
+}
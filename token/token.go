@@ -4,23 +4,51 @@ import "fmt"
 
 type TokenType string
 
-// TODO(): Store the filename and line number on the token?
+// String returns the name of the token type. Operators and delimiters are
+// named by how they are written, `==` or `(`, and everything else by what it
+// is, such as IDENT or LET.
+func (t TokenType) String() string { return string(t) }
+
+// TODO(): Store the filename on the token?
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// Position of the first character of the token, both starting at 1
+	Line int
+	Col  int
 }
 
 func (t Token) Useful() string {
 	return fmt.Sprintf("token.Token -> %s.%s", t.Type, t.Literal)
 }
 
+// Is reports whether the token is any of `types`
+func (t Token) Is(types ...TokenType) bool {
+	for _, typ := range types {
+		if t.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKeyword reports whether the token was written as a keyword. `and` is a
+// keyword but `&&`, which has the same type, isn't.
+func (t Token) IsKeyword() bool {
+	typ, ok := keywords[t.Literal]
+	return ok && typ == t.Type
+}
+
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
 
 	// Identifiers + literals
-	IDENT = "IDENT" // add, foobar, x, y, ...
-	INT   = "INT"   // 1343456
+	IDENT  = "IDENT"  // add, foobar, x, y, ...
+	INT    = "INT"    // 1343456
+	STRING = "STRING" // "foo bar"
+	DOC    = "DOC"    // ## Adds x and y
 
 	// Operators
 	ASSIGN   = "="
@@ -35,10 +63,17 @@ const (
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	QUESTION  = "?"
+	DOT       = "."
+	DOTDOT    = ".."
+	DOTDOTDOT = "..."
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
 	RBRACE    = "}"
+	LBRACKET  = "["
+	RBRACKET  = "]"
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -48,21 +83,57 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	GO       = "GO"
+	FOR      = "FOR"
+	IN       = "IN"
+	SELECT   = "SELECT"
+	CASE     = "CASE"
+	DEFAULT  = "DEFAULT"
+	MATCH    = "MATCH"
+	SWITCH   = "SWITCH"
+	TRY      = "TRY"
+	WITH     = "WITH"
+	AS       = "AS"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	NOT      = "NOT"
+	TYPE     = "TYPE"
 
 	// Binary Comparision
 	EQUALS     = "=="
-	NOT_EQUALS = "!"
+	NOT_EQUALS = "!="
+
+	// Logical operators, also written `and` and `or`
+	AND = "&&"
+	OR  = "||"
 )
 
 var (
 	keywords = map[string]TokenType{ // TODO(): Change variable name
-		"fn":     FUNCTION,
-		"let":    LET,
-		"true":   TRUE,
-		"false":  FALSE,
-		"if":     IF,
-		"else":   ELSE,
-		"return": RETURN,
+		"fn":      FUNCTION,
+		"let":     LET,
+		"true":    TRUE,
+		"false":   FALSE,
+		"if":      IF,
+		"else":    ELSE,
+		"return":  RETURN,
+		"go":      GO,
+		"for":     FOR,
+		"in":      IN,
+		"select":  SELECT,
+		"case":    CASE,
+		"default": DEFAULT,
+		"match":   MATCH,
+		"switch":  SWITCH,
+		"try":     TRY,
+		"with":    WITH,
+		"as":      AS,
+		"catch":   CATCH,
+		"finally": FINALLY,
+		"not":     NOT,
+		"type":    TYPE,
+		"and":     AND,
+		"or":      OR,
 	}
 )
 
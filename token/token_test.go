@@ -0,0 +1,132 @@
+package token
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// The tree-sitter grammar is maintained by hand, so check it still knows
+// about every keyword the lexer does.
+func TestTreeSitterGrammarKeywords(t *testing.T) {
+	grammar, err := ioutil.ReadFile("../grammar/tree-sitter-monkey/grammar.js")
+	if err != nil {
+		t.Fatalf("could not read grammar: %v", err)
+	}
+
+	for keyword := range keywords {
+		if !strings.Contains(string(grammar), "'"+keyword+"'") {
+			t.Errorf("keyword %q is missing from grammar.js", keyword)
+		}
+	}
+}
+
+func TestIs(t *testing.T) {
+	tests := []struct {
+		tok      Token
+		types    []TokenType
+		expected bool
+	}{
+		{Token{Type: IDENT, Literal: "x"}, []TokenType{IDENT}, true},
+		{Token{Type: IDENT, Literal: "x"}, []TokenType{INT, STRING}, false},
+		{Token{Type: STRING, Literal: "x"}, []TokenType{INT, STRING}, true},
+		{Token{Type: IDENT, Literal: "x"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.tok.Is(tt.types...); got != tt.expected {
+			t.Errorf("%s.Is(%v) wrong. expected=%t, got=%t", tt.tok.Type, tt.types, tt.expected, got)
+		}
+	}
+}
+
+func TestIsKeyword(t *testing.T) {
+	tests := []struct {
+		tok      Token
+		expected bool
+	}{
+		{Token{Type: LET, Literal: "let"}, true},
+		{Token{Type: AND, Literal: "and"}, true},
+		{Token{Type: AND, Literal: "&&"}, false},
+		{Token{Type: IDENT, Literal: "lets"}, false},
+		{Token{Type: PLUS, Literal: "+"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.tok.IsKeyword(); got != tt.expected {
+			t.Errorf("%q.IsKeyword() wrong. expected=%t, got=%t", tt.tok.Literal, tt.expected, got)
+		}
+	}
+}
+
+func TestTokenTypeString(t *testing.T) {
+	tests := []struct {
+		typ      TokenType
+		expected string
+	}{
+		{IDENT, "IDENT"},
+		{LET, "LET"},
+		{EQUALS, "=="},
+		{NOT_EQUALS, "!="},
+		{BANG, "!"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.typ.String(); got != tt.expected {
+			t.Errorf("wrong name. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestTokenStream(t *testing.T) {
+	s := NewTokenStream([]Token{
+		{Type: IDENT, Literal: "x"},
+		{Type: PLUS, Literal: "+"},
+		{Type: INT, Literal: "1"},
+		{Type: EOF},
+	})
+
+	if tok := s.Peek(2); tok.Literal != "1" {
+		t.Errorf("wrong Peek(2). got=%q", tok.Literal)
+	}
+	if tok := s.Consume(); tok.Literal != "x" {
+		t.Errorf("wrong first token. got=%q", tok.Literal)
+	}
+
+	mark := s.Mark()
+	if tok := s.Consume(); tok.Type != PLUS {
+		t.Errorf("wrong second token. got=%q", tok.Type)
+	}
+	s.Consume()
+	s.Reset(mark)
+	if tok := s.Peek(0); tok.Type != PLUS {
+		t.Errorf("expected Reset to go back to +. got=%q", tok.Type)
+	}
+
+	s.Consume()
+	s.Consume()
+	for i := 0; i < 3; i++ {
+		if tok := s.Consume(); tok.Type != EOF {
+			t.Errorf("expected EOF at the end. got=%q", tok.Type)
+		}
+	}
+	if tok := s.Peek(5); tok.Type != EOF {
+		t.Errorf("expected EOF past the end. got=%q", tok.Type)
+	}
+	if tok := NewTokenStream(nil).Consume(); tok.Type != EOF {
+		t.Errorf("expected EOF from an empty stream. got=%q", tok.Type)
+	}
+
+	s.Reset(0)
+	if tok := s.Peek(-1); tok.Literal != "x" {
+		t.Errorf("expected the first token before the start. got=%q", tok.Literal)
+	}
+	s.Reset(-3)
+	if tok := s.Consume(); tok.Literal != "x" {
+		t.Errorf("expected Reset before the start to go to x. got=%q", tok.Literal)
+	}
+	s.Reset(10)
+	if tok := s.Consume(); tok.Type != EOF {
+		t.Errorf("expected Reset past the end to go to EOF. got=%q", tok.Type)
+	}
+}